@@ -33,8 +33,11 @@ import (
 )
 
 type AuthCache struct {
-	NextBatch       string `yaml:"next_batch"`
-	FilterID        string `yaml:"filter_id"`
+	NextBatch string `yaml:"next_batch"`
+	FilterID  string `yaml:"filter_id"`
+	// FilterHash is the hash of the filter JSON that FilterID was created from.
+	// The filter is recreated if the filter JSON changes.
+	FilterHash      string `yaml:"filter_hash"`
 	InitialSyncDone bool   `yaml:"initial_sync_done"`
 }
 
@@ -45,13 +48,46 @@ type UserPreferences struct {
 	DisableImages       bool `yaml:"disable_images"`
 	DisableTypingNotifs bool `yaml:"disable_typing_notifs"`
 	DisableEmojis       bool `yaml:"disable_emojis"`
+	HideReadReceipts    bool `yaml:"hide_read_receipts"`
+	PrivateReadReceipts bool `yaml:"private_read_receipts"`
+	VimMode             bool `yaml:"vim_mode"`
+}
+
+// NotificationConfig contains the desktop notification settings.
+type NotificationConfig struct {
+	// The notification backend: auto, native, dbus, command, bell or none.
+	// The default, auto, uses D-Bus if a notification server is running and the native notifier otherwise.
+	Backend string `yaml:"backend"`
+	// The command for the command backend. Each notification is written to its stdin as JSON.
+	Command []string `yaml:"command,omitempty"`
+
+	// Messages from the same room within this many seconds after a notification are combined
+	// into a single notification. Zero disables batching.
+	BatchWindow int `yaml:"batch_window"`
+	// Periods during which no notifications are sent, except for the ones matching Exceptions.
+	QuietHours []QuietHours `yaml:"quiet_hours,omitempty"`
+	// Notifications that are sent even during quiet hours and do not disturb mode.
+	Exceptions NotificationExceptions `yaml:"exceptions"`
+}
+
+// ClipboardConfig contains the settings for copying text to the clipboard.
+type ClipboardConfig struct {
+	// The copy method: auto, osc52 or tool. The default, auto, uses both the OSC 52 terminal escape sequence
+	// and a clipboard tool if one is available.
+	Method string `yaml:"method"`
+	// The clipboard tool, which receives the text in stdin. By default, pbcopy is used on macOS, clip.exe on
+	// Windows and wl-copy, xclip, xsel or clip.exe (in WSL) elsewhere, whichever is found first.
+	Command []string `yaml:"command,omitempty"`
 }
 
 // Config contains the main config of gomuks.
 type Config struct {
 	UserID      string `yaml:"mxid"`
 	AccessToken string `yaml:"access_token"`
+	DeviceID    string `yaml:"device_id,omitempty"`
 	HS          string `yaml:"homeserver"`
+	// The identity server discovered from .well-known/matrix/client, if any.
+	IdentityServer string `yaml:"identity_server,omitempty"`
 
 	Dir         string `yaml:"-"`
 	CacheDir    string `yaml:"cache_dir"`
@@ -59,10 +95,17 @@ type Config struct {
 	MediaDir    string `yaml:"media_dir"`
 	StateDir    string `yaml:"state_dir"`
 
+	Notifications NotificationConfig `yaml:"notifications"`
+	Clipboard     ClipboardConfig    `yaml:"clipboard"`
+
 	Preferences UserPreferences        `yaml:"-"`
 	AuthCache   AuthCache              `yaml:"-"`
 	Rooms       map[string]*rooms.Room `yaml:"-"`
 	PushRules   *pushrules.PushRuleset `yaml:"-"`
+	// The users in the m.ignored_user_list account data event.
+	IgnoredUsers map[string]struct{} `yaml:"-"`
+	// The key bindings from keybindings.yaml merged on top of the defaults.
+	Keybindings *Keybindings `yaml:"-"`
 
 	nosave bool
 }
@@ -76,7 +119,14 @@ func NewConfig(configDir, cacheDir string) *Config {
 		StateDir:    filepath.Join(cacheDir, "state"),
 		MediaDir:    filepath.Join(cacheDir, "media"),
 
-		Rooms: make(map[string]*rooms.Room),
+		Notifications: NotificationConfig{
+			BatchWindow: DefaultNotificationBatchWindow,
+			Exceptions:  NotificationExceptions{Highlights: true},
+		},
+		Clipboard: ClipboardConfig{Method: "auto"},
+
+		Rooms:       make(map[string]*rooms.Room),
+		Keybindings: NewKeybindings(),
 	}
 }
 
@@ -101,6 +151,7 @@ func (config *Config) DeleteSession() {
 	config.AccessToken = ""
 	config.Rooms = make(map[string]*rooms.Room)
 	config.PushRules = nil
+	config.IgnoredUsers = nil
 
 	config.Clear()
 	config.nosave = false
@@ -111,6 +162,7 @@ func (config *Config) LoadAll() {
 	config.Load()
 	config.LoadAuthCache()
 	config.LoadPushRules()
+	config.LoadIgnoredUsers()
 	config.LoadPreferences()
 	config.LoadRooms()
 }
@@ -125,6 +177,7 @@ func (config *Config) SaveAll() {
 	config.Save()
 	config.SaveAuthCache()
 	config.SavePushRules()
+	config.SaveIgnoredUsers()
 	config.SavePreferences()
 	config.SaveRooms()
 }
@@ -142,6 +195,30 @@ func (config *Config) SavePreferences() {
 	config.save("user preferences", config.CacheDir, "preferences.yaml", &config.Preferences)
 }
 
+// LoadKeybindings loads keybindings.yaml from the config directory and merges it on top of the default bindings.
+//
+// If the file doesn't exist, it's created with the default bindings. An existing file is never overwritten.
+func (config *Config) LoadKeybindings() {
+	_, err := os.Stat(filepath.Join(config.Dir, "keybindings.yaml"))
+	exists := !os.IsNotExist(err)
+	var userBindings Keybindings
+	config.load("key bindings", config.Dir, "keybindings.yaml", &userBindings)
+	config.Keybindings = NewKeybindings()
+	config.Keybindings.Merge(&userBindings)
+	for _, conflict := range config.Keybindings.Conflicts {
+		debug.Print("Key binding problem:", conflict)
+	}
+	if !exists {
+		config.SaveKeybindings()
+	}
+}
+
+// SaveKeybindings saves the merged key bindings to keybindings.yaml, so that the file lists every binding.
+// It's only called when the file doesn't exist yet, as it would overwrite the comments and formatting of the user.
+func (config *Config) SaveKeybindings() {
+	config.save("key bindings", config.Dir, "keybindings.yaml", config.Keybindings)
+}
+
 func (config *Config) LoadAuthCache() {
 	config.load("auth cache", config.CacheDir, "auth-cache.yaml", &config.AuthCache)
 }
@@ -161,6 +238,17 @@ func (config *Config) SavePushRules() {
 	config.save("push rules", config.CacheDir, "pushrules.json", &config.PushRules)
 }
 
+func (config *Config) LoadIgnoredUsers() {
+	config.load("ignored users", config.CacheDir, "ignored-users.json", &config.IgnoredUsers)
+}
+
+func (config *Config) SaveIgnoredUsers() {
+	if config.IgnoredUsers == nil {
+		return
+	}
+	config.save("ignored users", config.CacheDir, "ignored-users.json", &config.IgnoredUsers)
+}
+
 func (config *Config) LoadRooms() {
 	os.MkdirAll(config.StateDir, 0700)
 
@@ -19,6 +19,7 @@ package config_test
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,6 +31,38 @@ func TestNewConfig_Defaults(t *testing.T) {
 	assert.Equal(t, "/tmp/gomuks-test-0", cfg.Dir)
 	assert.Equal(t, "/tmp/gomuks-test-0/history.db", cfg.HistoryPath)
 	assert.Equal(t, "/tmp/gomuks-test-0/media", cfg.MediaDir)
+	assert.Equal(t, "auto", cfg.Clipboard.Method)
+	assert.Nil(t, cfg.Clipboard.Command)
+}
+
+func TestConfig_Load_Clipboard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	configYAML := "clipboard:\n  method: tool\n  command: [xclip, -selection, clipboard]\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(configYAML), 0600))
+
+	cfg := config.NewConfig(dir, dir)
+	cfg.Load()
+	assert.Equal(t, "tool", cfg.Clipboard.Method)
+	assert.Equal(t, []string{"xclip", "-selection", "clipboard"}, cfg.Clipboard.Command)
+}
+
+func TestConfig_Save_ClipboardDefaults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.NewConfig(dir, dir)
+	cfg.Save()
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.yaml"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "clipboard:\n  method: auto\n")
+	assert.NotContains(t, string(data), "command:")
+
+	loaded := config.NewConfig(dir, dir)
+	loaded.Load()
+	assert.Equal(t, cfg.Clipboard, loaded.Clipboard)
 }
 
 func TestConfig_Load_NonexistentDoesntFail(t *testing.T) {
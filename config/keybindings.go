@@ -0,0 +1,349 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tulir/tcell"
+)
+
+// The contexts in which key bindings are looked up. Main view bindings are checked first,
+// so they shadow room view bindings of the same chord.
+const (
+	KeyContextMain = "main"
+	KeyContextRoom = "room"
+)
+
+// KeyActionNone can be bound to a chord to remove its default binding.
+const KeyActionNone = "none"
+
+// KeyAction is a named action that key chords can be bound to.
+type KeyAction struct {
+	Name        string
+	Context     string
+	Description string
+	Defaults    []string
+}
+
+// KeyActions lists all actions that can be bound in keybindings.yaml, with their default chords.
+var KeyActions = []KeyAction{
+	{"next_room", KeyContextMain, "Switch to the next room", []string{"ctrl+down", "alt+down"}},
+	{"prev_room", KeyContextMain, "Switch to the previous room", []string{"ctrl+up", "alt+up"}},
+	{"next_active_room", KeyContextMain, "Switch to the next room with unread messages", []string{"alt+a"}},
+	{"search_rooms", KeyContextMain, "Search for a room to jump to", []string{"ctrl+enter", "alt+enter"}},
+	{"scroll_top", KeyContextMain, "Scroll to the top of the loaded history", []string{"ctrl+home", "alt+home"}},
+	{"scroll_bottom", KeyContextMain, "Scroll to the bottom of the chat", []string{"ctrl+end", "alt+end"}},
+	{"newline", KeyContextMain, "Insert a newline in the message input", []string{"ctrl+n", "alt+n"}},
+	{"bare_mode", KeyContextMain, "Show the current room in bare mode for copying text", []string{"ctrl+l", "alt+l"}},
+	{"toggle_room_list", KeyContextMain, "Show or hide the room list", []string{"alt+r"}},
+	{"toggle_user_list", KeyContextMain, "Show or hide the user list", []string{"alt+u"}},
+	{"show_keys", KeyContextMain, "List the key bindings", []string{"f1"}},
+
+	{"scroll_up", KeyContextRoom, "Scroll the chat up by half a page", []string{"pgup"}},
+	{"scroll_down", KeyContextRoom, "Scroll the chat down by half a page", []string{"pgdn"}},
+	{"send", KeyContextRoom, "Send the message or run the command in the input", []string{"enter"}},
+	{"compose", KeyContextRoom, "Edit the message in $EDITOR and put the result back into the input", []string{"alt+e"}},
+	{"compose_send", KeyContextRoom, "Write a message in $EDITOR and send it when the editor exits", []string{"ctrl+alt+e"}},
+	{"show_pins", KeyContextRoom, "Show the pinned messages of the room", []string{"alt+p"}},
+	{"open_thread", KeyContextRoom, "Open the thread of the latest message", []string{"alt+t"}},
+	{"close_thread", KeyContextRoom, "Close the thread pane", nil},
+	{"normal_mode", KeyContextRoom, "Enter vim-style normal mode, if enabled with /toggle vimmode", []string{"esc"}},
+}
+
+// GetKeyAction returns the action with the given name, or nil if there is no such action.
+func GetKeyAction(name string) *KeyAction {
+	for i := range KeyActions {
+		if KeyActions[i].Name == name {
+			return &KeyActions[i]
+		}
+	}
+	return nil
+}
+
+// Chord is a key combination, i.e. a key and the modifiers held while pressing it.
+type Chord struct {
+	Key  tcell.Key
+	Rune rune
+	Mod  tcell.ModMask
+}
+
+var keyNames = map[tcell.Key]string{
+	tcell.KeyEnter:      "enter",
+	tcell.KeyTab:        "tab",
+	tcell.KeyBacktab:    "backtab",
+	tcell.KeyEsc:        "esc",
+	tcell.KeyBackspace2: "backspace",
+	tcell.KeyDelete:     "delete",
+	tcell.KeyInsert:     "insert",
+	tcell.KeyUp:         "up",
+	tcell.KeyDown:       "down",
+	tcell.KeyLeft:       "left",
+	tcell.KeyRight:      "right",
+	tcell.KeyHome:       "home",
+	tcell.KeyEnd:        "end",
+	tcell.KeyPgUp:       "pgup",
+	tcell.KeyPgDn:       "pgdn",
+}
+
+var keysByName = map[string]tcell.Key{
+	"return":   tcell.KeyEnter,
+	"escape":   tcell.KeyEsc,
+	"del":      tcell.KeyDelete,
+	"pageup":   tcell.KeyPgUp,
+	"pagedown": tcell.KeyPgDn,
+}
+
+func init() {
+	for key, name := range keyNames {
+		keysByName[name] = key
+	}
+	for i := 0; i < 12; i++ {
+		key := tcell.KeyF1 + tcell.Key(i)
+		name := fmt.Sprintf("f%d", i+1)
+		keyNames[key] = name
+		keysByName[name] = key
+	}
+}
+
+// NewChord creates a chord from the key, rune and modifiers of a key event.
+//
+// Control characters are turned into ctrl+letter chords and the two backspace keys are treated as one,
+// so that the chord matches the one parsed from the same description in the config.
+func NewChord(key tcell.Key, ch rune, mod tcell.ModMask) Chord {
+	mod &= tcell.ModShift | tcell.ModCtrl | tcell.ModAlt | tcell.ModMeta
+	if mod&tcell.ModMeta != 0 {
+		mod = mod&^tcell.ModMeta | tcell.ModAlt
+	}
+	switch {
+	case key == tcell.KeyRune && mod&tcell.ModCtrl != 0 && ch < utf8.RuneSelf && unicode.IsLetter(ch):
+		key = tcell.KeyCtrlA + tcell.Key(unicode.ToLower(ch)-'a')
+	case key == tcell.KeyBackspace:
+		key = tcell.KeyBackspace2
+	case key == tcell.KeyEnter || key == tcell.KeyTab || key == tcell.KeyEsc:
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ:
+		mod |= tcell.ModCtrl
+	}
+	if key != tcell.KeyRune {
+		ch = 0
+	}
+	return Chord{Key: key, Rune: ch, Mod: mod}
+}
+
+// ParseChord parses a chord description like "ctrl+alt+up", "alt+a" or "f1".
+func ParseChord(str string) (Chord, error) {
+	parts := strings.Split(str, "+")
+	// Allow binding the plus key itself, e.g. "alt++".
+	if strings.HasSuffix(str, "++") {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+	var mod tcell.ModMask
+	for _, part := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "ctrl", "control":
+			mod |= tcell.ModCtrl
+		case "alt", "meta":
+			mod |= tcell.ModAlt
+		case "shift":
+			mod |= tcell.ModShift
+		default:
+			return Chord{}, fmt.Errorf("unknown modifier %q in %q", part, str)
+		}
+	}
+	keyName := parts[len(parts)-1]
+	if key, ok := keysByName[strings.ToLower(keyName)]; ok {
+		return NewChord(key, 0, mod), nil
+	} else if strings.ToLower(keyName) == "space" {
+		return NewChord(tcell.KeyRune, ' ', mod), nil
+	} else if utf8.RuneCountInString(keyName) == 1 {
+		ch, _ := utf8.DecodeRuneInString(keyName)
+		return NewChord(tcell.KeyRune, ch, mod), nil
+	}
+	return Chord{}, fmt.Errorf("unknown key %q in %q", keyName, str)
+}
+
+// String returns the canonical description of the chord, which ParseChord parses back into the same chord.
+func (chord Chord) String() string {
+	var buf strings.Builder
+	mod := chord.Mod
+	var keyName string
+	switch {
+	case chord.Key == tcell.KeyRune && chord.Rune == ' ':
+		keyName = "space"
+	case chord.Key == tcell.KeyRune:
+		keyName = string(chord.Rune)
+	case chord.Key >= tcell.KeyCtrlA && chord.Key <= tcell.KeyCtrlZ && keyNames[chord.Key] == "":
+		keyName = string('a' + rune(chord.Key-tcell.KeyCtrlA))
+		mod |= tcell.ModCtrl
+	default:
+		keyName = keyNames[chord.Key]
+		if len(keyName) == 0 {
+			keyName = fmt.Sprintf("key%d", chord.Key)
+		}
+	}
+	if mod&tcell.ModCtrl != 0 {
+		buf.WriteString("ctrl+")
+	}
+	if mod&tcell.ModAlt != 0 {
+		buf.WriteString("alt+")
+	}
+	if mod&tcell.ModShift != 0 {
+		buf.WriteString("shift+")
+	}
+	buf.WriteString(keyName)
+	return buf.String()
+}
+
+// Keybindings maps key chords to the names of actions in KeyActions.
+type Keybindings struct {
+	Main map[string]string `yaml:"main"`
+	Room map[string]string `yaml:"room"`
+
+	// Problems found while loading the bindings, such as unknown actions and conflicting chords.
+	Conflicts []string `yaml:"-"`
+
+	compiled map[string]map[Chord]string
+}
+
+// NewKeybindings creates a set of key bindings containing the defaults of all actions.
+func NewKeybindings() *Keybindings {
+	kb := &Keybindings{
+		compiled: map[string]map[Chord]string{
+			KeyContextMain: make(map[Chord]string),
+			KeyContextRoom: make(map[Chord]string),
+		},
+	}
+	for _, action := range KeyActions {
+		for _, str := range action.Defaults {
+			chord, err := ParseChord(str)
+			if err != nil {
+				panic(err)
+			}
+			kb.compiled[action.Context][chord] = action.Name
+		}
+	}
+	kb.updateMaps()
+	return kb
+}
+
+func (kb *Keybindings) updateMaps() {
+	kb.Main = stringifyBindings(kb.compiled[KeyContextMain])
+	kb.Room = stringifyBindings(kb.compiled[KeyContextRoom])
+}
+
+func stringifyBindings(bindings map[Chord]string) map[string]string {
+	strs := make(map[string]string, len(bindings))
+	for chord, action := range bindings {
+		strs[chord.String()] = action
+	}
+	return strs
+}
+
+// Merge applies the given user-defined bindings on top of these bindings. Invalid bindings are skipped
+// and, like conflicting bindings, reported in Conflicts.
+func (kb *Keybindings) Merge(user *Keybindings) {
+	kb.mergeContext(KeyContextMain, user.Main)
+	kb.mergeContext(KeyContextRoom, user.Room)
+	kb.findShadowed()
+	kb.updateMaps()
+}
+
+func (kb *Keybindings) mergeContext(context string, bindings map[string]string) {
+	chordStrs := make([]string, 0, len(bindings))
+	for str := range bindings {
+		chordStrs = append(chordStrs, str)
+	}
+	sort.Strings(chordStrs)
+
+	setBy := make(map[Chord]string)
+	for _, str := range chordStrs {
+		actionName := bindings[str]
+		chord, err := ParseChord(str)
+		if err != nil {
+			kb.addConflict("%s: %v", context, err)
+			continue
+		}
+		if actionName != KeyActionNone {
+			action := GetKeyAction(actionName)
+			if action == nil {
+				kb.addConflict("%s: %s is bound to unknown action %s", context, str, actionName)
+				continue
+			} else if action.Context != context {
+				kb.addConflict("%s: %s is bound to %s, which is a %s action", context, str, actionName, action.Context)
+				continue
+			}
+		}
+		if prevStr, ok := setBy[chord]; ok && bindings[prevStr] != actionName {
+			kb.addConflict("%s: %s and %s are the same key, but are bound to %s and %s. Using %s",
+				context, prevStr, str, bindings[prevStr], actionName, bindings[prevStr])
+			continue
+		}
+		setBy[chord] = str
+		if actionName != KeyActionNone && chord.Key == tcell.KeyRune && chord.Mod&^tcell.ModShift == 0 {
+			kb.addConflict("%s: %s is bound to %s, which prevents typing it", context, str, actionName)
+		}
+		kb.compiled[context][chord] = actionName
+	}
+}
+
+// findShadowed reports chords that are bound in both contexts, as the main view binding wins.
+func (kb *Keybindings) findShadowed() {
+	var shadowed []string
+	for chord, roomAction := range kb.compiled[KeyContextRoom] {
+		mainAction := kb.compiled[KeyContextMain][chord]
+		if roomAction != KeyActionNone && len(mainAction) > 0 && mainAction != KeyActionNone {
+			shadowed = append(shadowed, fmt.Sprintf("%s is bound to %s in the main view, which overrides %s in the room view",
+				chord, mainAction, roomAction))
+		}
+	}
+	sort.Strings(shadowed)
+	kb.Conflicts = append(kb.Conflicts, shadowed...)
+}
+
+func (kb *Keybindings) addConflict(format string, args ...interface{}) {
+	kb.Conflicts = append(kb.Conflicts, fmt.Sprintf(format, args...))
+}
+
+// Action returns the name of the action bound to the given chord in the given context,
+// or an empty string if the chord isn't bound.
+func (kb *Keybindings) Action(context string, chord Chord) string {
+	action := kb.compiled[context][chord]
+	if action == KeyActionNone {
+		return ""
+	}
+	return action
+}
+
+// Chords returns the canonical descriptions of all chords bound to the given action in sorted order.
+func (kb *Keybindings) Chords(actionName string) []string {
+	action := GetKeyAction(actionName)
+	if action == nil {
+		return nil
+	}
+	var chords []string
+	for chord, boundAction := range kb.compiled[action.Context] {
+		if boundAction == actionName {
+			chords = append(chords, chord.String())
+		}
+	}
+	sort.Strings(chords)
+	return chords
+}
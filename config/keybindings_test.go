@@ -0,0 +1,158 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func TestParseChord(t *testing.T) {
+	chord, err := config.ParseChord("Ctrl+N")
+	assert.Nil(t, err)
+	// Ctrl+N is delivered by tcell as a control character.
+	assert.Equal(t, config.NewChord(tcell.KeyCtrlN, 14, tcell.ModCtrl), chord)
+	assert.Equal(t, "ctrl+n", chord.String())
+
+	chord, err = config.ParseChord("alt+ctrl+PageUp")
+	assert.Nil(t, err)
+	assert.Equal(t, config.NewChord(tcell.KeyPgUp, 0, tcell.ModCtrl|tcell.ModAlt), chord)
+	assert.Equal(t, "ctrl+alt+pgup", chord.String())
+
+	chord, err = config.ParseChord("alt++")
+	assert.Nil(t, err)
+	assert.Equal(t, config.NewChord(tcell.KeyRune, '+', tcell.ModAlt), chord)
+
+	_, err = config.ParseChord("hyper+a")
+	assert.NotNil(t, err)
+	_, err = config.ParseChord("ctrl+nope")
+	assert.NotNil(t, err)
+}
+
+func TestChord_String_RoundTrip(t *testing.T) {
+	for _, str := range []string{"enter", "ctrl+enter", "shift+enter", "alt+a", "f1", "ctrl+alt+up", "alt+space", "backspace"} {
+		chord, err := config.ParseChord(str)
+		assert.Nil(t, err)
+		assert.Equal(t, str, chord.String())
+	}
+}
+
+func TestNewKeybindings_Defaults(t *testing.T) {
+	kb := config.NewKeybindings()
+	assert.Empty(t, kb.Conflicts)
+	assert.Equal(t, "next_room", kb.Main["alt+down"])
+	assert.Equal(t, "send", kb.Action(config.KeyContextRoom, config.NewChord(tcell.KeyEnter, '\r', tcell.ModNone)))
+	assert.Equal(t, "", kb.Action(config.KeyContextRoom, config.NewChord(tcell.KeyEnter, '\r', tcell.ModShift)))
+	assert.Equal(t, []string{"alt+l", "ctrl+l"}, kb.Chords("bare_mode"))
+	for _, action := range config.KeyActions {
+		for _, str := range action.Defaults {
+			chord, _ := config.ParseChord(str)
+			assert.Equal(t, action.Name, kb.Action(action.Context, chord), str)
+		}
+	}
+}
+
+func TestKeybindings_Merge(t *testing.T) {
+	kb := config.NewKeybindings()
+	kb.Merge(&config.Keybindings{
+		Main: map[string]string{
+			"ALT+a":  "none",
+			"ctrl+g": "next_active_room",
+		},
+		Room: map[string]string{
+			"alt+s": "send",
+		},
+	})
+	assert.Empty(t, kb.Conflicts)
+	assert.Equal(t, "", kb.Action(config.KeyContextMain, config.NewChord(tcell.KeyRune, 'a', tcell.ModAlt)))
+	assert.Equal(t, []string{"ctrl+g"}, kb.Chords("next_active_room"))
+	assert.Equal(t, []string{"alt+s", "enter"}, kb.Chords("send"))
+	// Unbinding is saved so that the default isn't restored on the next load.
+	assert.Equal(t, "none", kb.Main["alt+a"])
+}
+
+func TestKeybindings_Merge_Conflicts(t *testing.T) {
+	kb := config.NewKeybindings()
+	kb.Merge(&config.Keybindings{
+		Main: map[string]string{
+			"ctrl+g":  "next_room",
+			"Ctrl+G":  "prev_room",
+			"ctrl+x":  "send",
+			"ctrl+y":  "does_not_exist",
+			"ctrl+zz": "next_room",
+			"q":       "next_room",
+			"alt+p":   "bare_mode",
+		},
+	})
+	assert.Len(t, kb.Conflicts, 6)
+	// The first binding in sorted order wins.
+	assert.Equal(t, "prev_room", kb.Action(config.KeyContextMain, config.NewChord(tcell.KeyCtrlG, 7, tcell.ModCtrl)))
+	assert.Equal(t, "", kb.Action(config.KeyContextMain, config.NewChord(tcell.KeyCtrlX, 24, tcell.ModCtrl)))
+	assert.Contains(t, kb.Conflicts, "alt+p is bound to bare_mode in the main view, which overrides show_pins in the room view")
+}
+
+func TestConfig_LoadKeybindings_CreatesMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test-keys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cfg := config.NewConfig(dir, dir)
+
+	cfg.LoadKeybindings()
+
+	dat, err := ioutil.ReadFile(filepath.Join(dir, "keybindings.yaml"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(dat), "next_room")
+}
+
+func TestConfig_SaveAll_KeepsKeybindingsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test-keys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cfg := config.NewConfig(dir, dir)
+
+	const userFile = "# My bindings\nmain:\n  ctrl+g: next_active_room\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "keybindings.yaml"), []byte(userFile), 0600))
+
+	cfg.LoadAll()
+	cfg.LoadKeybindings()
+	assert.Equal(t, "next_active_room", cfg.Keybindings.Main["ctrl+g"])
+	cfg.SaveAll()
+
+	dat, err := ioutil.ReadFile(filepath.Join(dir, "keybindings.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, userFile, string(dat))
+}
+
+func TestConfig_LoadAll_DoesNotCreateKeybindingsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test-keys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cfg := config.NewConfig(dir, dir)
+
+	cfg.LoadAll()
+
+	_, err = os.Stat(filepath.Join(dir, "keybindings.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "next_room", cfg.Keybindings.Main["ctrl+down"])
+}
@@ -0,0 +1,152 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultNotificationBatchWindow is the default number of seconds in which notifications from the same room are combined.
+const DefaultNotificationBatchWindow = 10
+
+// GetBatchWindow returns the notification batch window as a duration.
+func (cfg *NotificationConfig) GetBatchWindow() time.Duration {
+	return time.Duration(cfg.BatchWindow) * time.Second
+}
+
+// IsQuietTime returns whether or not the given time is within any of the quiet hours periods.
+func (cfg *NotificationConfig) IsQuietTime(t time.Time) bool {
+	for _, period := range cfg.QuietHours {
+		if period.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationExceptions lists the notifications that break through quiet hours and do not disturb mode.
+type NotificationExceptions struct {
+	// Whether or not highlights (mentions and keywords) are exceptions.
+	Highlights bool `yaml:"highlights"`
+	// Room IDs whose messages are exceptions.
+	Rooms []string `yaml:"rooms,omitempty"`
+	// User IDs whose messages are exceptions.
+	Users []string `yaml:"users,omitempty"`
+}
+
+// Matches returns whether or not a message with the given properties should be notified about
+// even when notifications are otherwise suppressed.
+func (exc *NotificationExceptions) Matches(roomID, senderID string, highlight bool) bool {
+	if highlight && exc.Highlights {
+		return true
+	}
+	for _, id := range exc.Rooms {
+		if id == roomID {
+			return true
+		}
+	}
+	for _, id := range exc.Users {
+		if id == senderID {
+			return true
+		}
+	}
+	return false
+}
+
+// QuietHours is a daily period during which notifications are suppressed.
+type QuietHours struct {
+	// The start and end of the period as 24-hour local times, e.g. 22:00 and 07:30.
+	// If the end is before the start, the period continues past midnight.
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// The weekdays on which the period starts, e.g. [mon, tue, wed]. Every day if empty.
+	Days []string `yaml:"days,omitempty"`
+}
+
+// parseClock parses a HH:MM time into the number of minutes since midnight.
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("invalid time %q: expected HH:MM", value)
+	} else if hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// Validate checks that the start and end times and the weekdays are valid.
+func (qh QuietHours) Validate() error {
+	if _, err := parseClock(qh.Start); err != nil {
+		return err
+	} else if _, err = parseClock(qh.End); err != nil {
+		return err
+	}
+	for _, day := range qh.Days {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("invalid weekday %q", day)
+		}
+	}
+	return nil
+}
+
+func parseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(day)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if day == name || day == name[:3] {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+func (qh QuietHours) startsOn(weekday time.Weekday) bool {
+	if len(qh.Days) == 0 {
+		return true
+	}
+	for _, day := range qh.Days {
+		if parsed, ok := parseWeekday(day); ok && parsed == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns whether or not the given time is within the period.
+// Invalid periods don't contain any time.
+func (qh QuietHours) Contains(t time.Time) bool {
+	start, err := parseClock(qh.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(qh.End)
+	if err != nil || start == end {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end && qh.startsOn(t.Weekday())
+	} else if now >= start {
+		return qh.startsOn(t.Weekday())
+	} else if now < end {
+		// The period started on the previous day.
+		return qh.startsOn((t.Weekday() + 6) % 7)
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func at(weekday time.Weekday, hour, minute int) time.Time {
+	// 2019-04-07 was a Sunday.
+	return time.Date(2019, 4, 7+int(weekday), hour, minute, 0, 0, time.Local)
+}
+
+func TestQuietHours_Contains_SameDay(t *testing.T) {
+	qh := config.QuietHours{Start: "12:00", End: "13:30"}
+	assert.False(t, qh.Contains(at(time.Monday, 11, 59)))
+	assert.True(t, qh.Contains(at(time.Monday, 12, 0)))
+	assert.True(t, qh.Contains(at(time.Monday, 13, 29)))
+	assert.False(t, qh.Contains(at(time.Monday, 13, 30)))
+}
+
+func TestQuietHours_Contains_Overnight(t *testing.T) {
+	qh := config.QuietHours{Start: "22:00", End: "07:00", Days: []string{"fri"}}
+	assert.True(t, qh.Contains(at(time.Friday, 23, 0)))
+	// The period that started on Friday continues into Saturday morning.
+	assert.True(t, qh.Contains(at(time.Saturday, 6, 59)))
+	assert.False(t, qh.Contains(at(time.Saturday, 7, 0)))
+	assert.False(t, qh.Contains(at(time.Saturday, 23, 0)))
+	assert.False(t, qh.Contains(at(time.Friday, 6, 0)))
+}
+
+func TestQuietHours_Invalid(t *testing.T) {
+	assert.NotNil(t, config.QuietHours{Start: "25:00", End: "07:00"}.Validate())
+	assert.NotNil(t, config.QuietHours{Start: "22:00", End: "7"}.Validate())
+	assert.NotNil(t, config.QuietHours{Start: "22:00", End: "07:00", Days: []string{"someday"}}.Validate())
+	assert.Nil(t, config.QuietHours{Start: "22:00", End: "24:00", Days: []string{"Sunday", "mon"}}.Validate())
+	assert.False(t, config.QuietHours{Start: "bad", End: "07:00"}.Contains(at(time.Monday, 3, 0)))
+}
+
+func TestNotificationExceptions_Matches(t *testing.T) {
+	exc := config.NotificationExceptions{
+		Highlights: true,
+		Rooms:      []string{"!important:example.com"},
+		Users:      []string{"@boss:example.com"},
+	}
+	assert.True(t, exc.Matches("!foo:example.com", "@user:example.com", true))
+	assert.True(t, exc.Matches("!important:example.com", "@user:example.com", false))
+	assert.True(t, exc.Matches("!foo:example.com", "@boss:example.com", false))
+	assert.False(t, exc.Matches("!foo:example.com", "@user:example.com", false))
+
+	exc.Highlights = false
+	assert.False(t, exc.Matches("!foo:example.com", "@user:example.com", true))
+}
+
+func TestNewConfig_NotificationDefaults(t *testing.T) {
+	cfg := config.NewConfig("/tmp/gomuks-test-notif", "/tmp/gomuks-test-notif")
+	assert.Equal(t, config.DefaultNotificationBatchWindow, cfg.Notifications.BatchWindow)
+	assert.Equal(t, 10*time.Second, cfg.Notifications.GetBatchWindow())
+	assert.True(t, cfg.Notifications.Exceptions.Highlights)
+	assert.False(t, cfg.Notifications.IsQuietTime(time.Now()))
+}
+
+func TestNotificationConfig_BatchWindowYAML(t *testing.T) {
+	var cfg config.NotificationConfig
+	assert.Nil(t, yaml.Unmarshal([]byte("batch_window: 30"), &cfg))
+	assert.Equal(t, 30*time.Second, cfg.GetBatchWindow())
+
+	data, err := yaml.Marshal(&config.NotificationConfig{BatchWindow: config.DefaultNotificationBatchWindow})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "batch_window: 10\n")
+}
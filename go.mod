@@ -5,6 +5,7 @@ go 1.12
 require (
 	github.com/alecthomas/chroma v0.6.3
 	github.com/disintegration/imaging v1.6.0
+	github.com/godbus/dbus/v5 v5.0.3
 	github.com/kyokomi/emoji v2.1.0+incompatible
 	github.com/lithammer/fuzzysearch v1.0.2
 	github.com/lucasb-eyer/go-colorful v1.0.1
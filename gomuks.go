@@ -17,8 +17,11 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -34,20 +37,32 @@ type Gomuks struct {
 	matrix *matrix.Container
 	config *config.Config
 	stop   chan bool
+
+	// Additional accounts that are logged in alongside the main account.
+	// Each account has its own config and cache directories inside the
+	// accounts subdirectory of the main config and cache directories.
+	accounts  []*matrix.Container
+	configDir string
+	cacheDir  string
 }
 
 // NewGomuks creates a new Gomuks instance with everything initialized,
 // but does not start it.
 func NewGomuks(uiProvider ifc.UIProvider, configDir, cacheDir string) *Gomuks {
 	gmx := &Gomuks{
-		stop: make(chan bool, 1),
+		stop:      make(chan bool, 1),
+		configDir: configDir,
+		cacheDir:  cacheDir,
 	}
 
 	gmx.config = config.NewConfig(configDir, cacheDir)
 	gmx.ui = uiProvider(gmx)
-	gmx.matrix = matrix.NewContainer(gmx)
+	gmx.matrix = matrix.NewContainer(gmx, gmx.config)
 
 	gmx.config.LoadAll()
+	// Key bindings are shared by all accounts, so they're only loaded from the main config directory.
+	gmx.config.LoadKeybindings()
+	gmx.loadAccounts()
 	gmx.ui.Init()
 
 	debug.OnRecover = gmx.ui.Finish
@@ -55,9 +70,42 @@ func NewGomuks(uiProvider ifc.UIProvider, configDir, cacheDir string) *Gomuks {
 	return gmx
 }
 
+// accountDirs returns the config and cache directories of the additional account with the given name.
+func (gmx *Gomuks) accountDirs(name string) (string, string) {
+	return filepath.Join(gmx.configDir, "accounts", name), filepath.Join(gmx.cacheDir, "accounts", name)
+}
+
+// loadAccounts loads the configs of all additional accounts from the accounts subdirectory of the config directory.
+func (gmx *Gomuks) loadAccounts() {
+	files, err := ioutil.ReadDir(filepath.Join(gmx.configDir, "accounts"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			debug.Print("Failed to list additional accounts:", err)
+		}
+		return
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		cfg := config.NewConfig(gmx.accountDirs(file.Name()))
+		cfg.LoadAll()
+		if len(cfg.AccessToken) == 0 {
+			debug.Print("Removing additional account", file.Name(), "without a session")
+			cfg.Clear()
+			_ = os.RemoveAll(cfg.Dir)
+			continue
+		}
+		gmx.accounts = append(gmx.accounts, matrix.NewContainer(gmx, cfg))
+	}
+}
+
 // Save saves the active session and message history.
 func (gmx *Gomuks) Save() {
 	gmx.config.SaveAll()
+	for _, account := range gmx.accounts {
+		account.Config().SaveAll()
+	}
 	//debug.Print("Saving history...")
 	//gmx.ui.MainView().SaveAllHistory()
 }
@@ -84,6 +132,9 @@ func (gmx *Gomuks) StartAutosave() {
 func (gmx *Gomuks) Stop() {
 	debug.Print("Disconnecting from Matrix...")
 	gmx.matrix.Stop()
+	for _, account := range gmx.accounts {
+		account.Stop()
+	}
 	debug.Print("Cleaning up UI...")
 	gmx.ui.Stop()
 	gmx.stop <- true
@@ -97,6 +148,14 @@ func (gmx *Gomuks) Stop() {
 // will be recovered as specified in Recover().
 func (gmx *Gomuks) Start() {
 	_ = gmx.matrix.InitClient()
+	for _, account := range gmx.accounts {
+		if len(gmx.config.AccessToken) == 0 {
+			// Additional accounts are only started if the main account is logged in.
+			break
+		} else if err := account.InitClient(); err != nil {
+			debug.Printf("Failed to initialize account %s: %v", account.Config().UserID, err)
+		}
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -116,6 +175,65 @@ func (gmx *Gomuks) Matrix() ifc.MatrixContainer {
 	return gmx.matrix
 }
 
+// Account returns the MatrixContainer of the account with the given user ID,
+// or nil if no such account is logged in.
+func (gmx *Gomuks) Account(userID string) ifc.MatrixContainer {
+	if gmx.config.UserID == userID {
+		return gmx.matrix
+	}
+	for _, account := range gmx.accounts {
+		if account.Config().UserID == userID {
+			return account
+		}
+	}
+	return nil
+}
+
+// Accounts returns the MatrixContainers of all accounts, starting with the main account.
+func (gmx *Gomuks) Accounts() []ifc.MatrixContainer {
+	accounts := make([]ifc.MatrixContainer, len(gmx.accounts)+1)
+	accounts[0] = gmx.matrix
+	for i, account := range gmx.accounts {
+		accounts[i+1] = account
+	}
+	return accounts
+}
+
+// AddAccount creates a new additional account with its own config and cache directories.
+//
+// The account is not logged in, the caller is expected to call InitClient() and Login() on it.
+func (gmx *Gomuks) AddAccount() ifc.MatrixContainer {
+	var name string
+	for i := len(gmx.accounts) + 2; ; i++ {
+		name = strconv.Itoa(i)
+		dir, _ := gmx.accountDirs(name)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+	}
+	cfg := config.NewConfig(gmx.accountDirs(name))
+	cfg.Load()
+	account := matrix.NewContainer(gmx, cfg)
+	gmx.accounts = append(gmx.accounts, account)
+	return account
+}
+
+// RemoveAccount drops the given additional account and deletes its config and cache.
+//
+// The account must already be stopped (see Container.Logout).
+// The main account can't be removed, it must be logged out instead.
+func (gmx *Gomuks) RemoveAccount(account ifc.MatrixContainer) {
+	for i, existing := range gmx.accounts {
+		if existing == account {
+			cfg := existing.Config()
+			cfg.Clear()
+			_ = os.RemoveAll(cfg.Dir)
+			gmx.accounts = append(gmx.accounts[:i], gmx.accounts[i+1:]...)
+			return
+		}
+	}
+}
+
 // Config returns the Gomuks config instance.
 func (gmx *Gomuks) Config() *config.Config {
 	return gmx.config
@@ -26,6 +26,11 @@ type Gomuks interface {
 	UI() GomuksUI
 	Config() *config.Config
 
+	Account(userID string) MatrixContainer
+	Accounts() []MatrixContainer
+	AddAccount() MatrixContainer
+	RemoveAccount(account MatrixContainer)
+
 	Start()
 	Stop()
 }
@@ -17,27 +17,68 @@
 package ifc
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/tulir/mautrix-go"
 
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/pills"
+	"github.com/kennetanti/gomuks/matrix/pushrules"
 	"github.com/kennetanti/gomuks/matrix/rooms"
 )
 
 type MatrixContainer interface {
 	Client() *mautrix.Client
+	Config() *config.Config
 	InitClient() error
 	Initialized() bool
 
 	Start()
 	Stop()
 
+	DiscoverHomeserver(userIDOrServerName string) error
+	GetLoginFlows() ([]string, error)
 	Login(user, password string) error
-	Logout()
+	LoginSSO(showURL func(url string)) error
+	Register(user, password string, prompter UIAPrompter) error
+	ChangePassword(newPassword string, prompter UIAPrompter) error
+	GetPresence(userID string) *Presence
+	SetPresence(presence string)
+
+	GetProfile(userID string) (*UserProfile, error)
+	SetDisplayName(name string) error
+	SetRoomNick(roomID, name string) error
+	SetAvatar(roomID, pathOrMXC string) (string, error)
+	GetUserDevices(userID string) ([]*Device, error)
+	GetSharedRooms(userID string) []*rooms.Room
+	GetDirectChat(userID string) *rooms.Room
+	CreateDirectChat(userID string) (*rooms.Room, error)
+	SetPowerLevel(roomID, userID string, level int) error
+	SetPinned(roomID, eventID string, pinned bool) error
+	SendPollResponse(roomID, pollID string, unstable bool, answerIDs []string) error
+	EndPoll(roomID, pollID string, unstable bool) error
+
+	PushRules() *pushrules.PushRuleset
+	SetRoomNotificationLevel(roomID string, level pushrules.RoomNotificationLevel) error
+	AddKeyword(keyword string) error
+	RemoveKeyword(keyword string) error
+	SetPushRuleEnabled(ruleType pushrules.PushRuleType, ruleID string, enabled bool) error
+	SetIgnored(userID string, ignored bool) error
+	IsIgnored(userID string) bool
+	GetIgnoredUsers() []string
+
+	GetDevices() ([]*Device, error)
+	RenameDevice(deviceID, name string) error
+	DeleteDevices(deviceIDs []string, prompter UIAPrompter) error
+	Logout(all bool)
 
 	SendPreferencesToMatrix()
-	PrepareMarkdownMessage(roomID string, msgtype mautrix.MessageType, message string) *mautrix.Event
+	PrepareMarkdownMessage(roomID string, msgtype mautrix.MessageType, message string, mentions ...pills.Pill) *mautrix.Event
 	SendEvent(event *mautrix.Event) (string, error)
+	Redact(roomID, eventID, reason string) error
 	SendTyping(roomID string, typing bool)
-	MarkRead(roomID, eventID string)
+	MarkRead(roomID, eventID string, moveFullyRead bool)
 	JoinRoom(roomID, server string) (*rooms.Room, error)
 	LeaveRoom(roomID string) error
 
@@ -49,3 +90,53 @@ type MatrixContainer interface {
 	GetDownloadURL(homeserver, fileID string) string
 	GetCachePath(homeserver, fileID string) string
 }
+
+// Login flow types supported by gomuks.
+const (
+	LoginFlowPassword = "m.login.password"
+	LoginFlowToken    = "m.login.token"
+	LoginFlowSSO      = "m.login.sso"
+)
+
+// ErrUIACancelled is returned when the user cancels a user-interactive authentication prompt.
+var ErrUIACancelled = fmt.Errorf("authentication cancelled")
+
+// UIAPrompter asks the user for information needed to complete user-interactive authentication stages.
+type UIAPrompter interface {
+	// Prompt asks the user to enter a line of text, which is masked if mask is true.
+	// The second return value is false if the user cancelled the prompt.
+	Prompt(title, text string, mask bool) (string, bool)
+	// Confirm asks the user to confirm the given text. It returns false if the user cancelled.
+	Confirm(title, text string) bool
+}
+
+// Device is a device (session) of the current user.
+// See https://matrix.org/docs/spec/client_server/r0.5.0#get-matrix-client-r0-devices
+type Device struct {
+	DeviceID    string `json:"device_id"`
+	DisplayName string `json:"display_name"`
+	LastSeenIP  string `json:"last_seen_ip"`
+	LastSeenTS  int64  `json:"last_seen_ts"`
+}
+
+// UserProfile is the global profile of a user.
+// See https://matrix.org/docs/spec/client_server/r0.5.0#get-matrix-client-r0-profile-userid
+type UserProfile struct {
+	DisplayName string `json:"displayname"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// Presence states. See https://matrix.org/docs/spec/client_server/r0.5.0#presence
+const (
+	PresenceOnline      = "online"
+	PresenceUnavailable = "unavailable"
+	PresenceOffline     = "offline"
+)
+
+// Presence is the last known presence of a user.
+type Presence struct {
+	Presence        string
+	LastActive      time.Time
+	CurrentlyActive bool
+	StatusMsg       string
+}
@@ -43,7 +43,9 @@ type MainView interface {
 	GetRoom(roomID string) RoomView
 	AddRoom(room *rooms.Room)
 	RemoveRoom(room *rooms.Room)
-	SetRooms(rooms map[string]*rooms.Room)
+	SetRooms(userID string, rooms map[string]*rooms.Room)
+	RemoveAccountRooms(userID string)
+	IgnoredUsersChanged(userID string)
 
 	UpdateTags(room *rooms.Room)
 
@@ -0,0 +1,161 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The methods that can be used to copy text.
+const (
+	// MethodAuto uses both OSC 52 and a clipboard tool if one is available, as there's no way to know whether
+	// the terminal supports OSC 52.
+	MethodAuto = "auto"
+	// MethodOSC52 only uses the OSC 52 escape sequence.
+	MethodOSC52 = "osc52"
+	// MethodTool only uses a clipboard tool, either the configured command or one found in $PATH.
+	MethodTool = "tool"
+)
+
+// MaxOSC52Length is the maximum length of the base64-encoded text in an OSC 52 sequence.
+// Many terminals ignore longer sequences, so longer text is copied with a clipboard tool instead.
+const MaxOSC52Length = 100000
+
+// ErrNoTool is returned if no clipboard tool is available.
+var ErrNoTool = errors.New("no clipboard tool found (install wl-copy, xclip or xsel)")
+
+// Clipboard copies text with the configured method.
+type Clipboard struct {
+	// The copy method. Empty means MethodAuto.
+	Method string
+	// The clipboard tool command, which receives the text in stdin. Empty means detecting the tool automatically.
+	Command []string
+	// The terminal that OSC 52 sequences are written to. Nil means stdout.
+	Terminal io.Writer
+}
+
+// Copy copies the given text to the clipboard and returns the name of the method that was used.
+func (cb *Clipboard) Copy(text string) (string, error) {
+	switch cb.Method {
+	case MethodOSC52:
+		return "OSC 52", cb.copyOSC52(text)
+	case MethodTool:
+		return cb.copyTool(text)
+	case MethodAuto, "":
+		oscErr := cb.copyOSC52(text)
+		tool, toolErr := cb.copyTool(text)
+		switch {
+		case oscErr == nil && toolErr == nil:
+			return tool + " and OSC 52", nil
+		case toolErr == nil:
+			return tool, nil
+		case oscErr == nil && toolErr == ErrNoTool:
+			// The terminal doesn't respond to OSC 52, so it's unknown whether the text was actually copied.
+			return "OSC 52 (if supported by the terminal)", nil
+		case oscErr == nil:
+			return fmt.Sprintf("OSC 52 (if supported by the terminal, %v)", toolErr), nil
+		default:
+			return "", fmt.Errorf("OSC 52 failed (%v) and %v", oscErr, toolErr)
+		}
+	default:
+		return "", fmt.Errorf("unknown clipboard method %s", cb.Method)
+	}
+}
+
+// isTerminal returns whether or not the given file is a character device, i.e. most likely a terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// OSC52 returns the escape sequence that sets the clipboard to the given text. Inside tmux, the sequence
+// is wrapped in a passthrough sequence so that it reaches the outer terminal.
+func OSC52(text string) string {
+	sequence := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	if len(os.Getenv("TMUX")) > 0 {
+		sequence = "\x1bPtmux;" + strings.Replace(sequence, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	}
+	return sequence
+}
+
+func (cb *Clipboard) copyOSC52(text string) error {
+	if base64.StdEncoding.EncodedLen(len(text)) > MaxOSC52Length {
+		return fmt.Errorf("text is too long for OSC 52")
+	}
+	terminal := cb.Terminal
+	if terminal == nil {
+		if !isTerminal(os.Stdout) {
+			return fmt.Errorf("stdout is not a terminal")
+		}
+		terminal = os.Stdout
+	}
+	_, err := io.WriteString(terminal, OSC52(text))
+	return err
+}
+
+// findTool returns the command of the first available clipboard tool for the current session.
+func findTool() []string {
+	var candidates [][]string
+	switch {
+	case runtime.GOOS == "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case runtime.GOOS == "windows":
+		candidates = [][]string{{"clip.exe"}}
+	default:
+		if len(os.Getenv("WAYLAND_DISPLAY")) > 0 {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		if len(os.Getenv("DISPLAY")) > 0 {
+			candidates = append(candidates,
+				[]string{"xclip", "-selection", "clipboard"},
+				[]string{"xsel", "--clipboard", "--input"})
+		}
+		// Clipboard of the Windows host in WSL.
+		candidates = append(candidates, []string{"clip.exe"})
+	}
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func (cb *Clipboard) copyTool(text string) (string, error) {
+	command := cb.Command
+	if len(command) == 0 {
+		command = findTool()
+		if len(command) == 0 {
+			return "", ErrNoTool
+		}
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	// The output isn't captured, because tools like xclip and wl-copy fork a process that stays in the
+	// background to own the clipboard and would keep the output pipes open.
+	if err := cmd.Run(); err != nil {
+		return command[0], fmt.Errorf("%s failed: %v", command[0], err)
+	}
+	return command[0], nil
+}
@@ -0,0 +1,182 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnv sets the given environment variable and returns a function that restores the old value.
+func setEnv(key, value string) func() {
+	old, existed := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if existed {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// noTools makes findTool find nothing by emptying $PATH.
+func noTools() func() {
+	restorePath := setEnv("PATH", "")
+	restoreWayland := setEnv("WAYLAND_DISPLAY", "")
+	restoreDisplay := setEnv("DISPLAY", "")
+	return func() {
+		restorePath()
+		restoreWayland()
+		restoreDisplay()
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestOSC52(t *testing.T) {
+	defer setEnv("TMUX", "")()
+	assert.Equal(t, "\x1b]52;c;aGVsbG8=\x07", OSC52("hello"))
+}
+
+func TestOSC52_Tmux(t *testing.T) {
+	defer setEnv("TMUX", "/tmp/tmux-1000/default,1234,0")()
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\x07\x1b\\", OSC52("hello"))
+}
+
+func TestClipboard_Copy_OSC52(t *testing.T) {
+	defer setEnv("TMUX", "")()
+	var terminal bytes.Buffer
+	cb := Clipboard{Method: MethodOSC52, Terminal: &terminal}
+	method, err := cb.Copy("hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "OSC 52", method)
+	assert.Equal(t, OSC52("hello"), terminal.String())
+}
+
+func TestClipboard_Copy_OSC52_TooLong(t *testing.T) {
+	var terminal bytes.Buffer
+	cb := Clipboard{Method: MethodOSC52, Terminal: &terminal}
+	// The longest text that still fits in the limit when encoded.
+	maxText := strings.Repeat("a", MaxOSC52Length/4*3)
+	assert.Equal(t, MaxOSC52Length, base64.StdEncoding.EncodedLen(len(maxText)))
+	_, err := cb.Copy(maxText)
+	assert.Nil(t, err)
+
+	terminal.Reset()
+	_, err = cb.Copy(maxText + "a")
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, terminal.Len())
+}
+
+func TestClipboard_Copy_Tool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	output, err := os.Create(filepath.Join(dir, "clipboard"))
+	assert.Nil(t, err)
+	defer output.Close()
+
+	var terminal bytes.Buffer
+	cb := Clipboard{Method: MethodTool, Command: []string{"sh", "-c", "cat > \"$0\"", output.Name()}, Terminal: &terminal}
+	method, err := cb.Copy("hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "sh", method)
+	data, err := ioutil.ReadFile(output.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, 0, terminal.Len(), "the tool method shouldn't write OSC 52")
+}
+
+func TestClipboard_Copy_ToolFails(t *testing.T) {
+	cb := Clipboard{Method: MethodTool, Command: []string{"false"}}
+	method, err := cb.Copy("hello")
+	assert.Equal(t, "false", method)
+	assert.NotNil(t, err)
+}
+
+func TestClipboard_Copy_NoTool(t *testing.T) {
+	defer noTools()()
+	cb := Clipboard{Method: MethodTool}
+	_, err := cb.Copy("hello")
+	assert.Equal(t, ErrNoTool, err)
+}
+
+func TestClipboard_Copy_Auto(t *testing.T) {
+	defer setEnv("TMUX", "")()
+	var terminal bytes.Buffer
+	cb := Clipboard{Command: []string{"cat"}, Terminal: &terminal}
+	method, err := cb.Copy("hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "cat and OSC 52", method)
+	assert.Equal(t, OSC52("hello"), terminal.String())
+}
+
+func TestClipboard_Copy_Auto_OnlyTool(t *testing.T) {
+	cb := Clipboard{Method: MethodAuto, Command: []string{"cat"}, Terminal: failingWriter{}}
+	method, err := cb.Copy("hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "cat", method)
+}
+
+func TestClipboard_Copy_Auto_OnlyOSC52(t *testing.T) {
+	defer noTools()()
+	cb := Clipboard{Method: MethodAuto, Terminal: &bytes.Buffer{}}
+	method, err := cb.Copy("hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "OSC 52 (if supported by the terminal)", method)
+}
+
+func TestClipboard_Copy_Auto_ToolFails(t *testing.T) {
+	cb := Clipboard{Method: MethodAuto, Command: []string{"false"}, Terminal: &bytes.Buffer{}}
+	method, err := cb.Copy("hello")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(method, "OSC 52 (if supported by the terminal, false failed: "), method)
+}
+
+func TestClipboard_Copy_Auto_BothFail(t *testing.T) {
+	cb := Clipboard{Method: MethodAuto, Command: []string{"false"}, Terminal: failingWriter{}}
+	_, err := cb.Copy("hello")
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "OSC 52 failed (write failed) and false failed: "), err.Error())
+}
+
+func TestClipboard_Copy_Auto_TooLongUsesTool(t *testing.T) {
+	var terminal bytes.Buffer
+	cb := Clipboard{Command: []string{"cat"}, Terminal: &terminal}
+	method, err := cb.Copy(strings.Repeat("a", MaxOSC52Length))
+	assert.Nil(t, err)
+	assert.Equal(t, "cat", method)
+	assert.Equal(t, 0, terminal.Len())
+}
+
+func TestClipboard_Copy_UnknownMethod(t *testing.T) {
+	cb := Clipboard{Method: "foo"}
+	_, err := cb.Copy("hello")
+	assert.NotNil(t, err)
+}
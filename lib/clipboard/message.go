@@ -0,0 +1,68 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package clipboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The parts of a message that can be copied.
+const (
+	PartText   = "text"
+	PartHTML   = "html"
+	PartID     = "id"
+	PartSource = "source"
+)
+
+// Message contains the parts of a Matrix message that can be copied.
+type Message struct {
+	Body          string
+	FormattedBody string
+	EventID       string
+	// The raw JSON of the event.
+	Source json.RawMessage
+}
+
+// Part returns the given part of the message. The source is indented for readability.
+func (msg Message) Part(part string) (string, error) {
+	switch part {
+	case PartText:
+		return msg.Body, nil
+	case PartHTML:
+		if len(msg.FormattedBody) == 0 {
+			return "", fmt.Errorf("message has no HTML")
+		}
+		return msg.FormattedBody, nil
+	case PartID:
+		if len(msg.EventID) == 0 {
+			return "", fmt.Errorf("message has no event ID")
+		}
+		return msg.EventID, nil
+	case PartSource:
+		if len(msg.Source) == 0 {
+			return "", fmt.Errorf("message has no source")
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, msg.Source, "", "  "); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		return "", fmt.Errorf("unknown part %s", part)
+	}
+}
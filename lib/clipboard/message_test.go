@@ -0,0 +1,72 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package clipboard_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/lib/clipboard"
+)
+
+var testMessage = clipboard.Message{
+	Body:          "hello **world**",
+	FormattedBody: "hello <strong>world</strong>",
+	EventID:       "$foo:example.com",
+	Source:        json.RawMessage(`{"type":"m.room.message","content":{"body":"hello **world**"}}`),
+}
+
+func TestMessage_Part(t *testing.T) {
+	text, err := testMessage.Part(clipboard.PartText)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello **world**", text)
+
+	html, err := testMessage.Part(clipboard.PartHTML)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello <strong>world</strong>", html)
+
+	id, err := testMessage.Part(clipboard.PartID)
+	assert.Nil(t, err)
+	assert.Equal(t, "$foo:example.com", id)
+
+	source, err := testMessage.Part(clipboard.PartSource)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"type\": \"m.room.message\",\n  \"content\": {\n    \"body\": \"hello **world**\"\n  }\n}", source)
+
+	_, err = testMessage.Part("foo")
+	assert.NotNil(t, err)
+}
+
+func TestMessage_Part_Missing(t *testing.T) {
+	msg := clipboard.Message{Body: "hello"}
+	text, err := msg.Part(clipboard.PartText)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", text)
+	_, err = msg.Part(clipboard.PartHTML)
+	assert.EqualError(t, err, "message has no HTML")
+	_, err = msg.Part(clipboard.PartID)
+	assert.EqualError(t, err, "message has no event ID")
+	_, err = msg.Part(clipboard.PartSource)
+	assert.EqualError(t, err, "message has no source")
+}
+
+func TestMessage_Part_InvalidSource(t *testing.T) {
+	msg := clipboard.Message{Source: json.RawMessage("{not json")}
+	_, err := msg.Part(clipboard.PartSource)
+	assert.NotNil(t, err)
+}
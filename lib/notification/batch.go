@@ -0,0 +1,126 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Batcher is a Notifier that combines notifications from the same room.
+// The first notification is sent immediately, after which further notifications from the room
+// are collected for the duration of the window and sent as a single summary notification.
+// Critical notifications are never delayed.
+type Batcher struct {
+	Notifier
+	window time.Duration
+	// OnError is called when sending a delayed notification fails.
+	OnError func(err error)
+
+	rooms map[string]*roomBatch
+	lock  sync.Mutex
+}
+
+type roomBatch struct {
+	count int
+	last  Notification
+	sound bool
+	timer *time.Timer
+}
+
+// NewBatcher wraps the given notifier in a Batcher. If the window is not positive, the notifier is returned as-is.
+func NewBatcher(notifier Notifier, window time.Duration) Notifier {
+	if window <= 0 {
+		return notifier
+	}
+	return &Batcher{
+		Notifier: notifier,
+		window:   window,
+		rooms:    make(map[string]*roomBatch),
+	}
+}
+
+func (batcher *Batcher) Send(notif Notification) error {
+	if notif.Critical || len(notif.RoomID) == 0 {
+		return batcher.Notifier.Send(notif)
+	}
+	batcher.lock.Lock()
+	batch, ok := batcher.rooms[notif.RoomID]
+	if ok {
+		batch.count++
+		batch.last = notif
+		batch.sound = batch.sound || notif.Sound
+		batcher.lock.Unlock()
+		return nil
+	}
+	batcher.rooms[notif.RoomID] = &roomBatch{timer: batcher.startTimer(notif.RoomID)}
+	batcher.lock.Unlock()
+	return batcher.Notifier.Send(notif)
+}
+
+func (batcher *Batcher) startTimer(roomID string) *time.Timer {
+	return time.AfterFunc(batcher.window, func() {
+		batcher.flush(roomID)
+	})
+}
+
+// flush sends the notifications collected during the window of the given room.
+// If there were any, a new window is started so that busy rooms produce at most one notification per window.
+func (batcher *Batcher) flush(roomID string) {
+	batcher.lock.Lock()
+	batch, ok := batcher.rooms[roomID]
+	if !ok {
+		batcher.lock.Unlock()
+		return
+	} else if batch.count == 0 {
+		delete(batcher.rooms, roomID)
+		batcher.lock.Unlock()
+		return
+	}
+	notif := batch.summary()
+	batcher.rooms[roomID] = &roomBatch{timer: batcher.startTimer(roomID)}
+	batcher.lock.Unlock()
+
+	if err := batcher.Notifier.Send(notif); err != nil && batcher.OnError != nil {
+		batcher.OnError(err)
+	}
+}
+
+// summary returns the last notification of the batch, or a notification about the number
+// of messages if the batch contains more than one.
+func (batch *roomBatch) summary() Notification {
+	notif := batch.last
+	notif.Sound = batch.sound
+	if batch.count > 1 {
+		notif.Title = notif.RoomName
+		notif.Body = fmt.Sprintf("%d new messages in %s", batch.count, notif.RoomName)
+		notif.Sender = ""
+	}
+	return notif
+}
+
+// Close stops all pending batches without sending them and closes the wrapped notifier.
+func (batcher *Batcher) Close() error {
+	batcher.lock.Lock()
+	for roomID, batch := range batcher.rooms {
+		batch.timer.Stop()
+		delete(batcher.rooms, roomID)
+	}
+	batcher.lock.Unlock()
+	return batcher.Notifier.Close()
+}
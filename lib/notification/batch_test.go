@@ -0,0 +1,167 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package notification
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNotifier struct {
+	sent   []Notification
+	err    error
+	closed bool
+	lock   sync.Mutex
+}
+
+func (notifier *fakeNotifier) Send(notif Notification) error {
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+	notifier.sent = append(notifier.sent, notif)
+	return notifier.err
+}
+
+func (notifier *fakeNotifier) Close() error {
+	notifier.closed = true
+	return nil
+}
+
+func (notifier *fakeNotifier) Sent() []Notification {
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+	return append([]Notification{}, notifier.sent...)
+}
+
+const testWindow = 50 * time.Millisecond
+
+func message(roomID, sender, body string) Notification {
+	return Notification{
+		Title:    sender + " (Room)",
+		Body:     body,
+		Sender:   sender,
+		RoomID:   roomID,
+		RoomName: "Room",
+	}
+}
+
+func TestNewBatcher_Disabled(t *testing.T) {
+	fake := &fakeNotifier{}
+	assert.Equal(t, fake, NewBatcher(fake, 0))
+}
+
+func TestBatcher_Combines(t *testing.T) {
+	fake := &fakeNotifier{}
+	batcher := NewBatcher(fake, testWindow)
+	defer batcher.Close()
+
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "first")))
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@b:example.com", "second")))
+	third := message("!foo:example.com", "@a:example.com", "third")
+	third.Sound = true
+	assert.Nil(t, batcher.Send(third))
+	// The first notification is sent immediately and the rest are held back until the window ends.
+	assert.Len(t, fake.Sent(), 1)
+	assert.Equal(t, "first", fake.Sent()[0].Body)
+
+	time.Sleep(testWindow * 3)
+	sent := fake.Sent()
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "Room", sent[1].Title)
+		assert.Equal(t, "2 new messages in Room", sent[1].Body)
+		assert.Equal(t, "", sent[1].Sender)
+		assert.True(t, sent[1].Sound)
+	}
+}
+
+func TestBatcher_SingleDelayed(t *testing.T) {
+	fake := &fakeNotifier{}
+	batcher := NewBatcher(fake, testWindow)
+	defer batcher.Close()
+
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "first")))
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@b:example.com", "second")))
+	time.Sleep(testWindow * 3)
+	sent := fake.Sent()
+	if assert.Len(t, sent, 2) {
+		// A single held back message is sent as is.
+		assert.Equal(t, message("!foo:example.com", "@b:example.com", "second"), sent[1])
+	}
+
+	// The window has passed without new messages, so the next one is sent immediately again.
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "third")))
+	assert.Len(t, fake.Sent(), 3)
+}
+
+func TestBatcher_SeparateRooms(t *testing.T) {
+	fake := &fakeNotifier{}
+	batcher := NewBatcher(fake, testWindow)
+	defer batcher.Close()
+
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "first")))
+	assert.Nil(t, batcher.Send(message("!bar:example.com", "@a:example.com", "second")))
+	assert.Len(t, fake.Sent(), 2)
+}
+
+func TestBatcher_CriticalNotBatched(t *testing.T) {
+	fake := &fakeNotifier{}
+	batcher := NewBatcher(fake, testWindow)
+	defer batcher.Close()
+
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "first")))
+	critical := message("!foo:example.com", "@a:example.com", "@user: look")
+	critical.Critical = true
+	assert.Nil(t, batcher.Send(critical))
+	assert.Len(t, fake.Sent(), 2)
+}
+
+func TestBatcher_OnError(t *testing.T) {
+	fake := &fakeNotifier{}
+	batcher := NewBatcher(fake, testWindow).(*Batcher)
+	defer batcher.Close()
+	errs := make(chan error, 1)
+	batcher.OnError = func(err error) {
+		errs <- err
+	}
+
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "first")))
+	fake.lock.Lock()
+	fake.err = errors.New("oops")
+	fake.lock.Unlock()
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "second")))
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "oops")
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError was not called")
+	}
+}
+
+func TestBatcher_Close(t *testing.T) {
+	fake := &fakeNotifier{}
+	batcher := NewBatcher(fake, testWindow)
+
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "first")))
+	assert.Nil(t, batcher.Send(message("!foo:example.com", "@a:example.com", "second")))
+	assert.Nil(t, batcher.Close())
+	assert.True(t, fake.closed)
+	time.Sleep(testWindow * 3)
+	// Held back notifications are dropped when the batcher is closed.
+	assert.Len(t, fake.Sent(), 1)
+}
@@ -0,0 +1,48 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"io"
+	"os"
+)
+
+// BellNotifier rings the terminal bell. Most terminals mark the window as urgent
+// or play a sound when the bell rings in an unfocused window.
+type BellNotifier struct {
+	// Beep rings the bell. If it's set, it's used instead of writing to stdout, so that
+	// the terminal UI can ring the bell without interfering with drawing the screen.
+	Beep func() error
+
+	output io.Writer
+}
+
+func NewBellNotifier() *BellNotifier {
+	return &BellNotifier{output: os.Stdout}
+}
+
+func (notifier *BellNotifier) Send(notif Notification) error {
+	if notifier.Beep != nil {
+		return notifier.Beep()
+	}
+	_, err := notifier.output.Write([]byte{'\a'})
+	return err
+}
+
+func (notifier *BellNotifier) Close() error {
+	return nil
+}
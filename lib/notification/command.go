@@ -0,0 +1,82 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// CommandTimeout is how long a notification command may run before it's killed.
+const CommandTimeout = 10 * time.Second
+
+// CommandNotifier runs an external command for each notification.
+// The notification is written to the stdin of the command as JSON.
+type CommandNotifier struct {
+	// OnError is called when the command fails after it has been started.
+	OnError func(err error)
+
+	command []string
+	timeout time.Duration
+}
+
+func NewCommandNotifier(command []string) (*CommandNotifier, error) {
+	if len(command) == 0 || len(command[0]) == 0 {
+		return nil, fmt.Errorf("no notification command configured")
+	}
+	return &CommandNotifier{command: command, timeout: CommandTimeout}, nil
+}
+
+// Send starts the command and returns without waiting for it to exit, as notifications are sent
+// while handling sync responses. Errors from the running command are passed to OnError.
+func (notifier *CommandNotifier) Send(notif Notification) error {
+	data, err := json.Marshal(&notif)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifier.timeout)
+	cmd := exec.CommandContext(ctx, notifier.command[0], notifier.command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err = cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+	go func() {
+		defer cancel()
+		err := cmd.Wait()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("notification command timed out after %v", notifier.timeout)
+		} else if err != nil && output.Len() > 0 {
+			err = fmt.Errorf("%v: %s", err, bytes.TrimSpace(output.Bytes()))
+		}
+		if err != nil && notifier.OnError != nil {
+			notifier.OnError(err)
+		}
+	}()
+	return nil
+}
+
+func (notifier *CommandNotifier) Close() error {
+	return nil
+}
@@ -0,0 +1,165 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusNotifyInterface = "org.freedesktop.Notifications"
+	dbusNotifyPath      = dbus.ObjectPath("/org/freedesktop/Notifications")
+
+	// The key of the action that opens the room of the notification.
+	// The default action is invoked when the notification itself is clicked.
+	actionDefault  = "default"
+	actionOpenRoom = "open-room"
+
+	urgencyLow      = byte(0)
+	urgencyNormal   = byte(1)
+	urgencyCritical = byte(2)
+)
+
+// DBusNotifier sends notifications to the org.freedesktop.Notifications service on the session bus.
+// Notifications have an "open room" action if the notification server supports actions.
+type DBusNotifier struct {
+	conn       *dbus.Conn
+	onAction   ActionHandler
+	hasActions bool
+	signals    chan *dbus.Signal
+
+	// The room ID of each notification that hasn't been closed yet.
+	rooms     map[uint32]string
+	roomsLock sync.Mutex
+}
+
+// NewDBusNotifier connects to the session bus and checks that a notification server is running.
+func NewDBusNotifier(onAction ActionHandler) (*DBusNotifier, error) {
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	} else if err = conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var capabilities []string
+	err = conn.Object(dbusNotifyInterface, dbusNotifyPath).
+		Call(dbusNotifyInterface+".GetCapabilities", 0).
+		Store(&capabilities)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	notifier := &DBusNotifier{
+		conn:     conn,
+		onAction: onAction,
+		rooms:    make(map[uint32]string),
+	}
+	for _, capability := range capabilities {
+		if capability == "actions" {
+			notifier.hasActions = onAction != nil
+		}
+	}
+	if notifier.hasActions {
+		err = conn.AddMatchSignal(dbus.WithMatchInterface(dbusNotifyInterface), dbus.WithMatchObjectPath(dbusNotifyPath))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		notifier.signals = make(chan *dbus.Signal, 16)
+		conn.Signal(notifier.signals)
+		go notifier.handleSignals()
+	}
+	return notifier, nil
+}
+
+func (notifier *DBusNotifier) handleSignals() {
+	for signal := range notifier.signals {
+		if len(signal.Body) < 2 {
+			continue
+		}
+		id, ok := signal.Body[0].(uint32)
+		if !ok {
+			continue
+		}
+		switch signal.Name {
+		case dbusNotifyInterface + ".ActionInvoked":
+			action, _ := signal.Body[1].(string)
+			notifier.roomsLock.Lock()
+			roomID, ok := notifier.rooms[id]
+			notifier.roomsLock.Unlock()
+			if ok && (action == actionDefault || action == actionOpenRoom) {
+				notifier.onAction(roomID)
+			}
+		case dbusNotifyInterface + ".NotificationClosed":
+			notifier.roomsLock.Lock()
+			delete(notifier.rooms, id)
+			notifier.roomsLock.Unlock()
+		}
+	}
+}
+
+func (notifier *DBusNotifier) Send(notif Notification) error {
+	var actions []string
+	if notifier.hasActions && len(notif.RoomID) > 0 {
+		actions = []string{actionDefault, "Open room", actionOpenRoom, "Open room"}
+	}
+	hints := map[string]dbus.Variant{
+		"category":      dbus.MakeVariant("im.received"),
+		"desktop-entry": dbus.MakeVariant("gomuks"),
+		"urgency":       dbus.MakeVariant(urgencyLow),
+	}
+	if notif.Critical {
+		hints["urgency"] = dbus.MakeVariant(urgencyCritical)
+	} else if notif.Sound {
+		hints["urgency"] = dbus.MakeVariant(urgencyNormal)
+	}
+	if notif.Sound {
+		// The notification server picks the sound file from the theme, so no paths are needed.
+		soundName := "message-new-instant"
+		if notif.Critical {
+			soundName = "complete"
+		}
+		hints["sound-name"] = dbus.MakeVariant(soundName)
+	} else {
+		hints["suppress-sound"] = dbus.MakeVariant(true)
+	}
+	var id uint32
+	err := notifier.conn.Object(dbusNotifyInterface, dbusNotifyPath).
+		Call(dbusNotifyInterface+".Notify", 0, "gomuks", uint32(0), "", notif.Title, notif.Body, actions, hints, int32(-1)).
+		Store(&id)
+	if err != nil {
+		return err
+	}
+	if len(actions) > 0 {
+		notifier.roomsLock.Lock()
+		notifier.rooms[id] = notif.RoomID
+		notifier.roomsLock.Unlock()
+	}
+	return nil
+}
+
+// Close closes the connection to the session bus, which also stops the signal handler.
+func (notifier *DBusNotifier) Close() error {
+	return notifier.conn.Close()
+}
@@ -1,2 +1,4 @@
-// Package notification contains a simple cross-platform desktop notification sending function.
+// Package notification contains desktop notification backends: the native notifier of each platform,
+// D-Bus org.freedesktop.Notifications, an external command that receives notifications as JSON
+// and the terminal bell.
 package notification
@@ -0,0 +1,105 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Notification contains the data of a single desktop notification.
+type Notification struct {
+	// The title of the notification, usually the sender and the room name.
+	Title string `json:"title"`
+	// The text of the notification.
+	Body string `json:"body"`
+
+	Sender   string `json:"sender"`
+	RoomID   string `json:"room_id"`
+	RoomName string `json:"room_name"`
+	EventID  string `json:"event_id"`
+
+	// Whether or not the notification is important, e.g. because it's a highlight.
+	Critical bool `json:"critical"`
+	// Whether or not the notification should play a sound.
+	Sound bool `json:"sound"`
+}
+
+// ActionHandler is called when the user clicks the "open room" action of a notification.
+type ActionHandler func(roomID string)
+
+// Notifier is a notification backend.
+type Notifier interface {
+	Send(notif Notification) error
+	Close() error
+}
+
+// Notification backend names for the notifications.backend config option.
+const (
+	// BackendAuto uses D-Bus if it's available and the native notifier otherwise.
+	BackendAuto    = "auto"
+	BackendNative  = "native"
+	BackendDBus    = "dbus"
+	BackendCommand = "command"
+	BackendBell    = "bell"
+	BackendNone    = "none"
+)
+
+// New creates the notification backend with the given name. The command is only used
+// by the command backend and the action handler only by the D-Bus backend.
+func New(backend string, command []string, onAction ActionHandler) (Notifier, error) {
+	switch strings.ToLower(backend) {
+	case "", BackendAuto:
+		if notifier, err := NewDBusNotifier(onAction); err == nil {
+			return notifier, nil
+		}
+		return NativeNotifier{}, nil
+	case BackendNative:
+		return NativeNotifier{}, nil
+	case BackendDBus:
+		return NewDBusNotifier(onAction)
+	case BackendCommand:
+		return NewCommandNotifier(command)
+	case BackendBell:
+		return NewBellNotifier(), nil
+	case BackendNone:
+		return noopNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification backend %s", backend)
+	}
+}
+
+// NativeNotifier sends notifications with the platform-specific Send function.
+type NativeNotifier struct{}
+
+func (NativeNotifier) Send(notif Notification) error {
+	return Send(notif.Title, notif.Body, notif.Critical, notif.Sound)
+}
+
+func (NativeNotifier) Close() error {
+	return nil
+}
+
+type noopNotifier struct{}
+
+func (noopNotifier) Send(notif Notification) error {
+	return nil
+}
+
+func (noopNotifier) Close() error {
+	return nil
+}
@@ -0,0 +1,147 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	notifier, err := New("none", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, noopNotifier{}, notifier)
+
+	notifier, err = New("Bell", nil, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &BellNotifier{}, notifier)
+
+	notifier, err = New("native", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, NativeNotifier{}, notifier)
+
+	notifier, err = New("command", []string{"notify-handler", "--gomuks"}, nil)
+	assert.Nil(t, err)
+	if assert.IsType(t, &CommandNotifier{}, notifier) {
+		assert.Equal(t, []string{"notify-handler", "--gomuks"}, notifier.(*CommandNotifier).command)
+	}
+
+	_, err = New("command", nil, nil)
+	assert.NotNil(t, err)
+	_, err = New("foo", nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestNew_Auto(t *testing.T) {
+	// Without a session bus, auto falls back to the native notifier instead of failing.
+	notifier, err := New("", nil, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, notifier)
+	notifier.Close()
+}
+
+func waitForFile(t *testing.T, path string) []byte {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := ioutil.ReadFile(path); err == nil && len(data) > 0 {
+			return data
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s was not written", path)
+	return nil
+}
+
+func TestCommandNotifier_Send(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The first argument after the script is $0, the rest are $1 onwards.
+	notifier, err := NewCommandNotifier([]string{"sh", "-c", `echo "$1" > "$0.args"; cat > "$0"`, filepath.Join(dir, "notif"), "--gomuks"})
+	assert.Nil(t, err)
+	assert.Nil(t, notifier.Send(Notification{Title: "Sender (Room)", Body: "Hello", RoomID: "!foo:example.com", Sound: true}))
+
+	var notif Notification
+	assert.Nil(t, json.Unmarshal(waitForFile(t, filepath.Join(dir, "notif")), &notif))
+	assert.Equal(t, Notification{Title: "Sender (Room)", Body: "Hello", RoomID: "!foo:example.com", Sound: true}, notif)
+	assert.Equal(t, "--gomuks\n", string(waitForFile(t, filepath.Join(dir, "notif.args"))))
+}
+
+func TestCommandNotifier_Send_Error(t *testing.T) {
+	notifier, err := NewCommandNotifier([]string{"sh", "-c", "echo oops >&2; exit 1"})
+	assert.Nil(t, err)
+	errs := make(chan error, 1)
+	notifier.OnError = func(err error) {
+		errs <- err
+	}
+	assert.Nil(t, notifier.Send(Notification{Title: "foo"}))
+	select {
+	case err = <-errs:
+		assert.True(t, strings.HasSuffix(err.Error(), ": oops"), err.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError was not called")
+	}
+}
+
+func TestCommandNotifier_Send_Timeout(t *testing.T) {
+	notifier, err := NewCommandNotifier([]string{"sleep", "10"})
+	assert.Nil(t, err)
+	notifier.timeout = 50 * time.Millisecond
+	errs := make(chan error, 1)
+	notifier.OnError = func(err error) {
+		errs <- err
+	}
+	start := time.Now()
+	assert.Nil(t, notifier.Send(Notification{Title: "foo"}))
+	assert.True(t, time.Since(start) < time.Second, "Send waited for the command")
+	select {
+	case err = <-errs:
+		assert.Contains(t, err.Error(), "timed out")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command was not killed")
+	}
+}
+
+func TestCommandNotifier_Send_NotFound(t *testing.T) {
+	notifier, err := NewCommandNotifier([]string{"/nonexistent/notify-handler"})
+	assert.Nil(t, err)
+	assert.NotNil(t, notifier.Send(Notification{Title: "foo"}))
+}
+
+func TestBellNotifier(t *testing.T) {
+	var output bytes.Buffer
+	notifier := &BellNotifier{output: &output}
+	assert.Nil(t, notifier.Send(Notification{Title: "foo"}))
+	assert.Equal(t, "\a", output.String())
+
+	beeps := 0
+	notifier.Beep = func() error {
+		beeps++
+		return nil
+	}
+	assert.Nil(t, notifier.Send(Notification{Title: "foo"}))
+	assert.Equal(t, 1, beeps)
+	assert.Equal(t, "\a", output.String())
+}
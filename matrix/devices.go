@@ -0,0 +1,62 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+)
+
+// GetDevices fetches the list of devices of the current user.
+//
+// If the ID of the current device isn't known (e.g. the session was created by an older version of gomuks),
+// it is fetched using /account/whoami first.
+func (c *Container) GetDevices() ([]*ifc.Device, error) {
+	if len(c.config.DeviceID) == 0 {
+		c.fetchDeviceID()
+	}
+	var resp struct {
+		Devices []*ifc.Device `json:"devices"`
+	}
+	_, err := c.client.MakeRequest("GET", c.client.BuildURL("devices"), nil, &resp)
+	return resp.Devices, err
+}
+
+// RenameDevice changes the display name of the given device of the current user.
+func (c *Container) RenameDevice(deviceID, name string) error {
+	_, err := c.client.MakeRequest("PUT", c.client.BuildURL("devices", deviceID), map[string]string{
+		"display_name": name,
+	}, nil)
+	return err
+}
+
+// fetchDeviceID fetches the ID of the current device from /account/whoami and stores it in the config.
+func (c *Container) fetchDeviceID() {
+	var resp struct {
+		UserID   string `json:"user_id"`
+		DeviceID string `json:"device_id"`
+	}
+	_, err := c.client.MakeRequest("GET", c.client.BuildURL("account", "whoami"), nil, &resp)
+	if err != nil {
+		debug.Print("Failed to fetch current device ID:", err)
+		return
+	} else if len(resp.DeviceID) == 0 {
+		return
+	}
+	c.config.DeviceID = resp.DeviceID
+	c.config.Save()
+}
@@ -0,0 +1,50 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func TestContainer_GetDevices_FetchesDeviceID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/_matrix/client/r0/account/whoami":
+			return mockResponse(http.StatusOK, `{"user_id": "@user:example.com", "device_id": "ABCDEF"}`), nil
+		case "/_matrix/client/r0/devices":
+			return mockResponse(http.StatusOK, `{"devices": [{"device_id": "ABCDEF"}, {"device_id": "GHIJKL"}]}`), nil
+		}
+		return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+	}), config: &config.Config{UserID: "@user:example.com", Dir: dir}}
+
+	devices, err := c.GetDevices()
+	assert.Nil(t, err)
+	assert.Len(t, devices, 2)
+	assert.Equal(t, "ABCDEF", c.config.DeviceID)
+}
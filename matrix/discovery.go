@@ -0,0 +1,160 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kennetanti/gomuks/debug"
+)
+
+// ClientWellKnown is the content of the .well-known/matrix/client file.
+// See https://matrix.org/docs/spec/client_server/r0.5.0#get-well-known-matrix-client
+type ClientWellKnown struct {
+	Homeserver struct {
+		BaseURL string `json:"base_url"`
+	} `json:"m.homeserver"`
+	IdentityServer struct {
+		BaseURL string `json:"base_url"`
+	} `json:"m.identity_server"`
+}
+
+// ErrInvalidWellKnown is returned by DiscoverHomeserver if the server has a .well-known file, but it is invalid.
+// Unlike other discovery errors, the client should not fall back to using the server name as the homeserver URL.
+type ErrInvalidWellKnown struct {
+	Reason string
+}
+
+func (err ErrInvalidWellKnown) Error() string {
+	return fmt.Sprintf("invalid .well-known/matrix/client: %s", err.Reason)
+}
+
+// ServerNameFromInput extracts the server name from a user ID (@user:example.com) or a plain server name.
+func ServerNameFromInput(input string) string {
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "@") {
+		colon := strings.IndexRune(input, ':')
+		if colon == -1 {
+			return ""
+		}
+		input = input[colon+1:]
+	}
+	return input
+}
+
+// DiscoverHomeserver resolves the client-server API base URL and identity server of the given server name using
+// .well-known/matrix/client. The homeserver URL is validated by requesting /_matrix/client/versions.
+//
+// If the server doesn't have a .well-known file, an error is returned and the caller may fall back to
+// https://<server name>. If the file exists but is invalid, an ErrInvalidWellKnown is returned.
+func DiscoverHomeserver(client *http.Client, serverName string) (*ClientWellKnown, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	wellKnownURL := url.URL{
+		Scheme: "https",
+		Host:   serverName,
+		Path:   "/.well-known/matrix/client",
+	}
+	resp, err := client.Get(wellKnownURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, wellKnownURL.String())
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var wellKnown ClientWellKnown
+	if err = json.Unmarshal(data, &wellKnown); err != nil {
+		return nil, ErrInvalidWellKnown{"failed to parse JSON"}
+	} else if len(wellKnown.Homeserver.BaseURL) == 0 {
+		return nil, ErrInvalidWellKnown{"m.homeserver base_url missing"}
+	}
+	wellKnown.Homeserver.BaseURL = strings.TrimRight(wellKnown.Homeserver.BaseURL, "/")
+	wellKnown.IdentityServer.BaseURL = strings.TrimRight(wellKnown.IdentityServer.BaseURL, "/")
+
+	if err = validateHomeserver(client, wellKnown.Homeserver.BaseURL); err != nil {
+		return nil, ErrInvalidWellKnown{err.Error()}
+	}
+	return &wellKnown, nil
+}
+
+// validateHomeserver checks that the given URL points to a Matrix homeserver by requesting the supported versions.
+func validateHomeserver(client *http.Client, baseURL string) error {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil || (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") {
+		return fmt.Errorf("homeserver URL %s is not a valid HTTP(S) URL", baseURL)
+	}
+	resp, err := client.Get(baseURL + "/_matrix/client/versions")
+	if err != nil {
+		return fmt.Errorf("failed to request versions from %s: %v", baseURL, err)
+	}
+	defer resp.Body.Close()
+	var versions struct {
+		Versions []string `json:"versions"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, baseURL)
+	} else if err = json.NewDecoder(resp.Body).Decode(&versions); err != nil || len(versions.Versions) == 0 {
+		return fmt.Errorf("%s did not return a list of versions", baseURL)
+	}
+	return nil
+}
+
+// DiscoverHomeserver resolves the homeserver URL of the given user ID or server name and stores it and the
+// identity server in the config. If the server doesn't have a .well-known file, https://<server name> is used
+// if it points to a Matrix homeserver.
+func (c *Container) DiscoverHomeserver(input string) error {
+	serverName := ServerNameFromInput(input)
+	if len(serverName) == 0 {
+		return fmt.Errorf("no server name in %s", input)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	if c.client != nil {
+		client = c.client.Client
+	}
+	wellKnown, err := DiscoverHomeserver(client, serverName)
+	if err != nil {
+		if _, ok := err.(ErrInvalidWellKnown); ok {
+			return err
+		}
+		debug.Printf("Homeserver discovery for %s failed, falling back to server name: %v", serverName, err)
+		fallbackURL := "https://" + serverName
+		if err = validateHomeserver(client, fallbackURL); err != nil {
+			return err
+		}
+		c.config.HS = fallbackURL
+		c.config.IdentityServer = ""
+		return nil
+	}
+	debug.Printf("Discovered homeserver %s and identity server %s for %s",
+		wellKnown.Homeserver.BaseURL, wellKnown.IdentityServer.BaseURL, serverName)
+	c.config.HS = wellKnown.Homeserver.BaseURL
+	c.config.IdentityServer = wellKnown.IdentityServer.BaseURL
+	return nil
+}
@@ -0,0 +1,119 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func newDiscoveryServer(wellKnown func(baseURL string) (int, string)) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/matrix/client":
+			status, body := wellKnown(server.URL)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		case "/_matrix/client/versions":
+			_, _ = w.Write([]byte(`{"versions": ["r0.5.0"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestServerNameFromInput(t *testing.T) {
+	assert.Equal(t, "maunium.net", ServerNameFromInput("@tulir:maunium.net"))
+	assert.Equal(t, "example.com:8448", ServerNameFromInput("@user:example.com:8448"))
+	assert.Equal(t, "maunium.net", ServerNameFromInput(" maunium.net "))
+	assert.Empty(t, ServerNameFromInput("@tulir"))
+}
+
+func TestDiscoverHomeserver(t *testing.T) {
+	server := newDiscoveryServer(func(baseURL string) (int, string) {
+		return http.StatusOK, fmt.Sprintf(`{"m.homeserver": {"base_url": "%s/"}, "m.identity_server": {"base_url": "https://vector.im"}}`, baseURL)
+	})
+	defer server.Close()
+
+	wellKnown, err := DiscoverHomeserver(server.Client(), strings.TrimPrefix(server.URL, "https://"))
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL, wellKnown.Homeserver.BaseURL)
+	assert.Equal(t, "https://vector.im", wellKnown.IdentityServer.BaseURL)
+}
+
+func TestDiscoverHomeserver_NotFound(t *testing.T) {
+	server := newDiscoveryServer(func(baseURL string) (int, string) {
+		return http.StatusNotFound, ""
+	})
+	defer server.Close()
+
+	_, err := DiscoverHomeserver(server.Client(), strings.TrimPrefix(server.URL, "https://"))
+	assert.NotNil(t, err)
+	_, isInvalid := err.(ErrInvalidWellKnown)
+	assert.False(t, isInvalid)
+}
+
+func TestDiscoverHomeserver_Invalid(t *testing.T) {
+	server := newDiscoveryServer(func(baseURL string) (int, string) {
+		return http.StatusOK, `{"m.homeserver": {}}`
+	})
+	defer server.Close()
+
+	_, err := DiscoverHomeserver(server.Client(), strings.TrimPrefix(server.URL, "https://"))
+	assert.IsType(t, ErrInvalidWellKnown{}, err)
+}
+
+func TestDiscoverHomeserver_NotAHomeserver(t *testing.T) {
+	server := newDiscoveryServer(func(baseURL string) (int, string) {
+		return http.StatusOK, `{"m.homeserver": {"base_url": "https://localhost:1"}}`
+	})
+	defer server.Close()
+
+	_, err := DiscoverHomeserver(server.Client(), strings.TrimPrefix(server.URL, "https://"))
+	assert.IsType(t, ErrInvalidWellKnown{}, err)
+}
+
+func TestContainer_DiscoverHomeserver_Fallback(t *testing.T) {
+	server := newDiscoveryServer(func(baseURL string) (int, string) {
+		return http.StatusNotFound, ""
+	})
+	defer server.Close()
+
+	c := Container{client: &mautrix.Client{Client: server.Client()}, config: &config.Config{IdentityServer: "https://vector.im"}}
+	assert.Nil(t, c.DiscoverHomeserver("@user:"+strings.TrimPrefix(server.URL, "https://")))
+	assert.Equal(t, server.URL, c.config.HS)
+	assert.Empty(t, c.config.IdentityServer)
+}
+
+func TestContainer_DiscoverHomeserver_FallbackNotAHomeserver(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	c := Container{client: &mautrix.Client{Client: server.Client()}, config: &config.Config{HS: "https://example.com"}}
+	assert.NotNil(t, c.DiscoverHomeserver(strings.TrimPrefix(server.URL, "https://")))
+	assert.Equal(t, "https://example.com", c.config.HS)
+}
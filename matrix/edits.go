@@ -0,0 +1,48 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/matrix/relations"
+)
+
+type editRelation struct {
+	RelType string `json:"rel_type"`
+	EventID string `json:"event_id"`
+}
+
+// editContent is the content of an edit. The embedded content is the fallback for clients that don't
+// support edits and the new content replaces the content of the edited event.
+type editContent struct {
+	*mautrix.Content
+	NewContent map[string]interface{} `json:"m.new_content"`
+	RelatesTo  editRelation           `json:"m.relates_to"`
+}
+
+func getEditContent(event *mautrix.Event) interface{} {
+	newContent, _ := event.Content.Raw["m.new_content"].(map[string]interface{})
+	return &editContent{
+		Content:    &event.Content,
+		NewContent: newContent,
+		RelatesTo: editRelation{
+			RelType: relations.RelReplace,
+			EventID: relations.GetEditTarget(&event.Content),
+		},
+	}
+}
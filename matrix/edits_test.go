@@ -0,0 +1,75 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/relations"
+)
+
+func TestContainer_SendEvent_Edit(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/_matrix/client/r0/rooms/!foo:example.com/send/m.room.message/") {
+			sent = parseBody(req)
+			return mockResponse(http.StatusOK, `{"event_id": "$edit"}`), nil
+		} else if req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/_matrix/client/r0/rooms/!foo:example.com/typing/") {
+			return mockResponse(http.StatusOK, `{}`), nil
+		}
+		return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	event := c.PrepareMarkdownMessage("!foo:example.com", "m.text", "**fixed**")
+	relations.SetEditTarget(&event.Content, "$orig")
+	assert.Equal(t, "$orig", relations.GetEditTarget(&event.Content))
+	evtID, err := c.SendEvent(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "$edit", evtID)
+	assert.Equal(t, "* **fixed**", sent["body"])
+	assert.Equal(t, "* <p><strong>fixed</strong></p>", sent["formatted_body"])
+	assert.Equal(t, map[string]interface{}{
+		"msgtype":        "m.text",
+		"body":           "**fixed**",
+		"format":         "org.matrix.custom.html",
+		"formatted_body": "<p><strong>fixed</strong></p>",
+	}, sent["m.new_content"])
+	assert.Equal(t, map[string]interface{}{
+		"rel_type": "m.replace",
+		"event_id": "$orig",
+	}, sent["m.relates_to"])
+}
+
+func TestContainer_Redact(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/_matrix/client/r0/rooms/!foo:example.com/redact/$orig/") {
+			sent = parseBody(req)
+			return mockResponse(http.StatusOK, `{"event_id": "$redaction"}`), nil
+		}
+		return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+	})}
+
+	assert.Nil(t, c.Redact("!foo:example.com", "$orig", "typo"))
+	assert.Equal(t, map[string]interface{}{"reason": "typo"}, sent)
+}
@@ -0,0 +1,151 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/lib/open"
+)
+
+// SSOLoginTimeout is how long LoginSSO waits for the browser to redirect back with a login token.
+const SSOLoginTimeout = 5 * time.Minute
+
+type respLoginFlows struct {
+	Flows []struct {
+		Type string `json:"type"`
+	} `json:"flows"`
+}
+
+// GetLoginFlows fetches the login flow types that the homeserver in the config supports.
+//
+// If the client hasn't been initialized, a temporary client is used for the request.
+func (c *Container) GetLoginFlows() ([]string, error) {
+	if len(c.config.HS) == 0 {
+		return nil, fmt.Errorf("no homeserver in config")
+	}
+	client := c.client
+	if client == nil {
+		var err error
+		client, err = mautrix.NewClient(c.config.HS, "", "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var resp respLoginFlows
+	if _, err := client.MakeRequest("GET", client.BuildURL("login"), nil, &resp); err != nil {
+		return nil, err
+	}
+	flows := make([]string, len(resp.Flows))
+	for i, flow := range resp.Flows {
+		flows[i] = flow.Type
+	}
+	return flows, nil
+}
+
+// LoginSSO logs in using single sign-on.
+//
+// A HTTP listener is started on the loopback interface and the SSO redirect URL of the homeserver is opened in the
+// browser. After the user has logged in, the homeserver redirects the browser back to the listener with a login
+// token, which is then exchanged for an access token using LoginToken(). The given function is called with the URL
+// that the user should open, in case opening the browser automatically fails.
+//
+// The redirect URL contains a random state parameter, and redirects without the same state are rejected,
+// so that other pages can't make gomuks log in with a token of their choosing.
+func (c *Container) LoginSSO(showURL func(url string)) error {
+	state, err := randomState()
+	if err != nil {
+		return fmt.Errorf("failed to generate SSO state: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to start listener for SSO redirect: %v", err)
+	}
+	defer listener.Close()
+
+	tokens := make(chan string, 1)
+	server := &http.Server{Handler: ssoRedirectHandler(state, tokens)}
+	go func() {
+		defer debug.Recover()
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			debug.Print("SSO redirect listener errored:", err)
+		}
+	}()
+	defer func() {
+		_ = server.Shutdown(context.Background())
+	}()
+
+	redirectURL := fmt.Sprintf("http://%s/?state=%s", listener.Addr().String(), state)
+	ssoURL := c.client.BuildURLWithQuery([]string{"login", "sso", "redirect"}, map[string]string{
+		"redirectUrl": redirectURL,
+	})
+	debug.Print("Starting SSO login via", ssoURL)
+	if showURL != nil {
+		showURL(ssoURL)
+	}
+	if err = open.Open(ssoURL); err != nil {
+		debug.Print("Failed to open SSO URL in browser:", err)
+	}
+
+	select {
+	case token := <-tokens:
+		return c.LoginToken(token)
+	case <-time.After(SSOLoginTimeout):
+		return fmt.Errorf("timed out waiting for SSO login")
+	}
+}
+
+func randomState() (string, error) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(state), nil
+}
+
+// ssoRedirectHandler returns the handler for the SSO redirect listener, which sends the login token
+// of the first redirect with the given state to the given channel.
+func ssoRedirectHandler(state string, tokens chan<- string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("Invalid state. Please start the login from gomuks again."))
+			return
+		}
+		token := query.Get("loginToken")
+		if len(token) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Missing login token. Please try again."))
+			return
+		}
+		_, _ = w.Write([]byte("Login successful, you can now close this page and return to gomuks."))
+		select {
+		case tokens <- token:
+		default:
+		}
+	})
+}
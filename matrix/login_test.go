@@ -0,0 +1,63 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func TestContainer_GetLoginFlows(t *testing.T) {
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet || req.URL.Path != "/_matrix/client/r0/login" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		}
+		return mockResponse(http.StatusOK, `{"flows": [{"type": "m.login.password"}, {"type": "m.login.sso"}]}`), nil
+	}), config: &config.Config{HS: "https://example.com"}}
+
+	flows, err := c.GetLoginFlows()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"m.login.password", "m.login.sso"}, flows)
+}
+
+func TestSSORedirectHandler(t *testing.T) {
+	tokens := make(chan string, 1)
+	handler := ssoRedirectHandler("abcd", tokens)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?state=wrong&loginToken=evil", nil))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?loginToken=evil", nil))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Empty(t, tokens)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?state=abcd", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?state=abcd&loginToken=token", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "token", <-tokens)
+}
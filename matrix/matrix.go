@@ -18,6 +18,7 @@ package matrix
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -29,6 +30,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/tulir/mautrix-go"
@@ -37,6 +39,8 @@ import (
 	"github.com/kennetanti/gomuks/config"
 	"github.com/kennetanti/gomuks/debug"
 	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/matrix/pills"
+	"github.com/kennetanti/gomuks/matrix/polls"
 	"github.com/kennetanti/gomuks/matrix/pushrules"
 	"github.com/kennetanti/gomuks/matrix/rooms"
 )
@@ -53,16 +57,32 @@ type Container struct {
 	history *HistoryManager
 	running bool
 	stop    chan bool
+	// stopLock guards running so that Stop is safe to call more than once.
+	stopLock sync.Mutex
 
 	typing int64
+
+	// presenceLock guards both presence and ownPresence.
+	presence     map[string]*ifc.Presence
+	presenceLock sync.RWMutex
+	ownPresence  string
+
+	// Protects config.IgnoredUsers, which is read from the UI goroutine.
+	ignoredLock sync.RWMutex
+
+	// directChats is the content of the m.direct account data event (user ID -> room IDs).
+	directChats map[string][]string
+	directLock  sync.RWMutex
 }
 
-// NewContainer creates a new Container for the given Gomuks instance.
-func NewContainer(gmx ifc.Gomuks) *Container {
+// NewContainer creates a new Container for the given Gomuks instance and account config.
+func NewContainer(gmx ifc.Gomuks, cfg *config.Config) *Container {
 	c := &Container{
-		config: gmx.Config(),
+		config: cfg,
 		ui:     gmx.UI(),
 		gmx:    gmx,
+
+		presence: make(map[string]*ifc.Presence),
 	}
 
 	return c
@@ -73,6 +93,16 @@ func (c *Container) Client() *mautrix.Client {
 	return c.client
 }
 
+// Config returns the config of the account this Container is for.
+func (c *Container) Config() *config.Config {
+	return c.config
+}
+
+// isMainAccount returns whether or not this Container is for the main account.
+func (c *Container) isMainAccount() bool {
+	return c.gmx == nil || c.gmx.Matrix() == ifc.MatrixContainer(c)
+}
+
 type mxLogger struct{}
 
 func (log mxLogger) Debugfln(message string, args ...interface{}) {
@@ -130,40 +160,90 @@ func (c *Container) Initialized() bool {
 
 // Login sends a password login request with the given username and password.
 func (c *Container) Login(user, password string) error {
-	resp, err := c.client.Login(&mautrix.ReqLogin{
-		Type:                     "m.login.password",
+	return c.login(&mautrix.ReqLogin{
+		Type:                     ifc.LoginFlowPassword,
 		User:                     user,
 		Password:                 password,
 		InitialDeviceDisplayName: "gomuks",
 	})
+}
+
+// LoginToken sends a token login request with the given login token.
+func (c *Container) LoginToken(token string) error {
+	return c.login(&mautrix.ReqLogin{
+		Type:                     ifc.LoginFlowToken,
+		Token:                    token,
+		InitialDeviceDisplayName: "gomuks",
+	})
+}
+
+func (c *Container) login(req *mautrix.ReqLogin) error {
+	resp, err := c.client.Login(req)
 	if err != nil {
 		return err
 	}
-	c.client.SetCredentials(resp.UserID, resp.AccessToken)
-	c.config.UserID = resp.UserID
-	c.config.AccessToken = resp.AccessToken
+	c.finishLogin(resp.UserID, resp.AccessToken, resp.DeviceID)
+	return nil
+}
+
+// finishLogin stores the credentials of a successful login or registration and starts syncing.
+func (c *Container) finishLogin(userID, accessToken, deviceID string) {
+	c.client.SetCredentials(userID, accessToken)
+	c.config.UserID = userID
+	c.config.AccessToken = accessToken
+	c.config.DeviceID = deviceID
 	c.config.Save()
 
 	go c.Start()
-
-	return nil
 }
 
 // Logout revokes the access token, stops the syncer and calls the OnLogout() method of the UI.
-func (c *Container) Logout() {
-	c.client.Logout()
+// If all is true, all access tokens of the user are revoked, which logs out every session.
+//
+// Additional accounts are removed from gomuks entirely instead of returning to the login view.
+func (c *Container) Logout(all bool) {
+	if all {
+		_, err := c.client.MakeRequest("POST", c.client.BuildURL("logout", "all"), struct{}{}, nil)
+		if err != nil {
+			debug.Print("Failed to log out all sessions:", err)
+		}
+	} else {
+		c.client.Logout()
+	}
 	c.config.DeleteSession()
 	c.Stop()
 	c.client = nil
-	c.ui.OnLogout()
+	c.presenceLock.Lock()
+	c.ownPresence = ""
+	c.presenceLock.Unlock()
+	c.ui.MainView().RemoveAccountRooms(c.config.UserID)
+	if c.isMainAccount() {
+		c.ui.OnLogout()
+	} else {
+		c.gmx.RemoveAccount(c)
+		c.ui.Render()
+	}
 }
 
 // Stop stops the Matrix syncer.
+//
+// Calling Stop on a container that is not running does nothing.
 func (c *Container) Stop() {
-	if c.running {
-		debug.Print("Stopping Matrix container...")
-		c.stop <- true
+	c.stopLock.Lock()
+	defer c.stopLock.Unlock()
+	if !c.running {
+		return
+	}
+	c.running = false
+	debug.Print("Stopping Matrix container...")
+	select {
+	case c.stop <- true:
+	default:
+	}
+	if c.client != nil {
 		c.client.StopSync()
+	}
+	if c.history != nil {
 		debug.Print("Closing history manager...")
 		err := c.history.Close()
 		if err != nil {
@@ -192,6 +272,7 @@ func (c *Container) PushRules() *pushrules.PushRuleset {
 }
 
 var AccountDataGomuksPreferences = mautrix.NewEventType("net.maunium.gomuks.preferences")
+var AccountDataFullyRead = mautrix.NewEventType("m.fully_read")
 
 // OnLogin initializes the syncer and updates the room list.
 func (c *Container) OnLogin() {
@@ -206,13 +287,23 @@ func (c *Container) OnLogin() {
 	c.syncer.OnEventType(mautrix.StateCanonicalAlias, c.HandleMessage)
 	c.syncer.OnEventType(mautrix.StateTopic, c.HandleMessage)
 	c.syncer.OnEventType(mautrix.StateRoomName, c.HandleMessage)
+	c.syncer.OnEventType(mautrix.StatePinnedEvents, c.HandleMessage)
+	c.syncer.OnEventType(polls.EventPollStart, c.HandleMessage)
+	c.syncer.OnEventType(polls.EventPollResponse, c.HandleMessage)
+	c.syncer.OnEventType(polls.EventPollEnd, c.HandleMessage)
+	c.syncer.OnEventType(polls.EventUnstablePollStart, c.HandleMessage)
+	c.syncer.OnEventType(polls.EventUnstablePollResponse, c.HandleMessage)
+	c.syncer.OnEventType(polls.EventUnstablePollEnd, c.HandleMessage)
 	c.syncer.OnEventType(mautrix.StateMember, c.HandleMembership)
 	c.syncer.OnEventType(mautrix.EphemeralEventReceipt, c.HandleReadReceipt)
 	c.syncer.OnEventType(mautrix.EphemeralEventTyping, c.HandleTyping)
 	c.syncer.OnEventType(mautrix.AccountDataDirectChats, c.HandleDirectChatInfo)
 	c.syncer.OnEventType(mautrix.AccountDataPushRules, c.HandlePushRules)
 	c.syncer.OnEventType(mautrix.AccountDataRoomTags, c.HandleTag)
+	c.syncer.OnEventType(AccountDataFullyRead, c.HandleFullyRead)
 	c.syncer.OnEventType(AccountDataGomuksPreferences, c.HandlePreferences)
+	c.syncer.OnEventType(EventPresence, c.HandlePresence)
+	c.syncer.OnEventType(AccountDataIgnoredUserList, c.HandleIgnoredUsers)
 	c.syncer.InitDoneCallback = func() {
 		debug.Print("Initial sync done")
 		c.config.AuthCache.InitialSyncDone = true
@@ -223,7 +314,9 @@ func (c *Container) OnLogin() {
 	c.client.Syncer = c.syncer
 
 	debug.Print("Setting existing rooms")
-	c.ui.MainView().SetRooms(c.config.Rooms)
+	c.ui.MainView().SetRooms(c.config.UserID, c.config.Rooms)
+
+	go c.SetPresence(ifc.PresenceOnline)
 
 	debug.Print("OnLogin() done.")
 }
@@ -238,19 +331,22 @@ func (c *Container) Start() {
 		return
 	}
 
+	c.checkFilter()
+
 	debug.Print("Starting sync...")
+	c.stopLock.Lock()
 	c.running = true
+	c.stopLock.Unlock()
 	for {
 		select {
 		case <-c.stop:
 			debug.Print("Stopping sync...")
-			c.running = false
 			return
 		default:
-			if err := c.client.Sync(); err != nil {
+			if err := c.sync(); err != nil {
 				if httpErr, ok := err.(mautrix.HTTPError); ok && httpErr.Code == http.StatusUnauthorized {
 					debug.Print("Sync() errored with ", err, " -> logging out")
-					c.Logout()
+					c.Logout(false)
 				} else {
 					debug.Print("Sync() errored", err)
 				}
@@ -261,6 +357,65 @@ func (c *Container) Start() {
 	}
 }
 
+// checkFilter clears the stored filter ID if the filter JSON has changed since the filter was created,
+// so that the next sync creates a new filter.
+func (c *Container) checkFilter() {
+	filterHash := fmt.Sprintf("%x", sha256.Sum256(c.syncer.GetFilterJSON(c.client.UserID)))
+	if c.config.AuthCache.FilterHash != filterHash {
+		c.client.Store.SaveFilterID(c.client.UserID, "")
+		c.config.AuthCache.FilterHash = filterHash
+	}
+}
+
+// sync works like mautrix.Client.Sync, but also parses the unread notification counts of rooms,
+// which mautrix.RespSync doesn't include. It returns when Stop is called.
+func (c *Container) sync() error {
+	nextBatch := c.client.Store.LoadNextBatch(c.client.UserID)
+	filterID := c.client.Store.LoadFilterID(c.client.UserID)
+	if filterID == "" {
+		resFilter, err := c.client.CreateFilter(c.syncer.GetFilterJSON(c.client.UserID))
+		if err != nil {
+			return err
+		}
+		filterID = resFilter.FilterID
+		c.client.Store.SaveFilterID(c.client.UserID, filterID)
+	}
+	for len(c.stop) == 0 {
+		query := map[string]string{
+			"timeout": "30000",
+			"filter":  filterID,
+		}
+		if nextBatch != "" {
+			query["since"] = nextBatch
+		}
+		var res *SyncResponse
+		data, err := c.client.MakeRequest("GET", c.client.BuildURLWithQuery([]string{"sync"}, query), nil, nil)
+		if err == nil {
+			res, err = ParseSyncResponse(data)
+		}
+		if err != nil {
+			duration, err2 := c.syncer.OnFailedSync(nil, err)
+			if err2 != nil {
+				return err2
+			}
+			time.Sleep(duration)
+			continue
+		} else if len(c.stop) > 0 {
+			// Stop was called during the request, so the response is discarded.
+			return nil
+		}
+
+		// Like in mautrix, the token is saved before processing the response
+		// so that a malformed event can't make gomuks get stuck.
+		c.client.Store.SaveNextBatch(c.client.UserID, res.NextBatch)
+		if err = c.syncer.ProcessSyncResponse(res, nextBatch); err != nil {
+			return err
+		}
+		nextBatch = res.NextBatch
+	}
+	return nil
+}
+
 func (c *Container) HandlePreferences(source EventSource, evt *mautrix.Event) {
 	if source&EventSourceAccountData == 0 {
 		return
@@ -285,6 +440,13 @@ func (c *Container) SendPreferencesToMatrix() {
 	}
 }
 
+// showsRoom returns whether or not the room with the given ID is shown in the UI through this account.
+// Rooms that multiple accounts are in are only shown through one of them.
+func (c *Container) showsRoom(roomID string) bool {
+	roomView := c.ui.MainView().GetRoom(roomID)
+	return roomView != nil && roomView.MxRoom().SessionUserID == c.config.UserID
+}
+
 // HandleMessage is the event handler for the m.room.message timeline event.
 func (c *Container) HandleMessage(source EventSource, evt *mautrix.Event) {
 	if source&EventSourceLeave != 0 || source&EventSourceState != 0 {
@@ -294,7 +456,15 @@ func (c *Container) HandleMessage(source EventSource, evt *mautrix.Event) {
 
 	roomView := mainView.GetRoom(evt.RoomID)
 	if roomView == nil {
-		debug.Printf("Failed to handle event %v: No room view found.", evt)
+		mainView.AddRoom(c.GetRoom(evt.RoomID))
+		roomView = mainView.GetRoom(evt.RoomID)
+		if roomView == nil {
+			debug.Printf("Failed to handle event %v: No room view found.", evt)
+			return
+		}
+	}
+	if roomView.MxRoom().SessionUserID != c.config.UserID {
+		// The room is shown through another account that is also in the room.
 		return
 	}
 
@@ -307,9 +477,14 @@ func (c *Container) HandleMessage(source EventSource, evt *mautrix.Event) {
 	message := roomView.ParseEvent(evt)
 	if message != nil {
 		roomView.AddMessage(message)
+		if polls.IsPollUpdate(evt.Type) {
+			// Poll responses and ends only update the poll, so they don't bump the room or cause notifications.
+			c.ui.Render()
+			return
+		}
 		roomView.MxRoom().LastReceivedMessage = message.Timestamp()
-		if c.syncer.FirstSyncDone {
-			pushRules := c.PushRules().GetActions(roomView.MxRoom(), evt).Should()
+		if c.syncer.FirstSyncDone && !c.IsIgnored(evt.Sender) {
+			pushRules := c.PushRules().GetActions(&pushRuleRoom{roomView.MxRoom(), c}, evt).Should()
 			mainView.NotifyMessage(roomView.MxRoom(), message, pushRules)
 			c.ui.Render()
 		}
@@ -330,6 +505,8 @@ func (c *Container) HandleMembership(source EventSource, evt *mautrix.Event) {
 	} else if !isTimeline && (!c.config.AuthCache.InitialSyncDone || isLeave) {
 		// We don't care about other users' membership events in the initial sync or chats we've left.
 		return
+	} else if !c.showsRoom(evt.RoomID) {
+		return
 	}
 
 	c.HandleMessage(source, evt)
@@ -351,16 +528,18 @@ func (c *Container) processOwnMembershipChange(evt *mautrix.Event) {
 		c.ui.MainView().AddRoom(room)
 		room.HasLeft = false
 	case "leave":
-		c.ui.MainView().RemoveRoom(room)
 		room.HasLeft = true
+		c.ui.MainView().RemoveRoom(room)
 	case "invite":
 		// TODO handle
 		debug.Printf("%s invited the user to %s", evt.Sender, evt.RoomID)
 	}
 }
 
-func (c *Container) parseReadReceipt(evt *mautrix.Event) (largestTimestampEvent string) {
-	var largestTimestamp int64
+// parseReadReceipt finds the latest event each user in the given receipt event has read.
+func (c *Container) parseReadReceipt(evt *mautrix.Event) (latestEvents map[string]string) {
+	latestEvents = make(map[string]string)
+	largestTimestamps := make(map[string]int64)
 	for eventID, rawContent := range evt.Content.Raw {
 		content, ok := rawContent.(map[string]interface{})
 		if !ok {
@@ -372,15 +551,17 @@ func (c *Container) parseReadReceipt(evt *mautrix.Event) (largestTimestampEvent
 			continue
 		}
 
-		myInfo, ok := mRead[c.config.UserID].(map[string]interface{})
-		if !ok {
-			continue
-		}
+		for userID, rawInfo := range mRead {
+			info, ok := rawInfo.(map[string]interface{})
+			if !ok {
+				continue
+			}
 
-		ts, ok := myInfo["ts"].(float64)
-		if int64(ts) > largestTimestamp {
-			largestTimestamp = int64(ts)
-			largestTimestampEvent = eventID
+			ts, _ := info["ts"].(float64)
+			if largest, ok := largestTimestamps[userID]; !ok || int64(ts) > largest {
+				largestTimestamps[userID] = int64(ts)
+				latestEvents[userID] = eventID
+			}
 		}
 	}
 	return
@@ -391,13 +572,19 @@ func (c *Container) HandleReadReceipt(source EventSource, evt *mautrix.Event) {
 		return
 	}
 
-	lastReadEvent := c.parseReadReceipt(evt)
-	if len(lastReadEvent) == 0 {
+	latestEvents := c.parseReadReceipt(evt)
+	if len(latestEvents) == 0 || !c.showsRoom(evt.RoomID) {
 		return
 	}
 
 	room := c.GetRoom(evt.RoomID)
-	room.MarkRead(lastReadEvent)
+	for userID, eventID := range latestEvents {
+		if userID == c.config.UserID {
+			room.MarkRead(eventID)
+		} else {
+			room.SetReadReceipt(userID, eventID)
+		}
+	}
 	c.ui.Render()
 }
 
@@ -425,6 +612,14 @@ func (c *Container) parseDirectChatInfo(evt *mautrix.Event) map[*rooms.Room]bool
 }
 
 func (c *Container) HandleDirectChatInfo(source EventSource, evt *mautrix.Event) {
+	var content map[string][]string
+	if err := json.Unmarshal(evt.Content.VeryRaw, &content); err != nil {
+		debug.Print("Failed to parse direct chat info:", err)
+	}
+	c.directLock.Lock()
+	c.directChats = content
+	c.directLock.Unlock()
+
 	directChats := c.parseDirectChatInfo(evt)
 	for _, room := range c.config.Rooms {
 		shouldBeDirect := directChats[room]
@@ -470,27 +665,56 @@ func (c *Container) HandleTag(source EventSource, evt *mautrix.Event) {
 	mainView.UpdateTags(room)
 }
 
+// HandleFullyRead is the event handler for the m.fully_read room account data event.
+func (c *Container) HandleFullyRead(source EventSource, evt *mautrix.Event) {
+	eventID, ok := evt.Content.Raw["event_id"].(string)
+	if !ok || len(evt.RoomID) == 0 {
+		return
+	}
+	c.GetRoom(evt.RoomID).FullyRead = eventID
+}
+
 // HandleTyping is the event handler for the m.typing event.
 func (c *Container) HandleTyping(source EventSource, evt *mautrix.Event) {
+	if !c.showsRoom(evt.RoomID) {
+		return
+	}
 	c.ui.MainView().SetTyping(evt.RoomID, evt.Content.TypingUserIDs)
 }
 
-func (c *Container) MarkRead(roomID, eventID string) {
-	urlPath := c.client.BuildURL("rooms", roomID, "receipt", "m.read", eventID)
-	c.client.MakeRequest("POST", urlPath, struct{}{}, nil)
+// MarkRead moves the read receipt of the current user to the given event. The m.fully_read marker is also
+// moved if moveFullyRead is true, which should only be done if the user has actually seen the messages up to
+// the event, i.e. the room is scrolled to the bottom.
+// The read receipt is private if the PrivateReadReceipts preference is enabled.
+func (c *Container) MarkRead(roomID, eventID string, moveFullyRead bool) {
+	prefs := c.config.Preferences
+	if c.gmx != nil {
+		// The preferences of the main account are used for all accounts.
+		prefs = c.gmx.Config().Preferences
+	}
+	receiptType := "m.read"
+	if prefs.PrivateReadReceipts {
+		receiptType = "m.read.private"
+	}
+	markers := map[string]string{
+		receiptType: eventID,
+	}
+	if moveFullyRead {
+		markers["m.fully_read"] = eventID
+	}
+	urlPath := c.client.BuildURL("rooms", roomID, "read_markers")
+	_, err := c.client.MakeRequest("POST", urlPath, markers, nil)
+	if err != nil {
+		debug.Printf("Failed to mark %s in %s as read: %v", eventID, roomID, err)
+	}
 }
 
-var mentionRegex = regexp.MustCompile("\\[(.+?)]\\(https://matrix.to/#/@.+?:.+?\\)")
-var roomRegex = regexp.MustCompile("\\[.+?]\\(https://matrix.to/#/(#.+?:[^/]+?)\\)")
-
-func (c *Container) PrepareMarkdownMessage(roomID string, msgtype mautrix.MessageType, text string) *mautrix.Event {
-	content := format.RenderMarkdown(text)
+// PrepareMarkdownMessage creates a local echo event from the given markdown text.
+// Pill tokens of the given pills in the text are sent as matrix.to links with the pill text in the plaintext body.
+func (c *Container) PrepareMarkdownMessage(roomID string, msgtype mautrix.MessageType, text string, mentions ...pills.Pill) *mautrix.Event {
+	content := format.RenderMarkdown(pills.ToMarkdown(text, mentions))
 	content.MsgType = msgtype
 
-	// Remove markdown link stuff from plaintext mentions and room links
-	content.Body = mentionRegex.ReplaceAllString(content.Body, "$1")
-	content.Body = roomRegex.ReplaceAllString(content.Body, "$1")
-
 	txnID := c.client.TxnID()
 	localEcho := &mautrix.Event{
 		ID:        txnID,
@@ -512,13 +736,19 @@ func (c *Container) SendEvent(event *mautrix.Event) (string, error) {
 	defer debug.Recover()
 
 	c.SendTyping(event.RoomID, false)
-	resp, err := c.client.SendMessageEvent(event.RoomID, event.Type, event.Content, mautrix.ReqSendEvent{TransactionID: event.Unsigned.TransactionID})
+	resp, err := c.client.SendMessageEvent(event.RoomID, event.Type, getSendableContent(event), mautrix.ReqSendEvent{TransactionID: event.Unsigned.TransactionID})
 	if err != nil {
 		return "", err
 	}
 	return resp.EventID, nil
 }
 
+// Redact redacts the given event with an optional reason.
+func (c *Container) Redact(roomID, eventID, reason string) error {
+	_, err := c.client.RedactEvent(roomID, eventID, mautrix.ReqRedact{Reason: reason})
+	return err
+}
+
 // SendTyping sets whether or not the user is typing in the given room.
 func (c *Container) SendTyping(roomID string, typing bool) {
 	defer debug.Recover()
@@ -28,6 +28,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/pills"
 	"github.com/tulir/mautrix-go"
 )
 
@@ -85,6 +86,15 @@ func TestContainer_SendMarkdownMessage_WithMarkdown(t *testing.T) {
 	assert.Equal(t, "!foobar2:example.com", evtID)
 }
 
+func TestContainer_PrepareMarkdownMessage_Pills(t *testing.T) {
+	c := Container{client: mockClient(nil), config: &config.Config{UserID: "@user:example.com"}}
+	alice := pills.Pill{Text: "Alice_", ID: "@alice:example.com"}
+
+	event := c.PrepareMarkdownMessage("!foo:example.com", "m.text", alice.Token()+": hello", alice)
+	assert.Equal(t, "Alice_: hello", event.Content.Body)
+	assert.Equal(t, `<p><a href="https://matrix.to/#/@alice:example.com">Alice_</a>: hello</p>`, event.Content.FormattedBody)
+}
+
 func TestContainer_SendTyping(t *testing.T) {
 	var calls []mautrix.ReqTyping
 	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
@@ -120,6 +130,24 @@ func TestContainer_SendTyping(t *testing.T) {
 	assert.False(t, calls[3].Typing)
 }
 
+func TestContainer_MarkRead(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost || req.URL.Path != "/_matrix/client/r0/rooms/!foo:example.com/read_markers" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		}
+		sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{}`), nil
+	}), config: &config.Config{}}
+
+	c.MarkRead("!foo:example.com", "$event", false)
+	assert.Equal(t, map[string]interface{}{"m.read": "$event"}, sent)
+
+	c.config.Preferences.PrivateReadReceipts = true
+	c.MarkRead("!foo:example.com", "$event", true)
+	assert.Equal(t, map[string]interface{}{"m.read.private": "$event", "m.fully_read": "$event"}, sent)
+}
+
 func TestContainer_JoinRoom(t *testing.T) {
 	defer os.RemoveAll("/tmp/gomuks-mxtest-2")
 	cfg := config.NewConfig("/tmp/gomuks-mxtest-2", "/tmp/gomuks-mxtest-2")
@@ -0,0 +1,140 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/matrix/pushrules"
+	"github.com/kennetanti/gomuks/matrix/rooms"
+)
+
+// pushRuleRoom is the room given to push rules. It can look up events for related_event_match conditions.
+type pushRuleRoom struct {
+	*rooms.Room
+	container *Container
+}
+
+// GetEvent returns the given event from the local history cache. Push rules are evaluated while handling
+// sync responses, so events are never fetched from the server and a cache miss simply doesn't match.
+func (room *pushRuleRoom) GetEvent(eventID string) *mautrix.Event {
+	if room.container.history == nil {
+		return nil
+	}
+	evt, err := room.container.history.Get(room.Room, eventID)
+	if err != nil {
+		debug.Printf("Failed to get event %s for push rules: %v", eventID, err)
+	}
+	return evt
+}
+
+// deletePushRule deletes a push rule, ignoring the error if the rule doesn't exist.
+func (c *Container) deletePushRule(ruleType pushrules.PushRuleType, ruleID string) error {
+	err := pushrules.DeletePushRule(c.client, ruleType, ruleID)
+	if httpErr, ok := err.(mautrix.HTTPError); ok && httpErr.Code == 404 {
+		return nil
+	}
+	return err
+}
+
+// SetRoomNotificationLevel changes which messages in the given room cause notifications
+// by creating or deleting the room-specific push rules.
+func (c *Container) SetRoomNotificationLevel(roomID string, level pushrules.RoomNotificationLevel) error {
+	dontNotify := pushrules.PushActionArray{{Action: pushrules.ActionDontNotify}}
+	var err error
+	switch level {
+	case pushrules.RoomNotifyAll:
+		if err = c.deletePushRule(pushrules.OverrideRule, roomID); err == nil {
+			err = c.deletePushRule(pushrules.RoomRule, roomID)
+		}
+	case pushrules.RoomNotifyMentions:
+		if err = c.deletePushRule(pushrules.OverrideRule, roomID); err == nil {
+			err = pushrules.PutPushRule(c.client, &pushrules.PushRule{
+				Type:    pushrules.RoomRule,
+				RuleID:  roomID,
+				Actions: dontNotify,
+			})
+		}
+	case pushrules.RoomNotifyMute:
+		if err = c.deletePushRule(pushrules.RoomRule, roomID); err == nil {
+			err = pushrules.PutPushRule(c.client, &pushrules.PushRule{
+				Type:    pushrules.OverrideRule,
+				RuleID:  roomID,
+				Actions: dontNotify,
+				Conditions: []*pushrules.PushCondition{{
+					Kind:    pushrules.KindEventMatch,
+					Key:     "room_id",
+					Pattern: roomID,
+				}},
+			})
+		}
+	default:
+		return fmt.Errorf("unknown notification level %s", level)
+	}
+	if err != nil {
+		return err
+	}
+	c.UpdatePushRules()
+	return nil
+}
+
+// AddKeyword adds a content rule that notifies about and highlights messages containing the given word.
+func (c *Container) AddKeyword(keyword string) error {
+	keyword = strings.TrimSpace(keyword)
+	if len(keyword) == 0 {
+		return fmt.Errorf("keyword can't be empty")
+	}
+	err := pushrules.PutPushRule(c.client, &pushrules.PushRule{
+		Type:    pushrules.ContentRule,
+		RuleID:  keyword,
+		Pattern: keyword,
+		Actions: pushrules.PushActionArray{
+			{Action: pushrules.ActionNotify},
+			{Action: pushrules.ActionSetTweak, Tweak: pushrules.TweakSound, Value: "default"},
+			{Action: pushrules.ActionSetTweak, Tweak: pushrules.TweakHighlight, Value: true},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	c.UpdatePushRules()
+	return nil
+}
+
+// RemoveKeyword removes the content rule of the given word.
+func (c *Container) RemoveKeyword(keyword string) error {
+	err := pushrules.DeletePushRule(c.client, pushrules.ContentRule, strings.TrimSpace(keyword))
+	if err != nil {
+		return err
+	}
+	c.UpdatePushRules()
+	return nil
+}
+
+// SetPushRuleEnabled enables or disables the push rule with the given type and ID.
+func (c *Container) SetPushRuleEnabled(ruleType pushrules.PushRuleType, ruleID string, enabled bool) error {
+	err := pushrules.SetPushRuleEnabled(c.client, ruleType, ruleID, enabled)
+	if err != nil {
+		return err
+	}
+	c.UpdatePushRules()
+	return nil
+}
@@ -0,0 +1,142 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/pushrules"
+	"github.com/kennetanti/gomuks/matrix/rooms"
+)
+
+type pushRuleRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// mockPushRulesClient records write requests to the push rule API. Deletes of missing rules return 404
+// and fetching the rules fails, so the cached ruleset isn't saved.
+func mockPushRulesClient(requests *[]pushRuleRequest) *Container {
+	return &Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		const prefix = "/_matrix/client/r0/pushrules/global/"
+		if !strings.HasPrefix(req.URL.Path, prefix) {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		}
+		path := strings.TrimPrefix(req.URL.Path, prefix)
+		switch req.Method {
+		case http.MethodPut:
+			*requests = append(*requests, pushRuleRequest{req.Method, path, parseBody(req)})
+		case http.MethodDelete:
+			*requests = append(*requests, pushRuleRequest{Method: req.Method, Path: path})
+			return mockResponse(http.StatusNotFound, `{"errcode": "M_NOT_FOUND", "error": "Push rule not found"}`), nil
+		default:
+			return mockResponse(http.StatusInternalServerError, `{"errcode": "M_UNKNOWN"}`), nil
+		}
+		return mockResponse(http.StatusOK, `{}`), nil
+	}), config: &config.Config{UserID: "@user:example.com"}}
+}
+
+func TestContainer_SetRoomNotificationLevel_Mute(t *testing.T) {
+	var requests []pushRuleRequest
+	c := mockPushRulesClient(&requests)
+	assert.Nil(t, c.SetRoomNotificationLevel("!foo:example.com", pushrules.RoomNotifyMute))
+	assert.Len(t, requests, 2)
+	assert.Equal(t, http.MethodDelete, requests[0].Method)
+	assert.Equal(t, "room/!foo:example.com", requests[0].Path)
+	assert.Equal(t, "override/!foo:example.com", requests[1].Path)
+	assert.Equal(t, []interface{}{"dont_notify"}, requests[1].Body["actions"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"kind":    "event_match",
+		"key":     "room_id",
+		"pattern": "!foo:example.com",
+	}}, requests[1].Body["conditions"])
+}
+
+func TestContainer_SetRoomNotificationLevel_Mentions(t *testing.T) {
+	var requests []pushRuleRequest
+	c := mockPushRulesClient(&requests)
+	assert.Nil(t, c.SetRoomNotificationLevel("!foo:example.com", pushrules.RoomNotifyMentions))
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "override/!foo:example.com", requests[0].Path)
+	assert.Equal(t, "room/!foo:example.com", requests[1].Path)
+	assert.Equal(t, []interface{}{"dont_notify"}, requests[1].Body["actions"])
+	assert.Nil(t, requests[1].Body["conditions"])
+}
+
+func TestContainer_SetRoomNotificationLevel_All(t *testing.T) {
+	var requests []pushRuleRequest
+	c := mockPushRulesClient(&requests)
+	assert.Nil(t, c.SetRoomNotificationLevel("!foo:example.com", pushrules.RoomNotifyAll))
+	assert.Len(t, requests, 2)
+	assert.Equal(t, http.MethodDelete, requests[0].Method)
+	assert.Equal(t, http.MethodDelete, requests[1].Method)
+	assert.NotNil(t, c.SetRoomNotificationLevel("!foo:example.com", "loud"))
+}
+
+func TestContainer_AddKeyword(t *testing.T) {
+	var requests []pushRuleRequest
+	c := mockPushRulesClient(&requests)
+	assert.Nil(t, c.AddKeyword(" gomuks "))
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "content/gomuks", requests[0].Path)
+	assert.Equal(t, "gomuks", requests[0].Body["pattern"])
+	assert.Contains(t, requests[0].Body["actions"], "notify")
+	assert.NotNil(t, c.AddKeyword(" "))
+}
+
+func TestContainer_SetPushRuleEnabled(t *testing.T) {
+	var requests []pushRuleRequest
+	c := mockPushRulesClient(&requests)
+	assert.Nil(t, c.SetPushRuleEnabled(pushrules.UnderrideRule, ".m.rule.message", false))
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "underride/.m.rule.message/enabled", requests[0].Path)
+	assert.Equal(t, false, requests[0].Body["enabled"])
+}
+
+func TestPushRuleRoom_GetEvent_OnlyUsesCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	history, err := NewHistoryManager(filepath.Join(dir, "history.db"))
+	assert.Nil(t, err)
+	defer history.Close()
+
+	c := &Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+	}), history: history}
+	room := rooms.NewRoom("!foo:example.com", "@user:example.com")
+	assert.Nil(t, history.Append(room, []*mautrix.Event{{ID: "$cached", RoomID: room.ID, Type: mautrix.EventMessage}}))
+
+	prRoom := &pushRuleRoom{Room: room, container: c}
+	evt := prRoom.GetEvent("$cached")
+	if assert.NotNil(t, evt) {
+		assert.Equal(t, "$cached", evt.ID)
+	}
+	assert.Nil(t, prRoom.GetEvent("$missing"))
+}
@@ -0,0 +1,2 @@
+// Package pills contains utilities for mentions of users and rooms in the message input.
+package pills
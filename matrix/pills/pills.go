@@ -0,0 +1,131 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pills
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pills are mentions of users and rooms inserted by tab-completion. In the message input, a pill is
+// its display text between Start and End. The markers are zero-width, so only the text is shown.
+const (
+	Start = '\u200b'
+	End   = '\u200c'
+)
+
+// Pill is a mention of a user or a room.
+type Pill struct {
+	// The text shown in the input and used as the plaintext body, e.g. the displayname of a user.
+	Text string
+	// The user ID, room alias or room ID the pill links to.
+	ID string
+}
+
+// Token returns the text of the pill wrapped in the pill markers.
+func (pill Pill) Token() string {
+	return string(Start) + pill.Text + string(End)
+}
+
+// URL returns the matrix.to link of the pill.
+func (pill Pill) URL() string {
+	return "https://matrix.to/#/" + pill.ID
+}
+
+// Range is the location of a pill token in a string.
+type Range struct {
+	Pill
+	// The byte offsets of the start marker and the byte after the end marker.
+	Start, End int
+}
+
+// Find returns the locations of the pill tokens in the given text whose text matches one of the given pills.
+// Tokens that don't match a pill are ignored.
+func Find(text string, pills []Pill) (ranges []Range) {
+	start := -1
+	for index, char := range text {
+		switch char {
+		case Start:
+			start = index
+		case End:
+			if start < 0 {
+				continue
+			}
+			pillText := text[start+len(string(Start)) : index]
+			for i := len(pills) - 1; i >= 0; i-- {
+				if pills[i].Text == pillText {
+					ranges = append(ranges, Range{
+						Pill:  pills[i],
+						Start: start,
+						End:   index + len(string(End)),
+					})
+					break
+				}
+			}
+			start = -1
+		}
+	}
+	return
+}
+
+// StripMarkers removes all pill markers from the given text.
+func StripMarkers(text string) string {
+	return strings.Map(func(char rune) rune {
+		if char == Start || char == End {
+			return -1
+		}
+		return char
+	}, text)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_",
+	"`", "\\`", "~", "\\~", "<", "\\<", ">", "\\>", "&", "\\&",
+)
+
+// Replace replaces the tokens of the given pills in the text with the return value of the replacer function.
+// Markers that aren't part of a known pill are removed.
+func Replace(text string, pills []Pill, replacer func(pill Pill) string) string {
+	ranges := Find(text, pills)
+	if len(ranges) == 0 {
+		return StripMarkers(text)
+	}
+	var buf strings.Builder
+	prevEnd := 0
+	for _, pill := range ranges {
+		buf.WriteString(StripMarkers(text[prevEnd:pill.Start]))
+		buf.WriteString(replacer(pill.Pill))
+		prevEnd = pill.End
+	}
+	buf.WriteString(StripMarkers(text[prevEnd:]))
+	return buf.String()
+}
+
+// ToMarkdown replaces the tokens of the given pills in the text with markdown links.
+// Markdown in the text of the pills is escaped.
+func ToMarkdown(text string, pills []Pill) string {
+	return Replace(text, pills, func(pill Pill) string {
+		return fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(pill.Text), pill.URL())
+	})
+}
+
+// ToIDs replaces the tokens of the given pills in the text with the IDs of the pills.
+func ToIDs(text string, pills []Pill) string {
+	return Replace(text, pills, func(pill Pill) string {
+		return pill.ID
+	})
+}
@@ -0,0 +1,57 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pills_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/matrix/pills"
+)
+
+var alice = pills.Pill{Text: "Alice [work]", ID: "@alice:example.com"}
+var room = pills.Pill{Text: "#room:example.com", ID: "#room:example.com"}
+
+func TestFind(t *testing.T) {
+	text := alice.Token() + ": see " + room.Token() + " and " + pills.Pill{Text: "Unknown"}.Token()
+	ranges := pills.Find(text, []pills.Pill{alice, room})
+	assert.Len(t, ranges, 2)
+	assert.Equal(t, alice, ranges[0].Pill)
+	assert.Equal(t, 0, ranges[0].Start)
+	assert.Equal(t, alice.Token(), text[ranges[0].Start:ranges[0].End])
+	assert.Equal(t, room.Token(), text[ranges[1].Start:ranges[1].End])
+}
+
+func TestToMarkdown(t *testing.T) {
+	text := "hi " + alice.Token() + " and " + pills.Pill{Text: "Bob"}.Token()
+	assert.Equal(t, "hi [Alice \\[work\\]](https://matrix.to/#/@alice:example.com) and Bob",
+		pills.ToMarkdown(text, []pills.Pill{alice}))
+}
+
+func TestToIDs(t *testing.T) {
+	assert.Equal(t, "/invite @alice:example.com", pills.ToIDs("/invite "+alice.Token(), []pills.Pill{alice}))
+	// Text without pills is returned unchanged.
+	assert.Equal(t, "/invite @bob:example.com", pills.ToIDs("/invite @bob:example.com", nil))
+}
+
+func TestStripMarkers(t *testing.T) {
+	// Broken tokens, e.g. after deleting the end marker, are turned into plain text.
+	broken := string(pills.Start) + "Alice"
+	assert.Equal(t, "Alice", pills.StripMarkers(broken))
+	assert.Equal(t, "Alice", pills.ToMarkdown(broken, []pills.Pill{alice}))
+}
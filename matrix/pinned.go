@@ -0,0 +1,48 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"github.com/tulir/mautrix-go"
+)
+
+// SetPinned pins or unpins the given event in the given room.
+// The current pinned events are fetched from the server so that concurrent changes aren't lost.
+func (c *Container) SetPinned(roomID, eventID string, pinned bool) error {
+	var content struct {
+		Pinned []string `json:"pinned"`
+	}
+	err := c.client.StateEvent(roomID, mautrix.StatePinnedEvents, "", &content)
+	if httpErr, ok := err.(mautrix.HTTPError); ok && httpErr.Code == 404 {
+		err = nil
+	} else if err != nil {
+		return err
+	}
+
+	newPinned := make([]string, 0, len(content.Pinned)+1)
+	for _, pinnedEventID := range content.Pinned {
+		if pinnedEventID != eventID {
+			newPinned = append(newPinned, pinnedEventID)
+		}
+	}
+	if pinned {
+		newPinned = append(newPinned, eventID)
+	}
+	content.Pinned = newPinned
+	_, err = c.client.SendStateEvent(roomID, mautrix.StatePinnedEvents, "", &content)
+	return err
+}
@@ -0,0 +1,62 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockPinnedEventsClient(current string, sent *map[string]interface{}) *Container {
+	return &Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if strings.TrimSuffix(req.URL.Path, "/") != "/_matrix/client/r0/rooms/!foo:example.com/state/m.room.pinned_events" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		} else if req.Method == http.MethodGet {
+			if len(current) == 0 {
+				return mockResponse(http.StatusNotFound, `{"errcode": "M_NOT_FOUND", "error": "Event not found"}`), nil
+			}
+			return mockResponse(http.StatusOK, current), nil
+		}
+		*sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{"event_id": "$state"}`), nil
+	})}
+}
+
+func TestContainer_SetPinned_Pin(t *testing.T) {
+	var sent map[string]interface{}
+	c := mockPinnedEventsClient(`{"pinned": ["$foo"]}`, &sent)
+	assert.Nil(t, c.SetPinned("!foo:example.com", "$bar", true))
+	assert.Equal(t, []interface{}{"$foo", "$bar"}, sent["pinned"])
+}
+
+func TestContainer_SetPinned_PinFirst(t *testing.T) {
+	var sent map[string]interface{}
+	c := mockPinnedEventsClient("", &sent)
+	assert.Nil(t, c.SetPinned("!foo:example.com", "$bar", true))
+	assert.Equal(t, []interface{}{"$bar"}, sent["pinned"])
+}
+
+func TestContainer_SetPinned_Unpin(t *testing.T) {
+	var sent map[string]interface{}
+	c := mockPinnedEventsClient(`{"pinned": ["$foo", "$bar"]}`, &sent)
+	assert.Nil(t, c.SetPinned("!foo:example.com", "$foo", false))
+	assert.Equal(t, []interface{}{"$bar"}, sent["pinned"])
+}
@@ -0,0 +1,35 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"github.com/kennetanti/gomuks/matrix/polls"
+)
+
+// SendPollResponse votes for the given answers in a poll. An empty list of answers removes the vote.
+func (c *Container) SendPollResponse(roomID, pollID string, unstable bool, answerIDs []string) error {
+	evtType, content := polls.ResponseContent(pollID, unstable, answerIDs)
+	_, err := c.client.SendMessageEvent(roomID, evtType, content)
+	return err
+}
+
+// EndPoll closes a poll, after which further responses aren't counted.
+func (c *Container) EndPoll(roomID, pollID string, unstable bool) error {
+	evtType, content := polls.EndContent(pollID, unstable)
+	_, err := c.client.SendMessageEvent(roomID, evtType, content)
+	return err
+}
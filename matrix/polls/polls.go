@@ -0,0 +1,261 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package polls
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tulir/mautrix-go"
+)
+
+// Poll event types. See MSC3381. The unstable types are still sent by most clients, so both are supported.
+var (
+	EventPollStart    = mautrix.NewEventType("m.poll.start")
+	EventPollResponse = mautrix.NewEventType("m.poll.response")
+	EventPollEnd      = mautrix.NewEventType("m.poll.end")
+
+	EventUnstablePollStart    = mautrix.NewEventType("org.matrix.msc3381.poll.start")
+	EventUnstablePollResponse = mautrix.NewEventType("org.matrix.msc3381.poll.response")
+	EventUnstablePollEnd      = mautrix.NewEventType("org.matrix.msc3381.poll.end")
+)
+
+const (
+	PollKindDisclosed   = "m.poll.disclosed"
+	PollKindUndisclosed = "m.poll.undisclosed"
+
+	unstablePollPrefix = "org.matrix.msc3381."
+	relReference       = "m.reference"
+
+	// MaxPollAnswers is the maximum number of answers a poll can have according to the spec.
+	MaxPollAnswers = 20
+)
+
+// IsPollStart returns whether or not the given event type starts a poll.
+func IsPollStart(evtType mautrix.EventType) bool {
+	return evtType.Type == EventPollStart.Type || evtType.Type == EventUnstablePollStart.Type
+}
+
+// IsPollUpdate returns whether or not the given event type is a response to or the end of a poll.
+func IsPollUpdate(evtType mautrix.EventType) bool {
+	switch evtType.Type {
+	case EventPollResponse.Type, EventPollEnd.Type, EventUnstablePollResponse.Type, EventUnstablePollEnd.Type:
+		return true
+	}
+	return false
+}
+
+// IsPollEnd returns whether or not the given event type ends a poll.
+func IsPollEnd(evtType mautrix.EventType) bool {
+	return evtType.Type == EventPollEnd.Type || evtType.Type == EventUnstablePollEnd.Type
+}
+
+type PollAnswer struct {
+	ID   string
+	Text string
+}
+
+// Poll contains the question and answers of a poll start event.
+type Poll struct {
+	Question      string
+	Kind          string
+	MaxSelections int
+	Answers       []PollAnswer
+	// Whether or not the poll was started with the unstable event type.
+	// Responses and the end event are sent with the same kind of type.
+	Unstable bool
+}
+
+// IsDisclosed returns whether or not the results should be shown before the poll has ended.
+func (poll *Poll) IsDisclosed() bool {
+	return poll.Kind != PollKindUndisclosed
+}
+
+// HasAnswer returns whether or not the poll has an answer with the given ID.
+func (poll *Poll) HasAnswer(answerID string) bool {
+	for _, answer := range poll.Answers {
+		if answer.ID == answerID {
+			return true
+		}
+	}
+	return false
+}
+
+type textBlock struct {
+	Body     string `json:"body"`
+	MimeType string `json:"mimetype,omitempty"`
+}
+
+// extensibleText is the value of an m.text field. See MSC1767.
+type extensibleText []textBlock
+
+func (text extensibleText) plain() string {
+	for _, block := range text {
+		if len(block.MimeType) == 0 || block.MimeType == "text/plain" {
+			return block.Body
+		}
+	}
+	return ""
+}
+
+type stablePollStart struct {
+	Kind          string `json:"kind"`
+	MaxSelections int    `json:"max_selections"`
+	Question      struct {
+		Text extensibleText `json:"m.text"`
+	} `json:"question"`
+	Answers []struct {
+		ID   string         `json:"m.id"`
+		Text extensibleText `json:"m.text"`
+	} `json:"answers"`
+}
+
+type unstablePollStart struct {
+	Kind          string `json:"kind"`
+	MaxSelections int    `json:"max_selections"`
+	Question      struct {
+		Text string `json:"org.matrix.msc1767.text"`
+	} `json:"question"`
+	Answers []struct {
+		ID   string `json:"id"`
+		Text string `json:"org.matrix.msc1767.text"`
+	} `json:"answers"`
+}
+
+type pollReference struct {
+	RelType string `json:"rel_type"`
+	EventID string `json:"event_id"`
+}
+
+type pollContent struct {
+	Start         *stablePollStart   `json:"m.poll"`
+	UnstableStart *unstablePollStart `json:"org.matrix.msc3381.poll.start"`
+
+	Selections       []string `json:"m.selections"`
+	UnstableResponse *struct {
+		Answers []string `json:"answers"`
+	} `json:"org.matrix.msc3381.poll.response"`
+
+	RelatesTo *pollReference `json:"m.relates_to"`
+}
+
+func parsePollContent(content *mautrix.Content) *pollContent {
+	var parsed pollContent
+	data := []byte(content.VeryRaw)
+	if len(data) == 0 {
+		var err error
+		if data, err = json.Marshal(content.Raw); err != nil {
+			return nil
+		}
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// ParsePoll parses the content of a poll start event. Nil is returned if the content isn't a valid poll.
+func ParsePoll(content *mautrix.Content) *Poll {
+	parsed := parsePollContent(content)
+	if parsed == nil {
+		return nil
+	}
+	var poll Poll
+	if parsed.Start != nil {
+		poll.Question = parsed.Start.Question.Text.plain()
+		poll.Kind = parsed.Start.Kind
+		poll.MaxSelections = parsed.Start.MaxSelections
+		for _, answer := range parsed.Start.Answers {
+			poll.Answers = append(poll.Answers, PollAnswer{ID: answer.ID, Text: answer.Text.plain()})
+		}
+	} else if parsed.UnstableStart != nil {
+		poll.Unstable = true
+		poll.Question = parsed.UnstableStart.Question.Text
+		poll.Kind = strings.Replace(parsed.UnstableStart.Kind, unstablePollPrefix, "m.", 1)
+		poll.MaxSelections = parsed.UnstableStart.MaxSelections
+		for _, answer := range parsed.UnstableStart.Answers {
+			poll.Answers = append(poll.Answers, PollAnswer{ID: answer.ID, Text: answer.Text})
+		}
+	} else {
+		return nil
+	}
+	if len(poll.Answers) == 0 {
+		return nil
+	} else if len(poll.Answers) > MaxPollAnswers {
+		poll.Answers = poll.Answers[:MaxPollAnswers]
+	}
+	if poll.MaxSelections < 1 {
+		poll.MaxSelections = 1
+	} else if poll.MaxSelections > len(poll.Answers) {
+		poll.MaxSelections = len(poll.Answers)
+	}
+	return &poll
+}
+
+// GetPollReference returns the ID of the poll that the given poll response or end event refers to.
+func GetPollReference(content *mautrix.Content) string {
+	parsed := parsePollContent(content)
+	if parsed == nil || parsed.RelatesTo == nil || parsed.RelatesTo.RelType != relReference {
+		return ""
+	}
+	return parsed.RelatesTo.EventID
+}
+
+// GetPollSelections returns the IDs of the answers chosen in the given poll response event.
+func GetPollSelections(content *mautrix.Content) []string {
+	parsed := parsePollContent(content)
+	if parsed == nil {
+		return nil
+	} else if parsed.Selections != nil {
+		return parsed.Selections
+	} else if parsed.UnstableResponse != nil {
+		return parsed.UnstableResponse.Answers
+	}
+	return nil
+}
+
+// ResponseContent returns the event type and content of a response to the given poll.
+// An empty list of answers removes the vote.
+func ResponseContent(pollID string, unstable bool, answerIDs []string) (mautrix.EventType, map[string]interface{}) {
+	if answerIDs == nil {
+		answerIDs = []string{}
+	}
+	content := map[string]interface{}{
+		"m.relates_to": &pollReference{RelType: relReference, EventID: pollID},
+	}
+	if unstable {
+		content[EventUnstablePollResponse.Type] = map[string]interface{}{"answers": answerIDs}
+		return EventUnstablePollResponse, content
+	}
+	content["m.selections"] = answerIDs
+	return EventPollResponse, content
+}
+
+// EndContent returns the event type and content of an event that ends the given poll.
+func EndContent(pollID string, unstable bool) (mautrix.EventType, map[string]interface{}) {
+	const fallback = "The poll has ended."
+	content := map[string]interface{}{
+		"m.relates_to": &pollReference{RelType: relReference, EventID: pollID},
+	}
+	if unstable {
+		content[EventUnstablePollEnd.Type] = map[string]interface{}{}
+		content["org.matrix.msc1767.text"] = fallback
+		return EventUnstablePollEnd, content
+	}
+	content["m.text"] = extensibleText{{Body: fallback}}
+	return EventPollEnd, content
+}
@@ -0,0 +1,88 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package polls_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/matrix/polls"
+)
+
+func parseContent(t *testing.T, data string) *mautrix.Content {
+	var content mautrix.Content
+	assert.Nil(t, json.Unmarshal([]byte(data), &content))
+	return &content
+}
+
+func TestParsePoll_Stable(t *testing.T) {
+	poll := polls.ParsePoll(parseContent(t, `{
+		"m.text": [{"body": "Lunch?"}],
+		"m.poll": {
+			"kind": "m.poll.undisclosed",
+			"max_selections": 2,
+			"question": {"m.text": [{"body": "<b>Lunch?</b>", "mimetype": "text/html"}, {"body": "Lunch?"}]},
+			"answers": [{"m.id": "pizza", "m.text": [{"body": "Pizza"}]}, {"m.id": "sushi", "m.text": [{"body": "Sushi"}]}]
+		}
+	}`))
+	assert.NotNil(t, poll)
+	assert.Equal(t, "Lunch?", poll.Question)
+	assert.False(t, poll.IsDisclosed())
+	assert.False(t, poll.Unstable)
+	assert.Equal(t, 2, poll.MaxSelections)
+	assert.Equal(t, []polls.PollAnswer{{ID: "pizza", Text: "Pizza"}, {ID: "sushi", Text: "Sushi"}}, poll.Answers)
+}
+
+func TestParsePoll_Unstable(t *testing.T) {
+	poll := polls.ParsePoll(parseContent(t, `{
+		"org.matrix.msc3381.poll.start": {
+			"kind": "org.matrix.msc3381.poll.disclosed",
+			"max_selections": 5,
+			"question": {"org.matrix.msc1767.text": "Standup time?"},
+			"answers": [{"id": "9", "org.matrix.msc1767.text": "9:00"}, {"id": "10", "org.matrix.msc1767.text": "10:00"}]
+		}
+	}`))
+	assert.NotNil(t, poll)
+	assert.Equal(t, "Standup time?", poll.Question)
+	assert.True(t, poll.IsDisclosed())
+	assert.True(t, poll.Unstable)
+	// The maximum number of selections is limited to the number of answers.
+	assert.Equal(t, 2, poll.MaxSelections)
+	assert.True(t, poll.HasAnswer("10"))
+	assert.False(t, poll.HasAnswer("11"))
+}
+
+func TestParsePoll_Invalid(t *testing.T) {
+	assert.Nil(t, polls.ParsePoll(parseContent(t, `{"body": "not a poll"}`)))
+	assert.Nil(t, polls.ParsePoll(parseContent(t, `{"m.poll": {"question": {"m.text": [{"body": "No answers?"}]}, "answers": []}}`)))
+}
+
+func TestGetPollSelections(t *testing.T) {
+	content := parseContent(t, `{"m.relates_to": {"rel_type": "m.reference", "event_id": "$poll"}, "m.selections": ["a", "b"]}`)
+	assert.Equal(t, "$poll", polls.GetPollReference(content))
+	assert.Equal(t, []string{"a", "b"}, polls.GetPollSelections(content))
+
+	content = parseContent(t, `{"m.relates_to": {"rel_type": "m.reference", "event_id": "$poll"}, "org.matrix.msc3381.poll.response": {"answers": ["c"]}}`)
+	assert.Equal(t, "$poll", polls.GetPollReference(content))
+	assert.Equal(t, []string{"c"}, polls.GetPollSelections(content))
+
+	content = parseContent(t, `{"m.relates_to": {"m.in_reply_to": {"event_id": "$poll"}}}`)
+	assert.Empty(t, polls.GetPollReference(content))
+}
@@ -0,0 +1,59 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockPollClient(eventType string, sent *map[string]interface{}) *Container {
+	return &Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPut || !strings.HasPrefix(req.URL.Path, "/_matrix/client/r0/rooms/!foo:example.com/send/"+eventType+"/") {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		}
+		*sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{"event_id": "$response"}`), nil
+	})}
+}
+
+func TestContainer_SendPollResponse(t *testing.T) {
+	var sent map[string]interface{}
+	c := mockPollClient("m.poll.response", &sent)
+	assert.Nil(t, c.SendPollResponse("!foo:example.com", "$poll", false, []string{"pizza"}))
+	assert.Equal(t, []interface{}{"pizza"}, sent["m.selections"])
+	assert.Equal(t, map[string]interface{}{"rel_type": "m.reference", "event_id": "$poll"}, sent["m.relates_to"])
+}
+
+func TestContainer_SendPollResponse_Unstable(t *testing.T) {
+	var sent map[string]interface{}
+	c := mockPollClient("org.matrix.msc3381.poll.response", &sent)
+	assert.Nil(t, c.SendPollResponse("!foo:example.com", "$poll", true, nil))
+	assert.Equal(t, map[string]interface{}{"answers": []interface{}{}}, sent["org.matrix.msc3381.poll.response"])
+}
+
+func TestContainer_EndPoll(t *testing.T) {
+	var sent map[string]interface{}
+	c := mockPollClient("m.poll.end", &sent)
+	assert.Nil(t, c.EndPoll("!foo:example.com", "$poll", false))
+	assert.Equal(t, map[string]interface{}{"rel_type": "m.reference", "event_id": "$poll"}, sent["m.relates_to"])
+	assert.NotEmpty(t, sent["m.text"])
+}
@@ -0,0 +1,85 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+)
+
+var EventPresence = mautrix.NewEventType("m.presence")
+
+type presenceContent struct {
+	Presence        string `json:"presence"`
+	LastActiveAgo   int64  `json:"last_active_ago"`
+	CurrentlyActive bool   `json:"currently_active"`
+	StatusMsg       string `json:"status_msg"`
+}
+
+// HandlePresence is the event handler for the m.presence event.
+func (c *Container) HandlePresence(source EventSource, evt *mautrix.Event) {
+	var content presenceContent
+	if err := json.Unmarshal(evt.Content.VeryRaw, &content); err != nil {
+		debug.Printf("Failed to parse presence of %s: %v", evt.Sender, err)
+		return
+	}
+	presence := &ifc.Presence{
+		Presence:        content.Presence,
+		CurrentlyActive: content.CurrentlyActive,
+		StatusMsg:       content.StatusMsg,
+	}
+	if content.LastActiveAgo > 0 {
+		presence.LastActive = time.Now().Add(-time.Duration(content.LastActiveAgo) * time.Millisecond)
+	}
+	c.presenceLock.Lock()
+	c.presence[evt.Sender] = presence
+	c.presenceLock.Unlock()
+	if c.syncer.FirstSyncDone {
+		c.ui.Render()
+	}
+}
+
+// GetPresence returns the last known presence of the given user, or nil if it is not known.
+func (c *Container) GetPresence(userID string) *ifc.Presence {
+	c.presenceLock.RLock()
+	defer c.presenceLock.RUnlock()
+	return c.presence[userID]
+}
+
+// SetPresence publishes the presence of the current user if it has changed.
+func (c *Container) SetPresence(presence string) {
+	defer debug.Recover()
+	if c.client == nil || len(c.config.AccessToken) == 0 {
+		return
+	}
+	c.presenceLock.Lock()
+	if c.ownPresence == presence {
+		c.presenceLock.Unlock()
+		return
+	}
+	c.ownPresence = presence
+	c.presenceLock.Unlock()
+	debug.Print("Setting presence to", presence)
+	if err := c.client.SetPresence(presence); err != nil {
+		debug.Print("Failed to set presence:", err)
+	}
+}
@@ -0,0 +1,96 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tulir/mautrix-go"
+)
+
+// SetDisplayName changes the global displayname of the current user.
+// The server propagates the change to the member events of all joined rooms.
+func (c *Container) SetDisplayName(name string) error {
+	return c.client.SetDisplayName(name)
+}
+
+// SetRoomNick overrides the displayname of the current user in the given room.
+func (c *Container) SetRoomNick(roomID, name string) error {
+	return c.setOwnMemberField(roomID, "displayname", name)
+}
+
+// SetAvatar changes the avatar of the current user. If roomID is empty, the global avatar is changed,
+// otherwise only the avatar in the given room is overridden.
+//
+// The avatar can either be a mxc:// URI or the path to a local image file, which is uploaded first.
+// The mxc:// URI of the avatar is returned.
+func (c *Container) SetAvatar(roomID, pathOrMXC string) (string, error) {
+	mxc := pathOrMXC
+	if !strings.HasPrefix(pathOrMXC, "mxc://") {
+		var err error
+		mxc, err = c.uploadImage(pathOrMXC)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(roomID) == 0 {
+		return mxc, c.client.SetAvatarURL(mxc)
+	}
+	return mxc, c.setOwnMemberField(roomID, "avatar_url", mxc)
+}
+
+// uploadImage uploads the image at the given path to the media repository and returns the mxc:// URI.
+func (c *Container) uploadImage(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("%s is not an image (detected type %s)", path, contentType)
+	}
+	resp, err := c.client.UploadBytes(data, contentType)
+	if err != nil {
+		return "", err
+	}
+	return resp.ContentURI, nil
+}
+
+// setOwnMemberField changes a single field in the m.room.member event of the current user in the given room.
+// The current member event is fetched from the server so that no other fields are lost.
+func (c *Container) setOwnMemberField(roomID, key, value string) error {
+	content := make(map[string]interface{})
+	err := c.client.StateEvent(roomID, mautrix.StateMember, c.config.UserID, &content)
+	if err != nil {
+		return err
+	}
+	content[key] = value
+	_, err = c.client.SendStateEvent(roomID, mautrix.StateMember, c.config.UserID, content)
+	return err
+}
@@ -0,0 +1,65 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func TestContainer_SetRoomNick(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/_matrix/client/r0/rooms/!foo:example.com/state/m.room.member/@user:example.com" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		} else if req.Method == http.MethodGet {
+			return mockResponse(http.StatusOK, `{"membership": "join", "displayname": "user", "avatar_url": "mxc://example.com/foo"}`), nil
+		}
+		sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{"event_id": "$foo"}`), nil
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	assert.Nil(t, c.SetRoomNick("!foo:example.com", "nickname"))
+	assert.Equal(t, map[string]interface{}{
+		"membership":  "join",
+		"displayname": "nickname",
+		"avatar_url":  "mxc://example.com/foo",
+	}, sent)
+}
+
+func TestContainer_SetAvatar_NotImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomuks-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "avatar.png")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("this is not an image"), 0600))
+
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	_, err = c.SetAvatar("", path)
+	assert.NotNil(t, err)
+}
@@ -54,6 +54,10 @@ type PushActionArrayShould struct {
 	PlaySound bool
 	// The name of the sound to play if PlaySound is true.
 	SoundName string
+
+	// The values of tweaks other than highlight and sound, for example ones added by newer spec versions
+	// or custom tweaks of the server. Nil if there are no other tweaks.
+	Tweaks map[PushActionTweak]interface{}
 }
 
 // Should parses this push action array and returns the relevant details wrapped in a PushActionArrayShould struct.
@@ -76,8 +80,13 @@ func (actions PushActionArray) Should() (should PushActionArrayShould) {
 					should.Highlight = true
 				}
 			case TweakSound:
-				should.SoundName = action.Value.(string)
+				should.SoundName, _ = action.Value.(string)
 				should.PlaySound = len(should.SoundName) > 0
+			default:
+				if should.Tweaks == nil {
+					should.Tweaks = make(map[PushActionTweak]interface{})
+				}
+				should.Tweaks[action.Tweak] = action.Value
 			}
 		}
 	}
@@ -125,7 +134,10 @@ func (action *PushAction) MarshalJSON() (raw []byte, err error) {
 	if action.Action == ActionSetTweak {
 		data := map[string]interface{}{
 			"set_tweak": action.Tweak,
-			"value":     action.Value,
+		}
+		// The value is optional, e.g. a highlight tweak without a value means true.
+		if action.Value != nil {
+			data["value"] = action.Value
 		}
 		return json.Marshal(&data)
 	}
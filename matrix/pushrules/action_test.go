@@ -208,3 +208,29 @@ func TestPushAction_MarshalJSON_OtherOutputWorks(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, []byte(`"something else"`), data)
 }
+
+func TestPushActionArray_Should_UnknownTweakPassthrough(t *testing.T) {
+	should := pushrules.PushActionArray{
+		{Action: pushrules.ActionNotify},
+		{Action: pushrules.ActionSetTweak, Tweak: pushrules.TweakHighlight},
+		{Action: pushrules.ActionSetTweak, Tweak: pushrules.PushActionTweak("org.example.vibrate"), Value: 200.0},
+		{Action: pushrules.ActionSetTweak, Tweak: pushrules.PushActionTweak("org.example.flag")},
+	}.Should()
+	assert.True(t, should.Notify)
+	assert.True(t, should.Highlight)
+	assert.Equal(t, map[pushrules.PushActionTweak]interface{}{
+		"org.example.vibrate": 200.0,
+		"org.example.flag":    nil,
+	}, should.Tweaks)
+	assert.Nil(t, pushrules.PushActionArray{{Action: pushrules.ActionNotify}}.Should().Tweaks)
+}
+
+func TestPushAction_MarshalJSON_TweakWithoutValue(t *testing.T) {
+	pa := &pushrules.PushAction{
+		Action: pushrules.ActionSetTweak,
+		Tweak:  pushrules.TweakHighlight,
+	}
+	data, err := pa.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(`{"set_tweak":"highlight"}`), data)
+}
@@ -17,6 +17,7 @@
 package pushrules
 
 import (
+	"encoding/json"
 	"regexp"
 	"strconv"
 	"strings"
@@ -31,6 +32,14 @@ type Room interface {
 	GetMember(mxid string) *mautrix.Member
 	GetMembers() map[string]*mautrix.Member
 	GetSessionOwner() string
+	GetStateEvent(eventType mautrix.EventType, stateKey string) *mautrix.Event
+}
+
+// EventRoom is a Room that can also fetch events by ID. It's needed for related_event_match conditions,
+// which never match in rooms that don't implement it.
+type EventRoom interface {
+	Room
+	GetEvent(eventID string) *mautrix.Event
 }
 
 // PushCondKind is the type of a push condition.
@@ -41,21 +50,55 @@ const (
 	KindEventMatch          PushCondKind = "event_match"
 	KindContainsDisplayName PushCondKind = "contains_display_name"
 	KindRoomMemberCount     PushCondKind = "room_member_count"
+
+	KindSenderNotificationPermission PushCondKind = "sender_notification_permission"
+	KindEventPropertyIs              PushCondKind = "event_property_is"
+	KindEventPropertyContains        PushCondKind = "event_property_contains"
+	KindRelatedEventMatch            PushCondKind = "related_event_match"
 )
 
+// RelInReplyTo is the relation type used in related_event_match conditions to match the event being replied to.
+const RelInReplyTo = "m.in_reply_to"
+
+// DefaultNotificationPowerLevel is the power level required for sender_notification_permission conditions
+// if the power levels of the room don't specify it.
+const DefaultNotificationPowerLevel = 50
+
 // PushCondition wraps a condition that is required for a specific PushRule to be used.
 type PushCondition struct {
 	// The type of the condition.
 	Kind PushCondKind `json:"kind"`
-	// The dot-separated field of the event to match. Only applicable if kind is EventMatch.
+	// The dot-separated field of the event to match. Literal dots and backslashes in field names are escaped
+	// with a backslash. Applicable to EventMatch, EventPropertyIs, EventPropertyContains and RelatedEventMatch.
+	// For SenderNotificationPermission, this is the key in the notifications object of the power levels.
 	Key string `json:"key,omitempty"`
-	// The glob-style pattern to match the field against. Only applicable if kind is EventMatch.
+	// The glob-style pattern to match the field against. Only applicable if kind is EventMatch or RelatedEventMatch.
 	Pattern string `json:"pattern,omitempty"`
+	// The exact value to compare the field to. Only applicable if kind is EventPropertyIs or EventPropertyContains.
+	// False, zero, empty strings and null are valid values, so the field is only omitted by MarshalJSON for other kinds.
+	Value interface{} `json:"value"`
+	// The relation type of the related event to match. Only applicable if kind is RelatedEventMatch.
+	RelType string `json:"rel_type,omitempty"`
+	// Whether or not fallback relations should match. Only applicable if kind is RelatedEventMatch.
+	IncludeFallbacks bool `json:"include_fallbacks,omitempty"`
 	// The condition that needs to be fulfilled for RoomMemberCount-type conditions.
 	// A decimal integer optionally prefixed by ==, <, >, >= or <=. Prefix "==" is assumed if no prefix found.
 	MemberCountCondition string `json:"is,omitempty"`
 }
 
+// MarshalJSON marshals the condition, only including the value field for the kinds that use it.
+func (cond *PushCondition) MarshalJSON() ([]byte, error) {
+	type plainCondition PushCondition
+	if cond.Kind == KindEventPropertyIs || cond.Kind == KindEventPropertyContains {
+		return json.Marshal((*plainCondition)(cond))
+	}
+	// The value field of the embedded struct is shadowed by this one, which is always empty.
+	return json.Marshal(&struct {
+		*plainCondition
+		Value interface{} `json:"value,omitempty"`
+	}{plainCondition: (*plainCondition)(cond)})
+}
+
 // MemberCountFilterRegex is the regular expression to parse the MemberCountCondition of PushConditions.
 var MemberCountFilterRegex = regexp.MustCompile("^(==|[<>]=?)?([0-9]+)$")
 
@@ -68,43 +111,187 @@ func (cond *PushCondition) Match(room Room, event *mautrix.Event) bool {
 		return cond.matchDisplayName(room, event)
 	case KindRoomMemberCount:
 		return cond.matchMemberCount(room, event)
+	case KindSenderNotificationPermission:
+		return cond.matchSenderNotificationPermission(room, event)
+	case KindEventPropertyIs:
+		return cond.matchPropertyIs(event)
+	case KindEventPropertyContains:
+		return cond.matchPropertyContains(event)
+	case KindRelatedEventMatch:
+		return cond.matchRelatedEvent(room, event)
 	default:
 		return false
 	}
 }
 
 func (cond *PushCondition) matchValue(room Room, event *mautrix.Event) bool {
-	index := strings.IndexRune(cond.Key, '.')
-	key := cond.Key
-	subkey := ""
-	if index > 0 {
-		subkey = key[index+1:]
-		key = key[0:index]
+	return matchEventField(event, cond.Key, cond.Pattern)
+}
+
+// matchEventField checks if the string at the given dotted path in the event matches the given glob pattern.
+func matchEventField(event *mautrix.Event, key, pattern string) bool {
+	if key == "state_key" && event.StateKey == nil {
+		return pattern == ""
 	}
 
-	pattern, err := glob.Compile(cond.Pattern)
+	compiled, err := glob.Compile(pattern)
 	if err != nil {
 		return false
 	}
+	value, _ := getEventProperty(event, key)
+	str, ok := value.(string)
+	return ok && compiled.MatchString(str)
+}
 
-	switch key {
+// splitKey splits a dotted push condition key into field names.
+// Dots and backslashes that are part of a field name are escaped with a backslash.
+// Other escape sequences are kept as-is.
+func splitKey(key string) []string {
+	var path []string
+	var field strings.Builder
+	escaped := false
+	for _, char := range key {
+		if escaped {
+			if char != '.' && char != '\\' {
+				field.WriteRune('\\')
+			}
+			field.WriteRune(char)
+			escaped = false
+		} else if char == '\\' {
+			escaped = true
+		} else if char == '.' {
+			path = append(path, field.String())
+			field.Reset()
+		} else {
+			field.WriteRune(char)
+		}
+	}
+	if escaped {
+		field.WriteRune('\\')
+	}
+	return append(path, field.String())
+}
+
+// getEventProperty returns the value at the given dotted path in the event.
+func getEventProperty(event *mautrix.Event, key string) (interface{}, bool) {
+	path := splitKey(key)
+	var value interface{}
+	switch path[0] {
 	case "type":
-		return pattern.MatchString(event.Type.String())
+		value = event.Type.String()
 	case "sender":
-		return pattern.MatchString(event.Sender)
+		value = event.Sender
 	case "room_id":
-		return pattern.MatchString(event.RoomID)
+		value = event.RoomID
+	case "event_id":
+		value = event.ID
 	case "state_key":
 		if event.StateKey == nil {
-			return cond.Pattern == ""
+			return nil, false
 		}
-		return pattern.MatchString(*event.StateKey)
+		value = *event.StateKey
 	case "content":
-		val, _ := event.Content.Raw[subkey].(string)
-		return pattern.MatchString(val)
+		value = event.Content.Raw
+	default:
+		return nil, false
+	}
+	for _, field := range path[1:] {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[field]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// normalizeScalar converts the given value into a form that can be compared with ==.
+// The second return value is false if the value isn't a JSON scalar (string, number, boolean or null).
+func normalizeScalar(value interface{}) (interface{}, bool) {
+	switch typedValue := value.(type) {
+	case nil, string, bool, float64:
+		return value, true
+	case int:
+		return float64(typedValue), true
+	case int64:
+		return float64(typedValue), true
 	default:
+		return nil, false
+	}
+}
+
+// scalarEquals checks if the given values are equal JSON scalars.
+func scalarEquals(a, b interface{}) bool {
+	a, aOK := normalizeScalar(a)
+	b, bOK := normalizeScalar(b)
+	return aOK && bOK && a == b
+}
+
+func (cond *PushCondition) matchPropertyIs(event *mautrix.Event) bool {
+	value, ok := getEventProperty(event, cond.Key)
+	return ok && scalarEquals(value, cond.Value)
+}
+
+func (cond *PushCondition) matchPropertyContains(event *mautrix.Event) bool {
+	value, _ := getEventProperty(event, cond.Key)
+	array, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range array {
+		if scalarEquals(item, cond.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cond *PushCondition) matchSenderNotificationPermission(room Room, event *mautrix.Event) bool {
+	plEvent := room.GetStateEvent(mautrix.StatePowerLevels, "")
+	if plEvent == nil {
+		return false
+	}
+	requiredLevel := DefaultNotificationPowerLevel
+	if notifications, ok := plEvent.Content.Raw["notifications"].(map[string]interface{}); ok {
+		if level, ok := notifications[cond.Key].(float64); ok {
+			requiredLevel = int(level)
+		}
+	}
+	return plEvent.Content.GetPowerLevels().GetUserLevel(event.Sender) >= requiredLevel
+}
+
+func (cond *PushCondition) matchRelatedEvent(room Room, event *mautrix.Event) bool {
+	eventRoom, ok := room.(EventRoom)
+	if !ok {
+		return false
+	}
+	relatesTo, ok := event.Content.Raw["m.relates_to"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	var relatedEventID string
+	if cond.RelType == RelInReplyTo {
+		if isFallingBack, _ := relatesTo["is_falling_back"].(bool); isFallingBack && !cond.IncludeFallbacks {
+			return false
+		}
+		inReplyTo, _ := relatesTo["m.in_reply_to"].(map[string]interface{})
+		relatedEventID, _ = inReplyTo["event_id"].(string)
+	} else if relType, _ := relatesTo["rel_type"].(string); relType == cond.RelType {
+		relatedEventID, _ = relatesTo["event_id"].(string)
+	}
+	if len(relatedEventID) == 0 {
+		return false
+	}
+	relatedEvent := eventRoom.GetEvent(relatedEventID)
+	if relatedEvent == nil {
 		return false
+	} else if len(cond.Key) == 0 {
+		return true
 	}
+	return matchEventField(relatedEvent, cond.Key, cond.Pattern)
 }
 
 func (cond *PushCondition) matchDisplayName(room Room, event *mautrix.Event) bool {
@@ -0,0 +1,200 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pushrules_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/matrix/pushrules"
+)
+
+func newJSONEvent(t *testing.T, content string) *mautrix.Event {
+	event := newFakeEvent(mautrix.EventMessage, mautrix.Content{})
+	assert.Nil(t, json.Unmarshal([]byte(content), &event.Content))
+	return event
+}
+
+const propertyTestContent = `{
+	"msgtype": "m.text",
+	"body": "hello",
+	"m.relates_to": {"rel_type": "m.thread", "event_id": "$root"},
+	"m.mentions": {"user_ids": ["@tulir:maunium.net", "@other:maunium.net"], "room": true},
+	"org.example.count": 5,
+	"m.dotted.key": {"x\\y": "escaped"},
+	"nothing": null
+}`
+
+func TestPushCondition_Match_KindEventPropertyIs(t *testing.T) {
+	event := newJSONEvent(t, propertyTestContent)
+	tests := []struct {
+		key      string
+		value    interface{}
+		expected bool
+	}{
+		{"content.msgtype", "m.text", true},
+		{"content.msgtype", "m.notice", false},
+		{"content.m\\.relates_to.rel_type", "m.thread", true},
+		{"content.m.relates_to.rel_type", "m.thread", false},
+		{"content.m\\.mentions.room", true, true},
+		{"content.m\\.mentions.room", "true", false},
+		{"content.org\\.example\\.count", 5, true},
+		{"content.org\\.example\\.count", 5.0, true},
+		{"content.org\\.example\\.count", 6, false},
+		{"content.nothing", nil, true},
+		{"content.missing", nil, false},
+		{"content.m\\.dotted\\.key.x\\\\y", "escaped", true},
+		{"content.m\\.dotted\\.key.x\\y", "escaped", true},
+		{"content.m\\.mentions", nil, false},
+		{"content.m\\.mentions.user_ids", nil, false},
+		{"sender", "@tulir:maunium.net", true},
+		{"type", "m.room.message", true},
+	}
+	for _, test := range tests {
+		condition := &pushrules.PushCondition{Kind: pushrules.KindEventPropertyIs, Key: test.key, Value: test.value}
+		assert.Equal(t, test.expected, condition.Match(blankTestRoom, event), "%s == %v", test.key, test.value)
+	}
+}
+
+func TestPushCondition_Match_KindEventPropertyContains(t *testing.T) {
+	event := newJSONEvent(t, propertyTestContent)
+	tests := []struct {
+		key      string
+		value    interface{}
+		expected bool
+	}{
+		{"content.m\\.mentions.user_ids", "@tulir:maunium.net", true},
+		{"content.m\\.mentions.user_ids", "@other:maunium.net", true},
+		{"content.m\\.mentions.user_ids", "@nobody:maunium.net", false},
+		{"content.m\\.mentions.user_ids", nil, false},
+		{"content.m\\.mentions.room", true, false},
+		{"content.body", "hello", false},
+		{"content.missing", "hello", false},
+	}
+	for _, test := range tests {
+		condition := &pushrules.PushCondition{Kind: pushrules.KindEventPropertyContains, Key: test.key, Value: test.value}
+		assert.Equal(t, test.expected, condition.Match(blankTestRoom, event), "%s contains %v", test.key, test.value)
+	}
+}
+
+func TestPushCondition_MarshalJSON_RoundTrip(t *testing.T) {
+	for _, value := range []interface{}{false, 0.0, "", nil, "m.text"} {
+		condition := &pushrules.PushCondition{Kind: pushrules.KindEventPropertyIs, Key: "content.field", Value: value}
+		data, err := json.Marshal(condition)
+		assert.Nil(t, err)
+		var raw map[string]interface{}
+		assert.Nil(t, json.Unmarshal(data, &raw))
+		assert.Contains(t, raw, "value", "%s", data)
+
+		var parsed pushrules.PushCondition
+		assert.Nil(t, json.Unmarshal(data, &parsed))
+		assert.Equal(t, *condition, parsed)
+	}
+
+	data, err := json.Marshal(newMatchPushCondition("content.body", "hello"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "value")
+}
+
+func TestPushCondition_Match_KindEvent_EscapedKey(t *testing.T) {
+	event := newJSONEvent(t, propertyTestContent)
+	assert.True(t, newMatchPushCondition("content.m\\.relates_to.rel_type", "m.thr*").Match(blankTestRoom, event))
+	assert.False(t, newMatchPushCondition("content.m\\.relates_to", "*").Match(blankTestRoom, event))
+	assert.False(t, newMatchPushCondition("content.org\\.example\\.count", "*").Match(blankTestRoom, event))
+}
+
+func newPowerLevelRoom(t *testing.T, powerLevels string) *FakeRoom {
+	room := newFakeRoom(2)
+	if len(powerLevels) > 0 {
+		plEvent := &mautrix.Event{Type: mautrix.StatePowerLevels}
+		assert.Nil(t, json.Unmarshal([]byte(powerLevels), &plEvent.Content))
+		room.state = map[string]*mautrix.Event{mautrix.StatePowerLevels.Type + "|": plEvent}
+	}
+	return room
+}
+
+func TestPushCondition_Match_KindSenderNotificationPermission(t *testing.T) {
+	tests := []struct {
+		powerLevels string
+		sender      string
+		expected    bool
+	}{
+		{`{"users": {"@tulir:maunium.net": 50}}`, "@tulir:maunium.net", true},
+		{`{"users": {"@tulir:maunium.net": 49}}`, "@tulir:maunium.net", false},
+		{`{"users": {"@tulir:maunium.net": 10}, "notifications": {"room": 10}}`, "@tulir:maunium.net", true},
+		{`{"users_default": 100}`, "@extrauser_0:matrix.org", true},
+		{`{"notifications": {"room": 0}}`, "@extrauser_0:matrix.org", true},
+		{`{"notifications": {"other": 0}}`, "@extrauser_0:matrix.org", false},
+		{"", "@tulir:maunium.net", false},
+	}
+	for _, test := range tests {
+		room := newPowerLevelRoom(t, test.powerLevels)
+		event := newFakeEvent(mautrix.EventMessage, mautrix.Content{})
+		event.Sender = test.sender
+		condition := &pushrules.PushCondition{Kind: pushrules.KindSenderNotificationPermission, Key: "room"}
+		assert.Equal(t, test.expected, condition.Match(room, event), "%s with %s", test.sender, test.powerLevels)
+	}
+}
+
+func TestPushCondition_Match_KindRelatedEventMatch(t *testing.T) {
+	room := newFakeRoom(2)
+	room.events = map[string]*mautrix.Event{
+		"$root": newJSONEvent(t, `{"msgtype": "m.text", "body": "root"}`),
+	}
+	room.events["$root"].Sender = "@other:maunium.net"
+
+	reply := newJSONEvent(t, `{"body": "reply", "m.relates_to": {"m.in_reply_to": {"event_id": "$root"}}}`)
+	fallbackReply := newJSONEvent(t, `{"body": "reply", "m.relates_to": {"rel_type": "m.thread", "event_id": "$root", "is_falling_back": true, "m.in_reply_to": {"event_id": "$root"}}}`)
+	missingReply := newJSONEvent(t, `{"body": "reply", "m.relates_to": {"m.in_reply_to": {"event_id": "$missing"}}}`)
+	noRelation := newJSONEvent(t, `{"body": "not a reply"}`)
+
+	tests := []struct {
+		event            *mautrix.Event
+		relType          string
+		includeFallbacks bool
+		key              string
+		pattern          string
+		expected         bool
+	}{
+		{reply, pushrules.RelInReplyTo, false, "sender", "@other:maunium.net", true},
+		{reply, pushrules.RelInReplyTo, false, "sender", "@tulir:maunium.net", false},
+		{reply, pushrules.RelInReplyTo, false, "", "", true},
+		{reply, "m.thread", false, "", "", false},
+		{fallbackReply, pushrules.RelInReplyTo, false, "sender", "@other:maunium.net", false},
+		{fallbackReply, pushrules.RelInReplyTo, true, "sender", "@other:maunium.net", true},
+		{fallbackReply, "m.thread", false, "content.body", "ro*", true},
+		{missingReply, pushrules.RelInReplyTo, false, "", "", false},
+		{noRelation, pushrules.RelInReplyTo, false, "", "", false},
+	}
+	for i, test := range tests {
+		condition := &pushrules.PushCondition{
+			Kind:             pushrules.KindRelatedEventMatch,
+			RelType:          test.relType,
+			IncludeFallbacks: test.includeFallbacks,
+			Key:              test.key,
+			Pattern:          test.pattern,
+		}
+		assert.Equal(t, test.expected, condition.Match(room, test.event), "test #%d", i)
+	}
+
+	// Rooms that can't fetch events never match.
+	condition := &pushrules.PushCondition{Kind: pushrules.KindRelatedEventMatch, RelType: pushrules.RelInReplyTo}
+	assert.False(t, condition.Match(blankTestRoom, reply))
+}
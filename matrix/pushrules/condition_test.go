@@ -93,6 +93,8 @@ func TestPushCondition_Match_InvalidKind(t *testing.T) {
 type FakeRoom struct {
 	members map[string]*mautrix.Member
 	owner   string
+	state   map[string]*mautrix.Event
+	events  map[string]*mautrix.Event
 }
 
 func newFakeRoom(memberCount int) *FakeRoom {
@@ -130,3 +132,11 @@ func (fr *FakeRoom) GetSessionOwner() string {
 func (fr *FakeRoom) GetMembers() map[string]*mautrix.Member {
 	return fr.members
 }
+
+func (fr *FakeRoom) GetStateEvent(eventType mautrix.EventType, stateKey string) *mautrix.Event {
+	return fr.state[eventType.Type+"|"+stateKey]
+}
+
+func (fr *FakeRoom) GetEvent(eventID string) *mautrix.Event {
+	return fr.events[eventID]
+}
@@ -35,3 +35,37 @@ func EventToPushRules(event *mautrix.Event) (*PushRuleset, error) {
 
 	return content.Ruleset, nil
 }
+
+type pushRuleRequest struct {
+	Actions    PushActionArray  `json:"actions"`
+	Conditions []*PushCondition `json:"conditions,omitempty"`
+	Pattern    string           `json:"pattern,omitempty"`
+}
+
+// PutPushRule creates or replaces the given push rule in the global scope.
+// The type and ID of the rule determine where the rule is stored.
+func PutPushRule(client *mautrix.Client, rule *PushRule) error {
+	req := &pushRuleRequest{
+		Actions:    rule.Actions,
+		Conditions: rule.Conditions,
+		Pattern:    rule.Pattern,
+	}
+	if req.Actions == nil {
+		req.Actions = PushActionArray{}
+	}
+	_, err := client.MakeRequest("PUT", client.BuildURL("pushrules", "global", string(rule.Type), rule.RuleID), req, nil)
+	return err
+}
+
+// DeletePushRule deletes the push rule with the given type and ID from the global scope.
+func DeletePushRule(client *mautrix.Client, ruleType PushRuleType, ruleID string) error {
+	_, err := client.MakeRequest("DELETE", client.BuildURL("pushrules", "global", string(ruleType), ruleID), nil, nil)
+	return err
+}
+
+// SetPushRuleEnabled enables or disables the push rule with the given type and ID in the global scope.
+func SetPushRuleEnabled(client *mautrix.Client, ruleType PushRuleType, ruleID string, enabled bool) error {
+	req := map[string]bool{"enabled": enabled}
+	_, err := client.MakeRequest("PUT", client.BuildURL("pushrules", "global", string(ruleType), ruleID, "enabled"), &req, nil)
+	return err
+}
@@ -47,7 +47,7 @@ func (rules PushRuleArray) GetActions(room Room, event *mautrix.Event) PushActio
 		if !rule.Match(room, event) {
 			continue
 		}
-		return rule.Actions
+		return rule.GetActions()
 	}
 	return nil
 }
@@ -79,7 +79,7 @@ func (ruleMap PushRuleMap) GetActions(room Room, event *mautrix.Event) PushActio
 		rule, found = ruleMap.Map[event.Sender]
 	}
 	if found && rule.Match(room, event) {
-		return rule.Actions
+		return rule.GetActions()
 	}
 	return nil
 }
@@ -124,6 +124,19 @@ type PushRule struct {
 	Pattern string `json:"pattern,omitempty"`
 }
 
+// dontNotifyActions are the actions of matching rules that have no actions.
+var dontNotifyActions = PushActionArray{{Action: ActionDontNotify}}
+
+// GetActions returns the actions of this rule. Newer spec versions deprecate dont_notify
+// in favor of an empty list of actions, which is converted back to dont_notify here so that
+// it isn't mistaken for the default actions used when no rules match.
+func (rule *PushRule) GetActions() PushActionArray {
+	if len(rule.Actions) == 0 {
+		return dontNotifyActions
+	}
+	return rule.Actions
+}
+
 func (rule *PushRule) Match(room Room, event *mautrix.Event) bool {
 	if !rule.Enabled {
 		return false
@@ -292,3 +292,19 @@ func TestPushRuleArray_SetTypeAndMap(t *testing.T) {
 		assert.Contains(t, newRuleArray, rule)
 	}
 }
+
+func TestPushRuleArray_GetActions_EmptyActionsMeanDontNotify(t *testing.T) {
+	rules := pushrules.PushRuleArray{{
+		Type:       pushrules.OverrideRule,
+		Enabled:    true,
+		Conditions: []*pushrules.PushCondition{newMatchPushCondition("content.msgtype", "m.notice")},
+		Actions:    pushrules.PushActionArray{},
+	}}
+	event := newFakeEvent(mautrix.EventMessage, mautrix.Content{
+		Raw:     map[string]interface{}{"msgtype": "m.notice"},
+		MsgType: mautrix.MsgNotice,
+	})
+	should := rules.GetActions(blankTestRoom, event).Should()
+	assert.True(t, should.NotifySpecified)
+	assert.False(t, should.Notify)
+}
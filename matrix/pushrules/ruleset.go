@@ -93,3 +93,51 @@ func (rs *PushRuleset) GetActions(room Room, event *mautrix.Event) (match PushAc
 	// No match found, return default actions.
 	return DefaultPushActions
 }
+
+// RoomNotificationLevel is the notification setting of a single room, which is stored as room-specific rules.
+type RoomNotificationLevel string
+
+const (
+	// RoomNotifyAll means that the room has no room-specific rules, so the other rules decide.
+	RoomNotifyAll RoomNotificationLevel = "all"
+	// RoomNotifyMentions means that a room rule disables notifications for messages that don't match
+	// an override or content rule, such as mentions and keywords.
+	RoomNotifyMentions RoomNotificationLevel = "mentions"
+	// RoomNotifyMute means that an override rule disables all notifications from the room.
+	RoomNotifyMute RoomNotificationLevel = "mute"
+)
+
+// GetRoomNotificationLevel returns the notification level of the given room based on the room-specific rules.
+func (rs *PushRuleset) GetRoomNotificationLevel(roomID string) RoomNotificationLevel {
+	if rule := rs.GetRule(OverrideRule, roomID); rule != nil && rule.Enabled && !rule.Actions.Should().Notify {
+		return RoomNotifyMute
+	} else if rule := rs.GetRule(RoomRule, roomID); rule != nil && rule.Enabled && !rule.Actions.Should().Notify {
+		return RoomNotifyMentions
+	}
+	return RoomNotifyAll
+}
+
+// GetRule returns the rule with the given type and ID, or nil if there is no such rule.
+func (rs *PushRuleset) GetRule(ruleType PushRuleType, ruleID string) *PushRule {
+	switch ruleType {
+	case RoomRule:
+		return rs.Room.Map[ruleID]
+	case SenderRule:
+		return rs.Sender.Map[ruleID]
+	}
+	var rules PushRuleArray
+	switch ruleType {
+	case OverrideRule:
+		rules = rs.Override
+	case ContentRule:
+		rules = rs.Content
+	case UnderrideRule:
+		rules = rs.Underride
+	}
+	for _, rule := range rules {
+		if rule.RuleID == ruleID {
+			return rule
+		}
+	}
+	return nil
+}
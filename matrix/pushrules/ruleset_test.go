@@ -0,0 +1,58 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pushrules_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/matrix/pushrules"
+)
+
+func TestPushRuleset_GetRoomNotificationLevel(t *testing.T) {
+	ruleset := &pushrules.PushRuleset{}
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"override": [{"rule_id": "!muted:example.com", "enabled": true, "actions": ["dont_notify"],
+			"conditions": [{"kind": "event_match", "key": "room_id", "pattern": "!muted:example.com"}]}],
+		"room": [
+			{"rule_id": "!mentions:example.com", "enabled": true, "actions": ["dont_notify"]},
+			{"rule_id": "!disabled:example.com", "enabled": false, "actions": ["dont_notify"]},
+			{"rule_id": "!loud:example.com", "enabled": true, "actions": ["notify", {"set_tweak": "sound", "value": "default"}]}
+		]
+	}`), ruleset))
+
+	assert.Equal(t, pushrules.RoomNotifyMute, ruleset.GetRoomNotificationLevel("!muted:example.com"))
+	assert.Equal(t, pushrules.RoomNotifyMentions, ruleset.GetRoomNotificationLevel("!mentions:example.com"))
+	assert.Equal(t, pushrules.RoomNotifyAll, ruleset.GetRoomNotificationLevel("!disabled:example.com"))
+	assert.Equal(t, pushrules.RoomNotifyAll, ruleset.GetRoomNotificationLevel("!loud:example.com"))
+	assert.Equal(t, pushrules.RoomNotifyAll, ruleset.GetRoomNotificationLevel("!unknown:example.com"))
+}
+
+func TestPushRuleset_GetRule(t *testing.T) {
+	ruleset := &pushrules.PushRuleset{}
+	assert.Nil(t, json.Unmarshal([]byte(JSONExamplePushRules), &struct {
+		Global *pushrules.PushRuleset `json:"global"`
+	}{ruleset}))
+
+	rule := ruleset.GetRule(pushrules.ContentRule, ".m.rule.contains_user_name")
+	assert.NotNil(t, rule)
+	assert.Equal(t, "alice", rule.Pattern)
+	assert.Nil(t, ruleset.GetRule(pushrules.OverrideRule, ".m.rule.contains_user_name"))
+	assert.Nil(t, ruleset.GetRule(pushrules.RoomRule, "!foo:example.com"))
+}
@@ -0,0 +1,100 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package relations
+
+import (
+	"github.com/tulir/mautrix-go"
+)
+
+// Relation types supported by gomuks.
+const (
+	// RelThread is the relation type of thread replies. See MSC3440.
+	RelThread = "m.thread"
+	// RelReplace is the relation type of edits. See MSC2676.
+	RelReplace = "m.replace"
+)
+
+func getRelation(content *mautrix.Content, relType string) (map[string]interface{}, string) {
+	relatesTo, ok := content.Raw["m.relates_to"].(map[string]interface{})
+	if !ok {
+		return nil, ""
+	}
+	if actualType, _ := relatesTo["rel_type"].(string); actualType != relType {
+		return nil, ""
+	}
+	eventID, _ := relatesTo["event_id"].(string)
+	return relatesTo, eventID
+}
+
+// GetThreadRoot returns the ID of the thread root event if the given content is a thread reply.
+func GetThreadRoot(content *mautrix.Content) string {
+	_, eventID := getRelation(content, RelThread)
+	return eventID
+}
+
+// IsThreadFallback returns whether or not the reply relation of the given thread reply only exists
+// for clients that don't support threads, i.e. the event isn't a real reply within the thread.
+func IsThreadFallback(content *mautrix.Content) bool {
+	relatesTo, eventID := getRelation(content, RelThread)
+	if len(eventID) == 0 {
+		return false
+	}
+	isFallingBack, _ := relatesTo["is_falling_back"].(bool)
+	return isFallingBack
+}
+
+// SetThreadRoot makes the given content a reply in the thread of the given root event.
+// The relation is included when the event is sent with Container.SendEvent.
+func SetThreadRoot(content *mautrix.Content, rootID string) {
+	if content.Raw == nil {
+		content.Raw = make(map[string]interface{})
+	}
+	content.Raw["m.relates_to"] = map[string]interface{}{
+		"rel_type": RelThread,
+		"event_id": rootID,
+	}
+}
+
+// GetEditTarget returns the ID of the edited event if the given content is an edit.
+func GetEditTarget(content *mautrix.Content) string {
+	_, eventID := getRelation(content, RelReplace)
+	return eventID
+}
+
+// SetEditTarget makes the given content an edit of the given event. The current body becomes the new content
+// and the body itself is prefixed with an asterisk, which is how clients without edit support show it.
+// The relation is included when the event is sent with Container.SendEvent.
+func SetEditTarget(content *mautrix.Content, eventID string) {
+	if content.Raw == nil {
+		content.Raw = make(map[string]interface{})
+	}
+	newContent := map[string]interface{}{
+		"msgtype": content.MsgType,
+		"body":    content.Body,
+	}
+	if len(content.FormattedBody) > 0 {
+		newContent["format"] = content.Format
+		newContent["formatted_body"] = content.FormattedBody
+		content.FormattedBody = "* " + content.FormattedBody
+	}
+	content.Body = "* " + content.Body
+	content.Raw["m.new_content"] = newContent
+	content.Raw["m.relates_to"] = map[string]interface{}{
+		"rel_type": RelReplace,
+		"event_id": eventID,
+	}
+}
@@ -0,0 +1,62 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package relations_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/matrix/relations"
+)
+
+func TestGetThreadRoot(t *testing.T) {
+	var content mautrix.Content
+	assert.Nil(t, json.Unmarshal([]byte(`{"body": "hi", "m.relates_to": {"rel_type": "m.thread", "event_id": "$root"}}`), &content))
+	assert.Equal(t, "$root", relations.GetThreadRoot(&content))
+	assert.False(t, relations.IsThreadFallback(&content))
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"body": "hi", "m.relates_to": {"rel_type": "m.thread", "event_id": "$root", "is_falling_back": true, "m.in_reply_to": {"event_id": "$root"}}}`), &content))
+	assert.Equal(t, "$root", relations.GetThreadRoot(&content))
+	assert.True(t, relations.IsThreadFallback(&content))
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"body": "hi", "m.relates_to": {"m.in_reply_to": {"event_id": "$root"}}}`), &content))
+	assert.Empty(t, relations.GetThreadRoot(&content))
+}
+
+func TestGetEditTarget(t *testing.T) {
+	var content mautrix.Content
+	assert.Nil(t, json.Unmarshal([]byte(`{"body": "* hi", "m.new_content": {"body": "hi"}, "m.relates_to": {"rel_type": "m.replace", "event_id": "$orig"}}`), &content))
+	assert.Equal(t, "$orig", relations.GetEditTarget(&content))
+	assert.Empty(t, relations.GetThreadRoot(&content))
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"body": "hi", "m.relates_to": {"rel_type": "m.thread", "event_id": "$root"}}`), &content))
+	assert.Empty(t, relations.GetEditTarget(&content))
+}
+
+func TestSetEditTarget(t *testing.T) {
+	content := mautrix.Content{MsgType: mautrix.MsgText, Body: "fixed"}
+	relations.SetEditTarget(&content, "$orig")
+	assert.Equal(t, "* fixed", content.Body)
+	assert.Equal(t, "$orig", relations.GetEditTarget(&content))
+	assert.Equal(t, map[string]interface{}{
+		"msgtype": mautrix.MsgText,
+		"body":    "fixed",
+	}, content.Raw["m.new_content"])
+}
@@ -76,6 +76,12 @@ type Room struct {
 	unreadCountCache *int
 	highlightCache   *bool
 	lastMarkedRead   string
+	// The unread notification and highlight counts from the server. If HasServerCounts is true,
+	// they're used instead of counting UnreadMessages, which only contains the messages
+	// received and evaluated locally since the cache was last cleared.
+	NotificationCount int
+	HighlightCount    int
+	HasServerCounts   bool
 	// Whether or not this room is marked as a direct chat.
 	IsDirect bool
 
@@ -83,12 +89,19 @@ type Room struct {
 	RawTags []RoomTag
 	// Timestamp of previously received actual message.
 	LastReceivedMessage time.Time
+	// The event ID of the m.fully_read marker, i.e. the last event the user has fully read.
+	FullyRead string
+	// MXID -> event ID map of the latest read receipt of each member.
+	ReadReceipts map[string]string
+	receiptLock  sync.RWMutex
 
 	// MXID -> Member cache calculated from membership events.
 	memberCache map[string]*mautrix.Member
 	// The first non-SessionUserID member in the room. Calculated at
 	// the same time as memberCache.
 	firstMemberCache *mautrix.Member
+	// The user ID of firstMemberCache.
+	firstMemberIDCache string
 	// The name of the room. Calculated from the state event name,
 	// canonical_alias or alias or the member cache.
 	nameCache string
@@ -163,7 +176,25 @@ func (room *Room) MarkRead(eventID string) bool {
 	return true
 }
 
+// SetUnreadCounts sets the unread notification and highlight counts received from the server.
+func (room *Room) SetUnreadCounts(notifications, highlights int) {
+	room.NotificationCount = notifications
+	room.HighlightCount = highlights
+	room.HasServerCounts = true
+}
+
+// ClearUnreadCounts resets the unread counts after the room was read locally.
+// The server confirms the new counts in a later sync.
+func (room *Room) ClearUnreadCounts() {
+	room.NotificationCount = 0
+	room.HighlightCount = 0
+}
+
+// UnreadCount returns the number of unread messages that were notified about.
 func (room *Room) UnreadCount() int {
+	if room.HasServerCounts {
+		return room.NotificationCount
+	}
 	if room.unreadCountCache == nil {
 		room.unreadCountCache = new(int)
 		for _, unreadMessage := range room.UnreadMessages {
@@ -175,7 +206,11 @@ func (room *Room) UnreadCount() int {
 	return *room.unreadCountCache
 }
 
+// Highlighted returns whether or not there are unread highlights in the room.
 func (room *Room) Highlighted() bool {
+	if room.HasServerCounts {
+		return room.HighlightCount > 0
+	}
 	if room.highlightCache == nil {
 		room.highlightCache = new(bool)
 		for _, unreadMessage := range room.UnreadMessages {
@@ -189,7 +224,7 @@ func (room *Room) Highlighted() bool {
 }
 
 func (room *Room) HasNewMessages() bool {
-	return len(room.UnreadMessages) > 0
+	return len(room.UnreadMessages) > 0 || room.NotificationCount > 0
 }
 
 func (room *Room) AddUnread(eventID string, counted, highlight bool) {
@@ -212,6 +247,29 @@ func (room *Room) AddUnread(eventID string, counted, highlight bool) {
 	}
 }
 
+// SetReadReceipt stores the event ID that the given user has most recently read.
+func (room *Room) SetReadReceipt(userID, eventID string) {
+	room.receiptLock.Lock()
+	defer room.receiptLock.Unlock()
+	if room.ReadReceipts == nil {
+		room.ReadReceipts = make(map[string]string)
+	}
+	room.ReadReceipts[userID] = eventID
+}
+
+// GetReadReceipts returns the IDs of users whose latest read receipt is the given event.
+func (room *Room) GetReadReceipts(eventID string) (userIDs []string) {
+	room.receiptLock.RLock()
+	defer room.receiptLock.RUnlock()
+	for userID, readEventID := range room.ReadReceipts {
+		if readEventID == eventID {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	return
+}
+
 func (room *Room) Tags() []RoomTag {
 	if len(room.RawTags) == 0 {
 		if room.IsDirect {
@@ -280,6 +338,22 @@ func (room *Room) GetStateEvents(eventType mautrix.EventType) map[string]*mautri
 	return stateEventMap
 }
 
+// GetPinnedEvents returns the IDs of the events pinned in the room.
+func (room *Room) GetPinnedEvents() []string {
+	event := room.GetStateEvent(mautrix.StatePinnedEvents, "")
+	if event == nil {
+		return nil
+	}
+	rawPinned, _ := event.Content.Raw["pinned"].([]interface{})
+	pinned := make([]string, 0, len(rawPinned))
+	for _, rawEventID := range rawPinned {
+		if eventID, ok := rawEventID.(string); ok {
+			pinned = append(pinned, eventID)
+		}
+	}
+	return pinned
+}
+
 // GetTopic returns the topic of the room.
 func (room *Room) GetTopic() string {
 	if len(room.topicCache) == 0 {
@@ -396,6 +470,7 @@ func (room *Room) createMemberCache() map[string]*mautrix.Member {
 	cache := make(map[string]*mautrix.Member)
 	events := room.GetStateEvents(mautrix.StateMember)
 	room.firstMemberCache = nil
+	room.firstMemberIDCache = ""
 	if events != nil {
 		for userID, event := range events {
 			member := &event.Content.Member
@@ -405,6 +480,7 @@ func (room *Room) createMemberCache() map[string]*mautrix.Member {
 			}
 			if room.firstMemberCache == nil && userID != room.SessionUserID {
 				room.firstMemberCache = member
+				room.firstMemberIDCache = userID
 			}
 			if member.Membership == mautrix.MembershipJoin || member.Membership == mautrix.MembershipInvite {
 				cache[userID] = member
@@ -436,6 +512,16 @@ func (room *Room) GetMember(userID string) *mautrix.Member {
 	return member
 }
 
+// GetDirectChatPartner returns the user ID of the other user in a direct chat.
+// If the room is not a direct chat or there are no other members, an empty string is returned.
+func (room *Room) GetDirectChatPartner() string {
+	if !room.IsDirect {
+		return ""
+	}
+	room.GetMembers()
+	return room.firstMemberIDCache
+}
+
 // GetSessionOwner returns the ID of the user whose session this room was created for.
 func (room *Room) GetSessionOwner() string {
 	return room.SessionUserID
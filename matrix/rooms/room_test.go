@@ -235,3 +235,52 @@ func TestRoom_MarkRead(t *testing.T) {
 	room.MarkRead("asd")
 	assert.Empty(t, room.UnreadMessages)
 }
+
+func TestRoom_ServerUnreadCounts(t *testing.T) {
+	room := rooms.NewRoom("!test:maunium.net", "@tulir:maunium.net")
+	room.AddUnread("foo", true, true)
+	assert.Equal(t, 1, room.UnreadCount())
+
+	// The server counts take precedence over the locally counted messages.
+	room.SetUnreadCounts(5, 0)
+	assert.Equal(t, 5, room.UnreadCount())
+	assert.False(t, room.Highlighted())
+	assert.True(t, room.HasNewMessages())
+
+	room.MarkRead("foo")
+	room.ClearUnreadCounts()
+	assert.Equal(t, 0, room.UnreadCount())
+	assert.False(t, room.HasNewMessages())
+}
+
+func TestRoom_ReadReceipts(t *testing.T) {
+	room := rooms.NewRoom("!test:maunium.net", "@tulir:maunium.net")
+	assert.Empty(t, room.GetReadReceipts("$foo"))
+
+	room.SetReadReceipt("@user2:example.com", "$foo")
+	room.SetReadReceipt("@user1:example.com", "$foo")
+	room.SetReadReceipt("@user3:example.com", "$bar")
+	assert.Equal(t, []string{"@user1:example.com", "@user2:example.com"}, room.GetReadReceipts("$foo"))
+	assert.Equal(t, []string{"@user3:example.com"}, room.GetReadReceipts("$bar"))
+
+	room.SetReadReceipt("@user2:example.com", "$bar")
+	assert.Equal(t, []string{"@user1:example.com"}, room.GetReadReceipts("$foo"))
+	assert.Equal(t, []string{"@user2:example.com", "@user3:example.com"}, room.GetReadReceipts("$bar"))
+}
+
+func TestRoom_GetPinnedEvents(t *testing.T) {
+	room := rooms.NewRoom("!test:maunium.net", "@tulir:maunium.net")
+	assert.Empty(t, room.GetPinnedEvents())
+
+	stateKey := ""
+	room.UpdateState(&mautrix.Event{
+		Type:     mautrix.StatePinnedEvents,
+		StateKey: &stateKey,
+		Content: mautrix.Content{
+			Raw: map[string]interface{}{
+				"pinned": []interface{}{"$foo", "$bar", 5},
+			},
+		},
+	})
+	assert.Equal(t, []string{"$foo", "$bar"}, room.GetPinnedEvents())
+}
@@ -85,6 +85,46 @@ func (es EventSource) String() string {
 
 type EventHandler func(source EventSource, event *mautrix.Event)
 
+// UnreadNotificationCounts contains the number of unread notifications and highlights in a room as counted by the server.
+type UnreadNotificationCounts struct {
+	NotificationCount int `json:"notification_count"`
+	HighlightCount    int `json:"highlight_count"`
+}
+
+// SyncResponse is a sync response with the fields that mautrix.RespSync doesn't include.
+type SyncResponse struct {
+	mautrix.RespSync
+	// The unread notification counts of joined rooms. Rooms without counts are not included.
+	UnreadCounts map[string]UnreadNotificationCounts
+}
+
+type syncResponseExtras struct {
+	Rooms struct {
+		Join map[string]struct {
+			UnreadNotifications *UnreadNotificationCounts `json:"unread_notifications"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// ParseSyncResponse parses a raw sync response body.
+func ParseSyncResponse(data []byte) (*SyncResponse, error) {
+	var res SyncResponse
+	if err := json.Unmarshal(data, &res.RespSync); err != nil {
+		return nil, err
+	}
+	var extras syncResponseExtras
+	if err := json.Unmarshal(data, &extras); err != nil {
+		return nil, err
+	}
+	res.UnreadCounts = make(map[string]UnreadNotificationCounts)
+	for roomID, roomData := range extras.Rooms.Join {
+		if roomData.UnreadNotifications != nil {
+			res.UnreadCounts[roomID] = *roomData.UnreadNotifications
+		}
+	}
+	return &res, nil
+}
+
 // GomuksSyncer is the default syncing implementation. You can either write your own syncer, or selectively
 // replace parts of this default syncer (e.g. the ProcessResponse method). The default syncer uses the observer
 // pattern to notify callers about incoming events. See GomuksSyncer.OnEventType for more information.
@@ -106,12 +146,26 @@ func NewGomuksSyncer(session SyncerSession) *GomuksSyncer {
 
 // ProcessResponse processes a Matrix sync response.
 func (s *GomuksSyncer) ProcessResponse(res *mautrix.RespSync, since string) (err error) {
+	return s.processResponse(res, nil, since)
+}
+
+// ProcessSyncResponse processes a Matrix sync response that includes the unread notification counts of rooms.
+func (s *GomuksSyncer) ProcessSyncResponse(res *SyncResponse, since string) (err error) {
+	return s.processResponse(&res.RespSync, res.UnreadCounts, since)
+}
+
+func (s *GomuksSyncer) processResponse(res *mautrix.RespSync, unreadCounts map[string]UnreadNotificationCounts, since string) (err error) {
 	debug.Print("Received sync response")
 	s.processSyncEvents(nil, res.Presence.Events, EventSourcePresence)
 	s.processSyncEvents(nil, res.AccountData.Events, EventSourceAccountData)
 
 	for roomID, roomData := range res.Rooms.Join {
 		room := s.Session.GetRoom(roomID)
+		// The counts are set before processing events so that rooms which are read locally
+		// while processing the timeline don't end up with stale counts.
+		if counts, ok := unreadCounts[roomID]; ok {
+			room.SetUnreadCounts(counts.NotificationCount, counts.HighlightCount)
+		}
 		s.processSyncEvents(room, roomData.State.Events, EventSourceJoin|EventSourceState)
 		s.processSyncEvents(room, roomData.Timeline.Events, EventSourceJoin|EventSourceTimeline)
 		s.processSyncEvents(room, roomData.Ephemeral.Events, EventSourceJoin|EventSourceEphemeral)
@@ -208,6 +262,7 @@ func (s *GomuksSyncer) GetFilterJSON(userID string) json.RawMessage {
 					"m.room.canonical_alias",
 					"m.room.aliases",
 					"m.room.power_levels",
+					"m.room.pinned_events",
 				},
 			},
 			Timeline: mautrix.FilterPart{
@@ -219,6 +274,13 @@ func (s *GomuksSyncer) GetFilterJSON(userID string) json.RawMessage {
 					"m.room.canonical_alias",
 					"m.room.aliases",
 					"m.room.power_levels",
+					"m.room.pinned_events",
+					"m.poll.start",
+					"m.poll.response",
+					"m.poll.end",
+					"org.matrix.msc3381.poll.start",
+					"org.matrix.msc3381.poll.response",
+					"org.matrix.msc3381.poll.end",
 				},
 				Limit: 50,
 			},
@@ -226,14 +288,14 @@ func (s *GomuksSyncer) GetFilterJSON(userID string) json.RawMessage {
 				Types: []string{"m.typing", "m.receipt"},
 			},
 			AccountData: mautrix.FilterPart{
-				Types: []string{"m.tag"},
+				Types: []string{"m.tag", "m.fully_read"},
 			},
 		},
 		AccountData: mautrix.FilterPart{
-			Types: []string{"m.push_rules", "m.direct", "net.maunium.gomuks.preferences"},
+			Types: []string{"m.push_rules", "m.direct", "m.ignored_user_list", "net.maunium.gomuks.preferences"},
 		},
 		Presence: mautrix.FilterPart{
-			Types: []string{},
+			Types: []string{"m.presence"},
 		},
 	}
 	rawFilter, _ := json.Marshal(&filter)
@@ -131,6 +131,34 @@ func TestGomuksSyncer_ProcessResponse(t *testing.T) {
 	assert.Contains(t, ml.received, leaveEvt, leaveEvt.ID)
 }
 
+func TestParseSyncResponse_UnreadCounts(t *testing.T) {
+	res, err := matrix.ParseSyncResponse([]byte(`{
+		"next_batch": "456",
+		"rooms": {"join": {
+			"!foo:maunium.net": {"unread_notifications": {"notification_count": 3, "highlight_count": 1}},
+			"!bar:maunium.net": {"timeline": {"events": []}}
+		}}
+	}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "456", res.NextBatch)
+	assert.Len(t, res.Rooms.Join, 2)
+	assert.Equal(t, map[string]matrix.UnreadNotificationCounts{
+		"!foo:maunium.net": {NotificationCount: 3, HighlightCount: 1},
+	}, res.UnreadCounts)
+
+	mss := &mockSyncerSession{
+		rooms: map[string]*rooms.Room{
+			"!foo:maunium.net": rooms.NewRoom("!foo:maunium.net", "@tulir:maunium.net"),
+			"!bar:maunium.net": rooms.NewRoom("!bar:maunium.net", "@tulir:maunium.net"),
+		},
+	}
+	syncer := matrix.NewGomuksSyncer(mss)
+	assert.Nil(t, syncer.ProcessSyncResponse(res, "since"))
+	assert.Equal(t, 3, mss.rooms["!foo:maunium.net"].UnreadCount())
+	assert.True(t, mss.rooms["!foo:maunium.net"].Highlighted())
+	assert.False(t, mss.rooms["!bar:maunium.net"].HasServerCounts)
+}
+
 type mockSyncerSession struct {
 	rooms  map[string]*rooms.Room
 	userID string
@@ -0,0 +1,56 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/matrix/relations"
+)
+
+type threadRelation struct {
+	RelType       string            `json:"rel_type"`
+	EventID       string            `json:"event_id"`
+	IsFallingBack bool              `json:"is_falling_back"`
+	InReplyTo     mautrix.InReplyTo `json:"m.in_reply_to"`
+}
+
+// threadReplyContent is the content of a thread reply.
+// The relation overrides the m.relates_to field of the embedded content, which doesn't support relation types.
+type threadReplyContent struct {
+	*mautrix.Content
+	RelatesTo threadRelation `json:"m.relates_to"`
+}
+
+// getSendableContent returns the content that should be sent for the given event.
+func getSendableContent(event *mautrix.Event) interface{} {
+	if rootID := relations.GetThreadRoot(&event.Content); len(rootID) > 0 {
+		return &threadReplyContent{
+			Content: &event.Content,
+			RelatesTo: threadRelation{
+				RelType: relations.RelThread,
+				EventID: rootID,
+				// Clients that don't support threads show the message as a reply to the root.
+				IsFallingBack: true,
+				InReplyTo:     mautrix.InReplyTo{EventID: rootID},
+			},
+		}
+	} else if len(relations.GetEditTarget(&event.Content)) > 0 {
+		return getEditContent(event)
+	}
+	return event.Content
+}
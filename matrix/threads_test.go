@@ -0,0 +1,56 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/relations"
+)
+
+func TestContainer_SendEvent_ThreadReply(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/_matrix/client/r0/rooms/!foo:example.com/send/m.room.message/") {
+			sent = parseBody(req)
+			return mockResponse(http.StatusOK, `{"event_id": "$reply"}`), nil
+		} else if req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/_matrix/client/r0/rooms/!foo:example.com/typing/") {
+			return mockResponse(http.StatusOK, `{}`), nil
+		}
+		return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	event := c.PrepareMarkdownMessage("!foo:example.com", "m.text", "thread reply")
+	relations.SetThreadRoot(&event.Content, "$root")
+	assert.Equal(t, "$root", relations.GetThreadRoot(&event.Content))
+	evtID, err := c.SendEvent(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "$reply", evtID)
+	assert.Equal(t, "thread reply", sent["body"])
+	assert.Equal(t, map[string]interface{}{
+		"rel_type":        "m.thread",
+		"event_id":        "$root",
+		"is_falling_back": true,
+		"m.in_reply_to":   map[string]interface{}{"event_id": "$root"},
+	}, sent["m.relates_to"])
+}
@@ -0,0 +1,315 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/lib/open"
+)
+
+// User-interactive authentication stage types.
+// See https://matrix.org/docs/spec/client_server/r0.5.0#authentication-types
+const (
+	AuthTypePassword      = "m.login.password"
+	AuthTypeDummy         = "m.login.dummy"
+	AuthTypeTerms         = "m.login.terms"
+	AuthTypeEmailIdentity = "m.login.email.identity"
+)
+
+// UIAResponse is the 401 response body of an endpoint that requires user-interactive authentication.
+type UIAResponse struct {
+	Flows []struct {
+		Stages []string `json:"stages"`
+	} `json:"flows"`
+	Completed []string                   `json:"completed"`
+	Params    map[string]json.RawMessage `json:"params"`
+	Session   string                     `json:"session"`
+
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+// HasCompleted returns whether or not the given stage has been completed.
+func (resp *UIAResponse) HasCompleted(stage string) bool {
+	for _, completed := range resp.Completed {
+		if completed == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// nativeUIAStages contains the stages that gomuks can complete without the web fallback.
+var nativeUIAStages = map[string]bool{
+	AuthTypePassword:      true,
+	AuthTypeDummy:         true,
+	AuthTypeTerms:         true,
+	AuthTypeEmailIdentity: true,
+}
+
+// ChooseFlow returns the stages of the first flow that only contains stages gomuks supports natively.
+// If there is no such flow, the stages of the first flow are returned and unsupported stages will be
+// completed using the web fallback.
+func (resp *UIAResponse) ChooseFlow() []string {
+	for _, flow := range resp.Flows {
+		supported := true
+		for _, stage := range flow.Stages {
+			if !nativeUIAStages[stage] {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			return flow.Stages
+		}
+	}
+	if len(resp.Flows) > 0 {
+		return resp.Flows[0].Stages
+	}
+	return nil
+}
+
+// NextStage returns the first stage of the chosen flow that hasn't been completed yet.
+func (resp *UIAResponse) NextStage() string {
+	for _, stage := range resp.ChooseFlow() {
+		if !resp.HasCompleted(stage) {
+			return stage
+		}
+	}
+	return ""
+}
+
+// UIARequest is a request to an endpoint that may require user-interactive authentication.
+type UIARequest struct {
+	Method string
+	URL    string
+	// The request body. The auth field is added to it automatically.
+	Body map[string]interface{}
+	// The URL path (relative to the client API prefix) used to request email validation tokens,
+	// e.g. []string{"register", "email", "requestToken"}.
+	EmailTokenPath []string
+}
+
+type uiaState struct {
+	*UIARequest
+	clientSecret string
+	sendAttempt  int
+}
+
+// DoUIA sends the given request and completes any user-interactive authentication stages that the server
+// requires, asking the user for input through the given prompter. The successful response is decoded into resBody.
+func (c *Container) DoUIA(req *UIARequest, resBody interface{}, prompter ifc.UIAPrompter) error {
+	if req.Body == nil {
+		req.Body = make(map[string]interface{})
+	}
+	state := &uiaState{UIARequest: req}
+	for {
+		data, err := c.client.MakeRequest(req.Method, req.URL, req.Body, resBody)
+		if err == nil {
+			return nil
+		}
+		httpErr, ok := err.(mautrix.HTTPError)
+		if !ok || httpErr.Code != http.StatusUnauthorized {
+			return err
+		}
+		var uia UIAResponse
+		if jsonErr := json.Unmarshal(data, &uia); jsonErr != nil || len(uia.Flows) == 0 {
+			return err
+		} else if len(uia.ErrCode) > 0 {
+			debug.Printf("Previous user-interactive auth stage failed: %s: %s", uia.ErrCode, uia.Error)
+		}
+		stage := uia.NextStage()
+		if len(stage) == 0 {
+			return fmt.Errorf("no supported authentication flows")
+		}
+		auth, err := c.completeUIAStage(state, &uia, stage, prompter)
+		if err != nil {
+			return err
+		}
+		if nativeUIAStages[stage] {
+			auth["type"] = stage
+		}
+		auth["session"] = uia.Session
+		req.Body["auth"] = auth
+	}
+}
+
+func (c *Container) completeUIAStage(state *uiaState, uia *UIAResponse, stage string, prompter ifc.UIAPrompter) (map[string]interface{}, error) {
+	switch stage {
+	case AuthTypeDummy:
+		return map[string]interface{}{}, nil
+	case AuthTypePassword:
+		password, ok := prompter.Prompt("Password", "Enter your password to continue", true)
+		if !ok {
+			return nil, ifc.ErrUIACancelled
+		}
+		return map[string]interface{}{
+			"identifier": map[string]interface{}{
+				"type": "m.id.user",
+				"user": c.config.UserID,
+			},
+			"user":     c.config.UserID,
+			"password": password,
+		}, nil
+	case AuthTypeTerms:
+		if !prompter.Confirm("Terms of service", "Do you accept the terms of service?\n"+termsText(uia.Params[AuthTypeTerms])) {
+			return nil, ifc.ErrUIACancelled
+		}
+		return map[string]interface{}{}, nil
+	case AuthTypeEmailIdentity:
+		return c.completeEmailStage(state, prompter)
+	default:
+		return c.completeFallbackStage(uia, stage, prompter)
+	}
+}
+
+// termsText formats the policies in the m.login.terms stage parameters into a human-readable list.
+func termsText(rawParams json.RawMessage) string {
+	var params struct {
+		Policies map[string]map[string]json.RawMessage `json:"policies"`
+	}
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		return ""
+	}
+	var buf strings.Builder
+	for _, policy := range params.Policies {
+		var translation struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		}
+		for key, value := range policy {
+			if key == "version" {
+				continue
+			} else if err := json.Unmarshal(value, &translation); err == nil && len(translation.URL) > 0 {
+				// Use the first translation that can be parsed.
+				break
+			}
+		}
+		if len(translation.URL) > 0 {
+			_, _ = fmt.Fprintf(&buf, "* %s: %s\n", translation.Name, translation.URL)
+		}
+	}
+	return buf.String()
+}
+
+func (c *Container) completeEmailStage(state *uiaState, prompter ifc.UIAPrompter) (map[string]interface{}, error) {
+	if len(state.EmailTokenPath) == 0 {
+		return nil, fmt.Errorf("email validation is not supported for this request")
+	}
+	email, ok := prompter.Prompt("Email address", "Enter your email address to receive a validation link", false)
+	if !ok {
+		return nil, ifc.ErrUIACancelled
+	}
+	if len(state.clientSecret) == 0 {
+		state.clientSecret = c.client.TxnID()
+	}
+	state.sendAttempt++
+	var resp struct {
+		SID string `json:"sid"`
+	}
+	_, err := c.client.MakeRequest("POST", c.client.BuildURL(state.EmailTokenPath...), map[string]interface{}{
+		"client_secret": state.clientSecret,
+		"email":         email,
+		"send_attempt":  state.sendAttempt,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !prompter.Confirm("Email validation", "Click the link sent to "+email+", then select OK to continue.") {
+		return nil, ifc.ErrUIACancelled
+	}
+	creds := map[string]interface{}{
+		"sid":           resp.SID,
+		"client_secret": state.clientSecret,
+	}
+	if len(c.config.IdentityServer) > 0 {
+		creds["id_server"] = strings.TrimPrefix(strings.TrimPrefix(c.config.IdentityServer, "https://"), "http://")
+	}
+	return map[string]interface{}{
+		"threepid_creds": creds,
+		// Older servers expect threepidCreds instead of threepid_creds.
+		"threepidCreds": creds,
+	}, nil
+}
+
+// completeFallbackStage completes an unsupported stage by opening the fallback web page in the browser.
+// See https://matrix.org/docs/spec/client_server/r0.5.0#fallback
+func (c *Container) completeFallbackStage(uia *UIAResponse, stage string, prompter ifc.UIAPrompter) (map[string]interface{}, error) {
+	fallbackURL := c.client.BuildURLWithQuery([]string{"auth", stage, "fallback", "web"}, map[string]string{
+		"session": uia.Session,
+	})
+	if err := open.Open(fallbackURL); err != nil {
+		debug.Print("Failed to open fallback auth URL in browser:", err)
+	}
+	if !prompter.Confirm("Authentication", "Complete the authentication step in your browser, then select OK to continue.\n"+fallbackURL) {
+		return nil, ifc.ErrUIACancelled
+	}
+	// The fallback page completes the stage directly, so the next request only needs the session.
+	return map[string]interface{}{}, nil
+}
+
+// Register registers a new account with the given username and password and logs in as it.
+func (c *Container) Register(user, password string, prompter ifc.UIAPrompter) error {
+	var resp mautrix.RespRegister
+	err := c.DoUIA(&UIARequest{
+		Method: "POST",
+		URL:    c.client.BuildURL("register"),
+		Body: map[string]interface{}{
+			"username":                    user,
+			"password":                    password,
+			"initial_device_display_name": "gomuks",
+		},
+		EmailTokenPath: []string{"register", "email", "requestToken"},
+	}, &resp, prompter)
+	if err != nil {
+		return err
+	}
+	c.finishLogin(resp.UserID, resp.AccessToken, resp.DeviceID)
+	return nil
+}
+
+// ChangePassword changes the password of the current user.
+func (c *Container) ChangePassword(newPassword string, prompter ifc.UIAPrompter) error {
+	return c.DoUIA(&UIARequest{
+		Method: "POST",
+		URL:    c.client.BuildURL("account", "password"),
+		Body: map[string]interface{}{
+			"new_password": newPassword,
+		},
+		EmailTokenPath: []string{"account", "password", "email", "requestToken"},
+	}, nil, prompter)
+}
+
+// DeleteDevices deletes the given devices of the current user, which also logs them out.
+// The server usually requires the user to confirm this with their password.
+func (c *Container) DeleteDevices(deviceIDs []string, prompter ifc.UIAPrompter) error {
+	return c.DoUIA(&UIARequest{
+		Method: "POST",
+		URL:    c.client.BuildURL("delete_devices"),
+		Body: map[string]interface{}{
+			"devices": deviceIDs,
+		},
+	}, nil, prompter)
+}
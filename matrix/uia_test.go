@@ -0,0 +1,86 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/interface"
+)
+
+type mockPrompter struct {
+	input   string
+	confirm bool
+	prompts []string
+}
+
+func (mp *mockPrompter) Prompt(title, text string, mask bool) (string, bool) {
+	mp.prompts = append(mp.prompts, title)
+	return mp.input, len(mp.input) > 0
+}
+
+func (mp *mockPrompter) Confirm(title, text string) bool {
+	mp.prompts = append(mp.prompts, title)
+	return mp.confirm
+}
+
+const uiaFlows = `{"session": "xyz", "flows": [{"stages": ["m.login.recaptcha"]}, {"stages": ["m.login.terms", "m.login.password"]}], "params": {}`
+
+func TestContainer_DoUIA(t *testing.T) {
+	var requests []map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost || req.URL.Path != "/_matrix/client/r0/delete_devices" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		}
+		body := parseBody(req)
+		requests = append(requests, body)
+		auth, _ := body["auth"].(map[string]interface{})
+		switch len(requests) {
+		case 1:
+			assert.Nil(t, auth)
+			return mockResponse(http.StatusUnauthorized, uiaFlows+`}`), nil
+		case 2:
+			assert.Equal(t, "m.login.terms", auth["type"])
+			assert.Equal(t, "xyz", auth["session"])
+			return mockResponse(http.StatusUnauthorized, uiaFlows+`, "completed": ["m.login.terms"]}`), nil
+		default:
+			assert.Equal(t, "m.login.password", auth["type"])
+			assert.Equal(t, "hunter2", auth["password"])
+			return mockResponse(http.StatusOK, `{}`), nil
+		}
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	prompter := &mockPrompter{input: "hunter2", confirm: true}
+	err := c.DeleteDevices([]string{"ABCDEF"}, prompter)
+	assert.Nil(t, err)
+	assert.Len(t, requests, 3)
+	assert.Equal(t, []string{"Terms of service", "Password"}, prompter.prompts)
+}
+
+func TestContainer_DoUIA_Cancelled(t *testing.T) {
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		return mockResponse(http.StatusUnauthorized, uiaFlows+`}`), nil
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	err := c.ChangePassword("hunter3", &mockPrompter{})
+	assert.Equal(t, ifc.ErrUIACancelled, err)
+}
@@ -0,0 +1,226 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/tulir/mautrix-go"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/matrix/rooms"
+)
+
+var AccountDataIgnoredUserList = mautrix.NewEventType("m.ignored_user_list")
+
+// GetProfile fetches the global displayname and avatar URL of the given user.
+func (c *Container) GetProfile(userID string) (*ifc.UserProfile, error) {
+	var profile ifc.UserProfile
+	_, err := c.client.MakeRequest("GET", c.client.BuildURL("profile", userID), nil, &profile)
+	return &profile, err
+}
+
+// GetUserDevices fetches the list of devices of the given user from the end-to-end encryption key server.
+func (c *Container) GetUserDevices(userID string) ([]*ifc.Device, error) {
+	var resp struct {
+		DeviceKeys map[string]map[string]struct {
+			Unsigned struct {
+				DeviceDisplayName string `json:"device_display_name"`
+			} `json:"unsigned"`
+		} `json:"device_keys"`
+	}
+	_, err := c.client.MakeRequest("POST", c.client.BuildURL("keys", "query"), map[string]interface{}{
+		"device_keys": map[string][]string{userID: {}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]*ifc.Device, 0, len(resp.DeviceKeys[userID]))
+	for deviceID, keys := range resp.DeviceKeys[userID] {
+		devices = append(devices, &ifc.Device{
+			DeviceID:    deviceID,
+			DisplayName: keys.Unsigned.DeviceDisplayName,
+		})
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].DeviceID < devices[j].DeviceID
+	})
+	return devices, nil
+}
+
+// GetSharedRooms returns the rooms where both the current user and the given user are joined.
+func (c *Container) GetSharedRooms(userID string) []*rooms.Room {
+	var shared []*rooms.Room
+	for _, room := range c.config.Rooms {
+		if room.HasLeft {
+			continue
+		}
+		if member := room.GetMember(userID); member != nil && member.Membership == mautrix.MembershipJoin {
+			shared = append(shared, room)
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		return shared[i].GetTitle() < shared[j].GetTitle()
+	})
+	return shared
+}
+
+// GetDirectChat returns an existing direct chat with the given user, or nil if there isn't one.
+func (c *Container) GetDirectChat(userID string) *rooms.Room {
+	c.directLock.RLock()
+	roomIDs := c.directChats[userID]
+	c.directLock.RUnlock()
+	for _, roomID := range roomIDs {
+		if room, ok := c.config.Rooms[roomID]; ok && !room.HasLeft {
+			return room
+		}
+	}
+	for _, room := range c.config.Rooms {
+		if !room.HasLeft && room.IsDirect && room.GetDirectChatPartner() == userID {
+			return room
+		}
+	}
+	return nil
+}
+
+// CreateDirectChat creates a new direct chat room and invites the given user to it.
+// The room is also added to the m.direct account data of the current user.
+func (c *Container) CreateDirectChat(userID string) (*rooms.Room, error) {
+	resp, err := c.client.CreateRoom(&mautrix.ReqCreateRoom{
+		Invite:   []string{userID},
+		Preset:   "trusted_private_chat",
+		IsDirect: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	room := c.GetRoom(resp.RoomID)
+	room.IsDirect = true
+	if err = c.addDirectChat(userID, resp.RoomID); err != nil {
+		debug.Print("Failed to add", resp.RoomID, "to direct chats:", err)
+	}
+	return room, nil
+}
+
+// addDirectChat adds the given room to the direct chats with the given user in the m.direct account data.
+// The current content is fetched from the server so that changes from other clients aren't lost.
+func (c *Container) addDirectChat(userID, roomID string) error {
+	urlPath := c.client.BuildURL("user", c.config.UserID, "account_data", "m.direct")
+	var content map[string][]string
+	_, err := c.client.MakeRequest("GET", urlPath, nil, &content)
+	if httpErr, ok := err.(mautrix.HTTPError); ok && httpErr.Code == 404 {
+		err = nil
+	} else if err != nil {
+		return err
+	}
+	if content == nil {
+		content = make(map[string][]string)
+	}
+	content[userID] = append(content[userID], roomID)
+	if _, err = c.client.MakeRequest("PUT", urlPath, &content, nil); err != nil {
+		return err
+	}
+	c.directLock.Lock()
+	c.directChats = content
+	c.directLock.Unlock()
+	return nil
+}
+
+// SetPowerLevel changes the power level of the given user in the given room.
+// The current power levels are fetched from the server so that no unknown fields are lost.
+func (c *Container) SetPowerLevel(roomID, userID string, level int) error {
+	content := make(map[string]interface{})
+	err := c.client.StateEvent(roomID, mautrix.StatePowerLevels, "", &content)
+	if err != nil {
+		return err
+	}
+	users, ok := content["users"].(map[string]interface{})
+	if !ok {
+		users = make(map[string]interface{})
+		content["users"] = users
+	}
+	users[userID] = level
+	_, err = c.client.SendStateEvent(roomID, mautrix.StatePowerLevels, "", content)
+	return err
+}
+
+type ignoredUserList struct {
+	IgnoredUsers map[string]struct{} `json:"ignored_users"`
+}
+
+// HandleIgnoredUsers is the event handler for the m.ignored_user_list account data event.
+func (c *Container) HandleIgnoredUsers(source EventSource, evt *mautrix.Event) {
+	var content ignoredUserList
+	if err := json.Unmarshal(evt.Content.VeryRaw, &content); err != nil {
+		debug.Print("Failed to parse ignored user list:", err)
+		return
+	}
+	if content.IgnoredUsers == nil {
+		content.IgnoredUsers = make(map[string]struct{})
+	}
+	c.ignoredLock.Lock()
+	c.config.IgnoredUsers = content.IgnoredUsers
+	c.ignoredLock.Unlock()
+	c.config.SaveIgnoredUsers()
+	debug.Print("Updated ignored user list:", c.GetIgnoredUsers())
+	c.ui.MainView().IgnoredUsersChanged(c.config.UserID)
+}
+
+// IsIgnored returns whether or not the given user is on the ignored user list of the current user.
+func (c *Container) IsIgnored(userID string) bool {
+	c.ignoredLock.RLock()
+	_, ignored := c.config.IgnoredUsers[userID]
+	c.ignoredLock.RUnlock()
+	return ignored
+}
+
+// GetIgnoredUsers returns the ignored user list of the current user.
+func (c *Container) GetIgnoredUsers() []string {
+	c.ignoredLock.RLock()
+	userIDs := make([]string, 0, len(c.config.IgnoredUsers))
+	for userID := range c.config.IgnoredUsers {
+		userIDs = append(userIDs, userID)
+	}
+	c.ignoredLock.RUnlock()
+	sort.Strings(userIDs)
+	return userIDs
+}
+
+// SetIgnored adds the given user to or removes them from the ignored user list of the current user.
+// The local list is updated when the server sends the changed account data in the next sync.
+func (c *Container) SetIgnored(userID string, ignored bool) error {
+	urlPath := c.client.BuildURL("user", c.config.UserID, "account_data", AccountDataIgnoredUserList.Type)
+	var content ignoredUserList
+	_, err := c.client.MakeRequest("GET", urlPath, nil, &content)
+	if httpErr, ok := err.(mautrix.HTTPError); ok && httpErr.Code == 404 {
+		err = nil
+	} else if err != nil {
+		return err
+	}
+	if content.IgnoredUsers == nil {
+		content.IgnoredUsers = make(map[string]struct{})
+	}
+	if ignored {
+		content.IgnoredUsers[userID] = struct{}{}
+	} else {
+		delete(content.IgnoredUsers, userID)
+	}
+	_, err = c.client.MakeRequest("PUT", urlPath, &content, nil)
+	return err
+}
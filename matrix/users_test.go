@@ -0,0 +1,106 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/rooms"
+)
+
+func TestContainer_SetPowerLevel(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if strings.TrimSuffix(req.URL.Path, "/") != "/_matrix/client/r0/rooms/!foo:example.com/state/m.room.power_levels" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		} else if req.Method == http.MethodGet {
+			return mockResponse(http.StatusOK, `{"users": {"@user:example.com": 100}, "notifications": {"room": 50}}`), nil
+		}
+		sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{"event_id": "$foo"}`), nil
+	})}
+
+	assert.Nil(t, c.SetPowerLevel("!foo:example.com", "@other:example.com", 50))
+	assert.Equal(t, map[string]interface{}{
+		"@user:example.com":  float64(100),
+		"@other:example.com": float64(50),
+	}, sent["users"])
+	assert.Equal(t, map[string]interface{}{"room": float64(50)}, sent["notifications"])
+}
+
+func TestContainer_SetIgnored(t *testing.T) {
+	var sent map[string]interface{}
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/_matrix/client/r0/user/@user:example.com/account_data/m.ignored_user_list" {
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		} else if req.Method == http.MethodGet {
+			return mockResponse(http.StatusNotFound, `{"errcode": "M_NOT_FOUND", "error": "Account data not found"}`), nil
+		}
+		sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{}`), nil
+	}), config: &config.Config{UserID: "@user:example.com"}}
+
+	assert.Nil(t, c.SetIgnored("@spam:example.com", true))
+	assert.Equal(t, map[string]interface{}{"@spam:example.com": map[string]interface{}{}}, sent["ignored_users"])
+}
+
+func TestContainer_GetIgnoredUsers(t *testing.T) {
+	c := Container{config: &config.Config{IgnoredUsers: map[string]struct{}{
+		"@spam:example.com": {},
+		"@bot:example.com":  {},
+	}}}
+	assert.True(t, c.IsIgnored("@spam:example.com"))
+	assert.False(t, c.IsIgnored("@user:example.com"))
+	assert.Equal(t, []string{"@bot:example.com", "@spam:example.com"}, c.GetIgnoredUsers())
+}
+
+func TestContainer_CreateDirectChat(t *testing.T) {
+	var sent map[string]interface{}
+	creates := 0
+	c := Container{client: mockClient(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/_matrix/client/r0/createRoom":
+			creates++
+			return mockResponse(http.StatusOK, `{"room_id": "!dm:example.com"}`), nil
+		case req.URL.Path != "/_matrix/client/r0/user/@user:example.com/account_data/m.direct":
+			return nil, fmt.Errorf("unexpected query: %s %s", req.Method, req.URL.Path)
+		case req.Method == http.MethodGet:
+			return mockResponse(http.StatusOK, `{"@other:example.com": ["!old:example.com"]}`), nil
+		}
+		sent = parseBody(req)
+		return mockResponse(http.StatusOK, `{}`), nil
+	}), config: &config.Config{UserID: "@user:example.com", Rooms: make(map[string]*rooms.Room)}}
+
+	assert.Nil(t, c.GetDirectChat("@friend:example.com"))
+	room, err := c.CreateDirectChat("@friend:example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "!dm:example.com", room.ID)
+	assert.True(t, room.IsDirect)
+	assert.Equal(t, map[string]interface{}{
+		"@other:example.com":  []interface{}{"!old:example.com"},
+		"@friend:example.com": []interface{}{"!dm:example.com"},
+	}, sent)
+
+	assert.Equal(t, room, c.GetDirectChat("@friend:example.com"))
+	assert.Equal(t, 1, creates)
+}
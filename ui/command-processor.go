@@ -87,6 +87,7 @@ func NewCommandProcessor(parent *MainView) *CommandProcessor {
 			"unknown-command": cmdUnknownCommand,
 			"help":            cmdHelp,
 			"me":              cmdMe,
+			"compose":         cmdCompose,
 			"quit":            cmdQuit,
 			"clearcache":      cmdClearCache,
 			"leave":           cmdLeave,
@@ -96,12 +97,35 @@ func NewCommandProcessor(parent *MainView) *CommandProcessor {
 			"unban":           cmdUnban,
 			"toggle":          cmdToggle,
 			"logout":          cmdLogout,
+			"account":         cmdAccount,
+			"passwd":          cmdChangePassword,
+			"devices":         cmdDevices,
+			"rename-device":   cmdRenameDevice,
+			"delete-device":   cmdDeleteDevice,
 			"sendevent":       cmdSendEvent,
 			"msendevent":      cmdMSendEvent,
 			"setstate":        cmdSetState,
 			"msetstate":       cmdMSetState,
 			"rainbow":         cmdRainbow,
 			"invite":          cmdInvite,
+			"whois":           cmdWhois,
+			"pin":             cmdPin,
+			"unpin":           cmdUnpin,
+			"pins":            cmdPins,
+			"copy":            cmdCopy,
+			"thread":          cmdThread,
+			"vote":            cmdVote,
+			"endpoll":         cmdEndPoll,
+			"notify":          cmdNotify,
+			"keyword":         cmdKeyword,
+			"pushrules":       cmdPushRules,
+			"dnd":             cmdDoNotDisturb,
+			"keys":            cmdKeys,
+			"nick":            cmdNick,
+			"myroomnick":      cmdMyRoomNick,
+			"avatar":          cmdAvatar,
+			"ignore":          cmdIgnore,
+			"unignore":        cmdUnignore,
 			"hprof":           cmdHeapProfile,
 		},
 	}
@@ -113,8 +137,13 @@ func (ch *CommandProcessor) ParseCommand(roomView *RoomView, text string) *Comma
 	}
 	text = text[1:]
 	split := strings.SplitN(text, " ", -1)
+	container := ch.gomuksPointerContainer
+	if roomView != nil {
+		// Commands are executed through the account the room belongs to.
+		container.Matrix = roomView.matrix
+	}
 	return &Command{
-		gomuksPointerContainer: ch.gomuksPointerContainer,
+		gomuksPointerContainer: container,
 		Handler:                ch,
 
 		Room:        roomView,
@@ -22,12 +22,17 @@ import (
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/lucasb-eyer/go-colorful"
 
 	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/lib/clipboard"
+	"github.com/kennetanti/gomuks/matrix/pushrules"
+	"github.com/kennetanti/gomuks/ui/messages"
 	"github.com/tulir/mautrix-go"
 )
 
@@ -114,16 +119,48 @@ func cmdUnknownCommand(cmd *Command) {
 func cmdHelp(cmd *Command) {
 	cmd.Reply(`/help - Show the temporary help message.
 
-/quit       - Quit gomuks.
-/clearcache - Clear cache and quit gomuks.
-/logout     - Log out of Matrix.
+/quit         - Quit gomuks.
+/clearcache   - Clear cache and quit gomuks.
+/logout [all] - Log out of Matrix (of the current room's account). "all" logs out every session.
+/passwd       - Change your password.
+
+/devices                          - List your devices.
+/rename-device <device id> <name> - Rename a device.
+/delete-device <device id>        - Delete (log out) a device.
+
+/account add              - Log in to an additional account.
+/account remove <user id> - Log out of an additional account.
+/account list             - List logged in accounts.
+
+/nick <name>                    - Change your displayname.
+/myroomnick <name>              - Change your displayname in the current room.
+/avatar [--room] <path|mxc uri> - Change your avatar, or your avatar in the current room with --room.
 
 /me <message>      - Send an emote message.
+/compose [send]    - Write a message in $EDITOR. With "send", the message is sent when the editor exits.
 /rainbow <message> - Send a rainbow message (markdown not supported).
 
+/pin [event id]            - Pin a message, or the latest message if no ID is given.
+/unpin <event id|number>   - Unpin a message.
+/pins                      - Show pinned messages.
+/copy [text|html|id|source] [event id] - Copy a part of a message, or of the latest message if no ID is given.
+/thread [event id]         - Open the thread of a message, or of the latest message if no ID is given.
+/vote <answer number...> [poll event id] - Vote in a poll, or the latest poll if no ID is given.
+/endpoll [poll event id]   - End a poll you started.
+
+/notify [all|mentions|mute]    - Show or change which messages in the current room cause notifications.
+/keyword <add|remove> <word>   - Add or remove a notification keyword, or list keywords without arguments.
+/pushrules                     - Show all push rules and enable or disable them.
+/dnd [duration|off|status]     - Toggle do not disturb mode, or enable it for the given duration (e.g. 1h30m).
+
+/keys [reload] - List the key bindings, or reload them from keybindings.yaml.
+
 /join <room address> - Join a room.
 /leave               - Leave the current room.
 
+/whois  <user id>          - Show information about a user.
+/ignore [user id]          - Ignore a user, or list ignored users.
+/unignore <user id>        - Stop ignoring a user.
 /invite <user id>          - Invite a user.
 /kick   <user id> [reason] - Kick a user.
 /ban    <user id> [reason] - Ban a user.
@@ -298,7 +335,7 @@ func cmdSetState(cmd *Command) {
 
 func cmdToggle(cmd *Command) {
 	if len(cmd.Args) == 0 {
-		cmd.Reply("Usage: /toggle <rooms/users/baremessages/images/typingnotif/emojis>")
+		cmd.Reply("Usage: /toggle <rooms/users/baremessages/images/typingnotif/emojis/receipts/privatereceipts/vimmode>")
 		return
 	}
 	switch cmd.Args[0] {
@@ -314,16 +351,570 @@ func cmdToggle(cmd *Command) {
 		cmd.Config.Preferences.DisableTypingNotifs = !cmd.Config.Preferences.DisableTypingNotifs
 	case "emojis":
 		cmd.Config.Preferences.DisableEmojis = !cmd.Config.Preferences.DisableEmojis
+	case "receipts":
+		cmd.Config.Preferences.HideReadReceipts = !cmd.Config.Preferences.HideReadReceipts
+	case "privatereceipts":
+		cmd.Config.Preferences.PrivateReadReceipts = !cmd.Config.Preferences.PrivateReadReceipts
+	case "vimmode":
+		cmd.Config.Preferences.VimMode = !cmd.Config.Preferences.VimMode
 	default:
-		cmd.Reply("Usage: /toggle <rooms/users/baremessages/images/typingnotif/emojis>")
+		cmd.Reply("Usage: /toggle <rooms/users/baremessages/images/typingnotif/emojis/receipts/privatereceipts/vimmode>")
 		return
 	}
 	// is there a reason this is called twice?
 	// cmd.UI.Render()
 	cmd.UI.Render()
-	go cmd.Matrix.SendPreferencesToMatrix()
+	go cmd.Gomuks.Matrix().SendPreferencesToMatrix()
+}
+
+func cmdNick(cmd *Command) {
+	if len(cmd.Args) == 0 {
+		cmd.Reply("Usage: /nick <name>")
+		return
+	}
+	name := strings.Join(cmd.Args, " ")
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetDisplayName(name); err != nil {
+			cmd.Reply("Failed to change displayname: %v", err)
+		} else {
+			cmd.Reply("Changed displayname to %s", name)
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdMyRoomNick(cmd *Command) {
+	if len(cmd.Args) == 0 {
+		cmd.Reply("Usage: /myroomnick <name>")
+		return
+	}
+	name := strings.Join(cmd.Args, " ")
+	roomID := cmd.Room.MxRoom().ID
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetRoomNick(roomID, name); err != nil {
+			cmd.Reply("Failed to change displayname in room: %v", err)
+		} else {
+			cmd.Reply("Changed displayname in this room to %s", name)
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdAvatar(cmd *Command) {
+	var roomID string
+	args := cmd.Args
+	if len(args) > 0 && args[0] == "--room" {
+		roomID = cmd.Room.MxRoom().ID
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		cmd.Reply("Usage: /avatar [--room] <path|mxc uri>")
+		return
+	}
+	pathOrMXC := strings.Join(args, " ")
+	go func() {
+		defer debug.Recover()
+		mxc, err := cmd.Matrix.SetAvatar(roomID, pathOrMXC)
+		if err != nil {
+			cmd.Reply("Failed to change avatar: %v", err)
+		} else if len(roomID) > 0 {
+			cmd.Reply("Changed avatar in this room to %s", mxc)
+		} else {
+			cmd.Reply("Changed avatar to %s", mxc)
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdPin(cmd *Command) {
+	var eventID string
+	if len(cmd.Args) == 1 {
+		eventID = cmd.Args[0]
+	} else if len(cmd.Args) > 1 {
+		cmd.Reply("Usage: /pin [event id]")
+		return
+	} else if eventID = cmd.Room.MessageView().lastEventID(); len(eventID) == 0 {
+		cmd.Reply("No message to pin")
+		return
+	}
+	roomID := cmd.Room.MxRoom().ID
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetPinned(roomID, eventID, true); err != nil {
+			cmd.Reply("Failed to pin message: %v", err)
+		} else {
+			cmd.Reply("Pinned %s", eventID)
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdUnpin(cmd *Command) {
+	if len(cmd.Args) != 1 {
+		cmd.Reply("Usage: /unpin <event id|number>")
+		return
+	}
+	eventID := cmd.Args[0]
+	pinned := cmd.Room.MxRoom().GetPinnedEvents()
+	if index, err := strconv.Atoi(eventID); err == nil {
+		if index < 1 || index > len(pinned) {
+			cmd.Reply("There are only %d pinned messages", len(pinned))
+			return
+		}
+		eventID = pinned[index-1]
+	}
+	roomID := cmd.Room.MxRoom().ID
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetPinned(roomID, eventID, false); err != nil {
+			cmd.Reply("Failed to unpin message: %v", err)
+		} else {
+			cmd.Reply("Unpinned %s", eventID)
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdPins(cmd *Command) {
+	cmd.Room.ShowPinnedMessages()
+}
+
+func cmdThread(cmd *Command) {
+	if len(cmd.Args) > 1 {
+		cmd.Reply("Usage: /thread [event id]")
+		return
+	}
+	rootID := cmd.Room.MessageView().lastEventID()
+	if len(cmd.Args) == 1 {
+		rootID = cmd.Args[0]
+	} else if len(rootID) == 0 {
+		cmd.Reply("No message to open the thread of")
+		return
+	}
+	cmd.Room.OpenThread(rootID)
+	cmd.UI.Render()
+}
+
+func cmdVote(cmd *Command) {
+	args := cmd.Args
+	pollID := ""
+	if len(args) > 0 && strings.HasPrefix(args[len(args)-1], "$") {
+		pollID = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	if len(args) == 0 {
+		cmd.Reply("Usage: /vote <answer number...> [poll event id]")
+		return
+	}
+	poll := cmd.Room.GetPoll(pollID)
+	if poll == nil {
+		cmd.Reply("Poll not found")
+		return
+	} else if poll.IsEnded() {
+		cmd.Reply("That poll has already ended")
+		return
+	} else if len(args) > poll.Poll.MaxSelections {
+		cmd.Reply("You can choose at most %d answers", poll.Poll.MaxSelections)
+		return
+	}
+	answerIDs := make([]string, len(args))
+	for i, arg := range args {
+		index, err := strconv.Atoi(arg)
+		if err != nil || index < 1 || index > len(poll.Poll.Answers) {
+			cmd.Reply("Answer number must be between 1 and %d", len(poll.Poll.Answers))
+			return
+		}
+		answerIDs[i] = poll.Poll.Answers[index-1].ID
+	}
+	go cmd.Room.VotePoll(poll, answerIDs)
+}
+
+func cmdEndPoll(cmd *Command) {
+	if len(cmd.Args) > 1 {
+		cmd.Reply("Usage: /endpoll [poll event id]")
+		return
+	}
+	pollID := ""
+	if len(cmd.Args) == 1 {
+		pollID = cmd.Args[0]
+	}
+	poll := cmd.Room.GetPoll(pollID)
+	if poll == nil {
+		cmd.Reply("Poll not found")
+		return
+	} else if !poll.CanEnd() {
+		cmd.Reply("Only the creator of a poll can end it, and only once")
+		return
+	}
+	go cmd.Room.EndPoll(poll)
+}
+
+func cmdNotify(cmd *Command) {
+	if len(cmd.Args) == 0 {
+		ruleset := cmd.Matrix.PushRules()
+		if ruleset == nil {
+			cmd.Reply("Push rules haven't been loaded")
+			return
+		}
+		cmd.Reply("Notification level of this room: %s", ruleset.GetRoomNotificationLevel(cmd.Room.Room.ID))
+		return
+	}
+	level := pushrules.RoomNotificationLevel(strings.ToLower(cmd.Args[0]))
+	switch level {
+	case pushrules.RoomNotifyAll, pushrules.RoomNotifyMentions, pushrules.RoomNotifyMute:
+	default:
+		cmd.Reply("Usage: /notify [all|mentions|mute]")
+		return
+	}
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetRoomNotificationLevel(cmd.Room.Room.ID, level); err != nil {
+			cmd.Reply("Failed to change notification level: %v", err)
+		} else {
+			cmd.Reply("Notification level of this room changed to %s", level)
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdKeyword(cmd *Command) {
+	if len(cmd.Args) == 0 || cmd.Args[0] == "list" {
+		ruleset := cmd.Matrix.PushRules()
+		if ruleset == nil {
+			cmd.Reply("Push rules haven't been loaded")
+			return
+		}
+		var keywords []string
+		for _, rule := range ruleset.Content {
+			if !rule.Default {
+				keywords = append(keywords, rule.Pattern)
+			}
+		}
+		if len(keywords) == 0 {
+			cmd.Reply("You don't have any notification keywords")
+		} else {
+			cmd.Reply("Notification keywords: %s", strings.Join(keywords, ", "))
+		}
+		return
+	} else if len(cmd.Args) < 2 || (cmd.Args[0] != "add" && cmd.Args[0] != "remove") {
+		cmd.Reply("Usage: /keyword <add|remove> <word>")
+		return
+	}
+	keyword := strings.Join(cmd.Args[1:], " ")
+	go func() {
+		defer debug.Recover()
+		if cmd.Args[0] == "add" {
+			if err := cmd.Matrix.AddKeyword(keyword); err != nil {
+				cmd.Reply("Failed to add keyword: %v", err)
+			} else {
+				cmd.Reply("Added notification keyword %s", keyword)
+			}
+		} else {
+			if err := cmd.Matrix.RemoveKeyword(keyword); err != nil {
+				cmd.Reply("Failed to remove keyword: %v", err)
+			} else {
+				cmd.Reply("Removed notification keyword %s", keyword)
+			}
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdPushRules(cmd *Command) {
+	cmd.MainView.ShowModal(NewPushRulesModal(cmd.MainView, cmd.Matrix))
+}
+
+func cmdDoNotDisturb(cmd *Command) {
+	enabled, until := cmd.MainView.DoNotDisturbStatus()
+	arg := ""
+	if len(cmd.Args) > 0 {
+		arg = strings.ToLower(cmd.Args[0])
+	}
+	switch arg {
+	case "":
+		enabled = !enabled
+		cmd.MainView.SetDoNotDisturb(enabled, 0)
+		until = time.Time{}
+	case "off":
+		enabled = false
+		cmd.MainView.SetDoNotDisturb(false, 0)
+	case "status":
+	default:
+		duration, err := time.ParseDuration(arg)
+		if err != nil || duration <= 0 {
+			cmd.Reply("Usage: /dnd [duration|off|status], e.g. /dnd 1h30m")
+			return
+		}
+		enabled = true
+		cmd.MainView.SetDoNotDisturb(true, duration)
+		_, until = cmd.MainView.DoNotDisturbStatus()
+	}
+	if !enabled {
+		cmd.Reply("Do not disturb is off")
+	} else if until.IsZero() {
+		cmd.Reply("Do not disturb is on until you turn it off with /dnd off")
+	} else {
+		cmd.Reply("Do not disturb is on until %s", until.Format("Jan _2 15:04"))
+	}
+	if cmd.Config.Notifications.IsQuietTime(time.Now()) {
+		cmd.Reply("Quiet hours are currently in effect")
+	}
+}
+
+func cmdCompose(cmd *Command) {
+	if len(cmd.Args) > 1 || (len(cmd.Args) == 1 && cmd.Args[0] != "send") {
+		cmd.Reply("Usage: /compose [send]")
+		return
+	}
+	cmd.Room.ComposeInEditor(len(cmd.Args) == 1)
+}
+
+func cmdCopy(cmd *Command) {
+	part := clipboard.PartText
+	eventID := cmd.Room.MessageView().lastEventID()
+	for _, arg := range cmd.Args {
+		switch arg {
+		case clipboard.PartText, clipboard.PartHTML, clipboard.PartID, clipboard.PartSource:
+			part = arg
+		default:
+			eventID = arg
+		}
+	}
+	if len(cmd.Args) > 2 || len(eventID) == 0 {
+		cmd.Reply("Usage: /copy [text|html|id|source] [event id]")
+		return
+	}
+	message, ok := cmd.Room.GetEvent(eventID).(messages.UIMessage)
+	if !ok {
+		cmd.Reply("Message %s not found", eventID)
+		return
+	}
+	cmd.UI.app.QueueUpdate(func() {
+		cmd.Reply("%s", cmd.MainView.CopyMessage(message, part))
+		cmd.UI.Render()
+	})
+}
+
+func cmdKeys(cmd *Command) {
+	if len(cmd.Args) > 0 && cmd.Args[0] == "reload" {
+		defer func() {
+			if err := recover(); err != nil {
+				cmd.Reply("Failed to reload key bindings: %v", err)
+			}
+		}()
+		cmd.Config.LoadKeybindings()
+		if len(cmd.Config.Keybindings.Conflicts) > 0 {
+			cmd.Reply("Reloaded key bindings with %d problems, see /keys", len(cmd.Config.Keybindings.Conflicts))
+		} else {
+			cmd.Reply("Reloaded key bindings")
+		}
+		return
+	} else if len(cmd.Args) > 0 {
+		cmd.Reply("Usage: /keys [reload]")
+		return
+	}
+	cmd.Reply("%s", keybindingHelp(cmd.Config.Keybindings))
+}
+
+func cmdWhois(cmd *Command) {
+	if len(cmd.Args) != 1 || !strings.HasPrefix(cmd.Args[0], "@") {
+		cmd.Reply("Usage: /whois <user id>")
+		return
+	}
+	cmd.UI.app.QueueUpdate(func() {
+		cmd.MainView.ShowModal(NewUserInfoModal(cmd.MainView, cmd.Room, cmd.Args[0]))
+		cmd.UI.Render()
+	})
+}
+
+func cmdIgnore(cmd *Command) {
+	if len(cmd.Args) == 0 {
+		ignored := cmd.Matrix.GetIgnoredUsers()
+		if len(ignored) == 0 {
+			cmd.Reply("You're not ignoring anyone")
+		} else {
+			cmd.Reply("Ignored users: %s", strings.Join(ignored, ", "))
+		}
+		return
+	} else if len(cmd.Args) != 1 || !strings.HasPrefix(cmd.Args[0], "@") {
+		cmd.Reply("Usage: /ignore [user id]")
+		return
+	} else if cmd.Args[0] == cmd.Matrix.Config().UserID {
+		cmd.Reply("You can't ignore yourself")
+		return
+	}
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetIgnored(cmd.Args[0], true); err != nil {
+			cmd.Reply("Failed to ignore user: %v", err)
+		} else {
+			cmd.Reply("Ignored %s", cmd.Args[0])
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdUnignore(cmd *Command) {
+	if len(cmd.Args) != 1 {
+		cmd.Reply("Usage: /unignore <user id>")
+		return
+	} else if !cmd.Matrix.IsIgnored(cmd.Args[0]) {
+		cmd.Reply("%s is not ignored", cmd.Args[0])
+		return
+	}
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.SetIgnored(cmd.Args[0], false); err != nil {
+			cmd.Reply("Failed to unignore user: %v", err)
+		} else {
+			cmd.Reply("Unignored %s", cmd.Args[0])
+		}
+		cmd.UI.Render()
+	}()
 }
 
 func cmdLogout(cmd *Command) {
-	cmd.Matrix.Logout()
+	if len(cmd.Args) > 0 && cmd.Args[0] == "all" {
+		cmd.Matrix.Logout(true)
+	} else if len(cmd.Args) > 0 {
+		cmd.Reply("Usage: /logout [all]")
+	} else {
+		cmd.Matrix.Logout(false)
+	}
+}
+
+func cmdDevices(cmd *Command) {
+	devices, err := cmd.Matrix.GetDevices()
+	if err != nil {
+		cmd.UI.app.QueueUpdate(func() {
+			cmd.Reply("Failed to get devices: %v", err)
+			cmd.UI.Render()
+		})
+		return
+	}
+	var buf strings.Builder
+	buf.WriteString("Your devices:")
+	for _, device := range devices {
+		fmt.Fprintf(&buf, "\n* %s", device.DeviceID)
+		if len(device.DisplayName) > 0 {
+			fmt.Fprintf(&buf, " (%s)", device.DisplayName)
+		}
+		if device.DeviceID == cmd.Matrix.Config().DeviceID {
+			buf.WriteString(" [current]")
+		}
+		if device.LastSeenTS > 0 {
+			lastSeen := time.Unix(device.LastSeenTS/1000, device.LastSeenTS%1000*int64(time.Millisecond))
+			fmt.Fprintf(&buf, " - last seen %s", lastSeen.Format("2006-01-02 15:04"))
+			if len(device.LastSeenIP) > 0 {
+				fmt.Fprintf(&buf, " from %s", device.LastSeenIP)
+			}
+		}
+	}
+	cmd.UI.app.QueueUpdate(func() {
+		cmd.Reply("%s", buf.String())
+		cmd.UI.Render()
+	})
+}
+
+func cmdRenameDevice(cmd *Command) {
+	if len(cmd.Args) < 2 {
+		cmd.Reply("Usage: /rename-device <device id> <name>")
+		return
+	}
+	name := strings.Join(cmd.Args[1:], " ")
+	err := cmd.Matrix.RenameDevice(cmd.Args[0], name)
+	cmd.UI.app.QueueUpdate(func() {
+		if err != nil {
+			cmd.Reply("Failed to rename device: %v", err)
+		} else {
+			cmd.Reply("Renamed device %s to %s", cmd.Args[0], name)
+		}
+		cmd.UI.Render()
+	})
+}
+
+func cmdDeleteDevice(cmd *Command) {
+	if len(cmd.Args) == 0 {
+		cmd.Reply("Usage: /delete-device <device id> [device id...]")
+		return
+	}
+	for _, deviceID := range cmd.Args {
+		if deviceID == cmd.Matrix.Config().DeviceID {
+			cmd.Reply("Can't delete the current device, use /logout instead")
+			return
+		}
+	}
+	go func() {
+		defer debug.Recover()
+		if err := cmd.Matrix.DeleteDevices(cmd.Args, cmd.UI); err != nil {
+			cmd.Reply("Failed to delete devices: %v", err)
+		} else {
+			cmd.Reply("Deleted %s", strings.Join(cmd.Args, ", "))
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdChangePassword(cmd *Command) {
+	go func() {
+		defer debug.Recover()
+		newPassword, ok := cmd.UI.Prompt("Change password", "Enter your new password", true)
+		if !ok {
+			return
+		}
+		confirm, ok := cmd.UI.Prompt("Change password", "Enter your new password again", true)
+		if !ok {
+			return
+		} else if newPassword != confirm {
+			cmd.Reply("Passwords don't match")
+			return
+		}
+		err := cmd.Matrix.ChangePassword(newPassword, cmd.UI)
+		if err != nil {
+			cmd.Reply("Failed to change password: %v", err)
+		} else {
+			cmd.Reply("Password changed successfully")
+		}
+		cmd.UI.Render()
+	}()
+}
+
+func cmdAccount(cmd *Command) {
+	if len(cmd.Args) == 0 {
+		cmd.Reply("Usage: /account <add/remove/list> [user id]")
+		return
+	}
+	switch strings.ToLower(cmd.Args[0]) {
+	case "add":
+		cmd.UI.loginView.SetAccount(cmd.Gomuks.AddAccount(), true)
+		cmd.UI.SetView(ViewLogin)
+	case "remove":
+		if len(cmd.Args) < 2 {
+			cmd.Reply("Usage: /account remove <user id>")
+			return
+		}
+		account := cmd.Gomuks.Account(cmd.Args[1])
+		if account == nil {
+			cmd.Reply("You're not logged in as %s", cmd.Args[1])
+			return
+		} else if account == cmd.Gomuks.Matrix() {
+			cmd.Reply("The main account can't be removed, use /logout instead")
+			return
+		}
+		go account.Logout(false)
+	case "list":
+		var buf strings.Builder
+		buf.WriteString("Logged in accounts:")
+		for i, account := range cmd.Gomuks.Accounts() {
+			fmt.Fprintf(&buf, "\n* %s", account.Config().UserID)
+			if i == 0 {
+				buf.WriteString(" (main)")
+			}
+		}
+		cmd.Reply(buf.String())
+	default:
+		cmd.Reply("Usage: /account <add/remove/list> [user id]")
+	}
 }
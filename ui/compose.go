@@ -0,0 +1,100 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/matrix/pills"
+)
+
+// editorCommand returns the command of the user's preferred editor from $VISUAL or $EDITOR.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(env)); len(editor) > 0 {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editInEditor suspends the UI, lets the user edit the given text in their editor and returns the result.
+// It must be called from the UI goroutine, which is blocked while the editor is open.
+func (ui *GomuksUI) editInEditor(text string) (string, error) {
+	file, err := ioutil.TempFile("", "gomuks-compose-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(text)
+	file.Close()
+	if err != nil {
+		return "", err
+	}
+
+	editor := append(editorCommand(), file.Name())
+	var editErr error
+	suspended := ui.app.Suspend(func() {
+		cmd := exec.Command(editor[0], editor[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		editErr = cmd.Run()
+	})
+	if !suspended {
+		return "", fmt.Errorf("the terminal is not available")
+	} else if editErr != nil {
+		return "", fmt.Errorf("%s failed: %v", editor[0], editErr)
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// ComposeInEditor opens the text in the input in the user's editor. When the editor exits, the result
+// is sent if send is true and it isn't empty, or otherwise put back into the input.
+// Mention pills are turned into markdown links, which are sent as mentions just like the pills.
+func (view *RoomView) ComposeInEditor(send bool) {
+	ui := view.parent.parent
+	ui.app.QueueUpdate(func() {
+		defer debug.Recover()
+		text, err := ui.editInEditor(pills.ToMarkdown(view.input.GetText(), view.mentions))
+		if err != nil {
+			view.AddServiceMessage(fmt.Sprintf("Failed to compose message: %v", err))
+			ui.Render()
+			return
+		}
+		view.mentions = nil
+		view.SetInputText(text)
+		if send && len(strings.TrimSpace(text)) > 0 {
+			view.InputSubmit(text)
+		}
+		ui.Render()
+	})
+}
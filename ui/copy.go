@@ -0,0 +1,53 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kennetanti/gomuks/lib/clipboard"
+	"github.com/kennetanti/gomuks/ui/messages"
+)
+
+// messageCopyText returns the given part of the given message. The part is one of the clipboard.Part* constants.
+func messageCopyText(message messages.UIMessage, part string) (string, error) {
+	msg := clipboard.Message{EventID: message.ID()}
+	msg.Body, msg.FormattedBody = messageContent(message)
+	if source, ok := message.(interface{ Source() json.RawMessage }); ok {
+		msg.Source = source.Source()
+	}
+	return msg.Part(part)
+}
+
+// CopyMessage copies the given part of the given message to the clipboard and returns a description of the result.
+// It must be called from the UI goroutine, because OSC 52 sequences are written to the terminal.
+func (view *MainView) CopyMessage(message messages.UIMessage, part string) string {
+	text, err := messageCopyText(message, part)
+	if err != nil {
+		return fmt.Sprintf("Failed to copy %s: %v", part, err)
+	}
+	cb := clipboard.Clipboard{
+		Method:  view.config.Clipboard.Method,
+		Command: view.config.Clipboard.Command,
+	}
+	method, err := cb.Copy(text)
+	if err != nil {
+		return fmt.Sprintf("Failed to copy %s: %v", part, err)
+	}
+	return fmt.Sprintf("Copied %s with %s", part, method)
+}
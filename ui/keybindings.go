@@ -0,0 +1,165 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tulir/mautrix-go"
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/config"
+)
+
+func eventChord(event mauview.KeyEvent) config.Chord {
+	return config.NewChord(event.Key(), event.Rune(), event.Modifiers())
+}
+
+// runKeyAction runs a main view action from keybindings.yaml. It returns false if the action wasn't handled.
+func (view *MainView) runKeyAction(action string, event mauview.KeyEvent) bool {
+	switch action {
+	case "next_room":
+		view.SwitchRoom(view.roomList.Next())
+	case "prev_room":
+		view.SwitchRoom(view.roomList.Previous())
+	case "next_active_room":
+		view.SwitchRoom(view.roomList.NextWithActivity())
+	case "search_rooms":
+		view.ShowModal(NewFuzzySearchModal(view, 42, 12))
+	case "scroll_top":
+		msgView := view.currentRoom.MessageView()
+		msgView.AddScrollOffset(msgView.TotalHeight())
+	case "scroll_bottom":
+		msgView := view.currentRoom.MessageView()
+		msgView.AddScrollOffset(-msgView.TotalHeight())
+	case "newline":
+		return view.flex.OnKeyEvent(tcell.NewEventKey(tcell.KeyEnter, '\n', event.Modifiers()|tcell.ModShift))
+	case "bare_mode":
+		view.ShowBare(view.currentRoom)
+	case "toggle_room_list":
+		view.config.Preferences.HideRoomList = !view.config.Preferences.HideRoomList
+		go view.matrix.SendPreferencesToMatrix()
+	case "toggle_user_list":
+		view.config.Preferences.HideUserList = !view.config.Preferences.HideUserList
+		go view.matrix.SendPreferencesToMatrix()
+	case "show_keys":
+		view.currentRoom.AddServiceMessage(keybindingHelp(view.config.Keybindings))
+	default:
+		return false
+	}
+	return true
+}
+
+// runKeyAction runs a room view action from keybindings.yaml. It returns false if the action wasn't handled.
+func (view *RoomView) runKeyAction(action string) bool {
+	msgView := view.MessageView()
+	switch action {
+	case "scroll_up":
+		if msgView.IsAtTop() {
+			go view.parent.LoadHistory(view.Room.ID)
+		}
+		msgView.AddScrollOffset(+msgView.Height() / 2)
+	case "scroll_down":
+		msgView.AddScrollOffset(-msgView.Height() / 2)
+	case "send":
+		view.InputSubmit(view.input.GetText())
+	case "compose":
+		view.ComposeInEditor(false)
+	case "compose_send":
+		view.ComposeInEditor(true)
+	case "show_pins":
+		view.ShowPinnedMessages()
+	case "open_thread":
+		rootID := msgView.lastEventID()
+		if len(rootID) == 0 {
+			return false
+		}
+		view.OpenThread(rootID)
+	case "close_thread":
+		if view.thread == nil {
+			return false
+		}
+		view.CloseThread()
+	case "normal_mode":
+		if !view.config.Preferences.VimMode && view.replyTo == nil && view.editing == nil {
+			return false
+		} else if !view.config.Preferences.VimMode {
+			// Without vim mode, the key still cancels replies and edits.
+			if view.editing != nil {
+				view.SetInputText("")
+			}
+			view.replyTo = nil
+			view.editing = nil
+		} else if !view.normal.active {
+			view.EnterNormalMode()
+		} else {
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// runKeyAction runs a room view action from keybindings.yaml in the thread pane.
+// Actions that don't make sense in a thread are left to the input area.
+func (view *ThreadView) runKeyAction(action string) bool {
+	switch action {
+	case "scroll_up":
+		view.content.AddScrollOffset(+view.content.Height() / 2)
+	case "scroll_down":
+		view.content.AddScrollOffset(-view.content.Height() / 2)
+	case "send":
+		if text := view.input.GetText(); len(text) > 0 {
+			go view.parent.SendThreadMessage(view.rootID, mautrix.MsgText, text)
+			view.input.SetTextAndMoveCursor("")
+		}
+	case "close_thread", "normal_mode":
+		view.parent.CloseThread()
+	default:
+		return false
+	}
+	return true
+}
+
+// keybindingHelp lists every action with the chords bound to it, followed by any problems found in keybindings.yaml.
+func keybindingHelp(kb *config.Keybindings) string {
+	var buf strings.Builder
+	buf.WriteString("Key bindings (edit keybindings.yaml in the config directory to change them):\n")
+	for _, context := range []string{config.KeyContextMain, config.KeyContextRoom} {
+		fmt.Fprintf(&buf, "\n%s view:\n", strings.Title(context))
+		for _, action := range config.KeyActions {
+			if action.Context != context {
+				continue
+			}
+			chords := strings.Join(kb.Chords(action.Name), ", ")
+			if len(chords) == 0 {
+				chords = "(unbound)"
+			}
+			fmt.Fprintf(&buf, "  %-18s %-24s %s\n", action.Name, chords, action.Description)
+		}
+	}
+	if len(kb.Conflicts) > 0 {
+		buf.WriteString("\nProblems in keybindings.yaml:\n")
+		for _, conflict := range kb.Conflicts {
+			fmt.Fprintf(&buf, "  %s\n", conflict)
+		}
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
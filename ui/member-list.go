@@ -26,15 +26,17 @@ import (
 	"github.com/tulir/mauview"
 	"github.com/tulir/tcell"
 
+	"github.com/kennetanti/gomuks/interface"
 	"github.com/kennetanti/gomuks/ui/widget"
 )
 
 type MemberList struct {
-	list roomMemberList
+	list   roomMemberList
+	parent *RoomView
 }
 
-func NewMemberList() *MemberList {
-	return &MemberList{}
+func NewMemberList(parent *RoomView) *MemberList {
+	return &MemberList{parent: parent}
 }
 
 type memberListItem struct {
@@ -79,17 +81,43 @@ func (ml *MemberList) Update(data map[string]*mautrix.Member, levels *mautrix.Po
 
 func (ml *MemberList) Draw(screen mauview.Screen) {
 	width, _ := screen.Size()
+	presences := make([]*ifc.Presence, len(ml.list))
+	hasPresence := false
+	if ml.parent != nil && ml.parent.matrix != nil {
+		for i, member := range ml.list {
+			presences[i] = ml.parent.matrix.GetPresence(member.UserID)
+			hasPresence = hasPresence || presences[i] != nil
+		}
+	}
+	x := 0
+	if hasPresence {
+		// Only reserve space for presence dots if the server sends presence.
+		x = 2
+	}
 	for y, member := range ml.list {
+		if presences[y] != nil {
+			screen.SetCell(0, y, tcell.StyleDefault.Foreground(PresenceColor(presences[y])), PresenceDot)
+		}
 		if member.Membership == "invite" {
-			widget.WriteLineSimpleColor(screen, member.Displayname, 1, y, member.Color)
-			screen.SetCell(0, y, tcell.StyleDefault, '(')
-			if sw := runewidth.StringWidth(member.Displayname); sw < width-1 {
-				screen.SetCell(sw+1, y, tcell.StyleDefault, ')')
+			widget.WriteLineSimpleColor(screen, member.Displayname, x+1, y, member.Color)
+			screen.SetCell(x, y, tcell.StyleDefault, '(')
+			if sw := runewidth.StringWidth(member.Displayname); sw < width-x-1 {
+				screen.SetCell(x+sw+1, y, tcell.StyleDefault, ')')
 			} else {
 				screen.SetCell(width-1, y, tcell.StyleDefault, ')')
 			}
 		} else {
-			widget.WriteLineSimpleColor(screen, member.Displayname, 0, y, member.Color)
+			widget.WriteLineSimpleColor(screen, member.Displayname, x, y, member.Color)
+		}
+		if ml.parent != nil && ml.parent.IsTyping(member.UserID) {
+			markerX := x + runewidth.StringWidth(member.Displayname) + 1
+			if member.Membership == "invite" {
+				markerX += 2
+			}
+			if markerX >= width {
+				markerX = width - 1
+			}
+			screen.SetCell(markerX, y, tcell.StyleDefault.Foreground(member.Color), TypingMarker)
 		}
 	}
 }
@@ -34,6 +34,9 @@ import (
 	"github.com/kennetanti/gomuks/ui/widget"
 )
 
+// ReadReceiptWidth is the width of the column on the right edge of the message view where read receipts are drawn.
+const ReadReceiptWidth = 4
+
 type MessageView struct {
 	parent *RoomView
 	config *config.Config
@@ -57,6 +60,27 @@ type MessageView struct {
 	messages   []messages.UIMessage
 
 	msgBuffer []messages.UIMessage
+
+	// The event ID after which the "new messages" divider should be shown, and the divider itself.
+	// The divider is only added to the buffer, so it's not one of the messages.
+	fullyReadEventID string
+	fullyReadDivider messages.UIMessage
+
+	// Thread replies by the ID of their root event. Replies are only shown in the main timeline
+	// until their root is loaded, so that they don't disappear completely.
+	threads map[string][]messages.UIMessage
+	// Whether or not this view shows a single thread, in which case replies aren't collapsed.
+	threadMode bool
+
+	// Poll responses and ends by the ID of the poll. They're kept in case the poll itself is loaded later.
+	pollUpdates map[string][]*messages.PollUpdateMessage
+	// The latest edit of messages that aren't loaded yet by the ID of the edited message.
+	pendingEdits map[string]messages.UIMessage
+
+	// The message selected in normal mode, or nil if nothing is selected.
+	selected messages.UIMessage
+	// Whether or not the next draw should scroll so that the selected message is visible.
+	scrollToSelected bool
 }
 
 func NewMessageView(parent *RoomView) *MessageView {
@@ -71,6 +95,10 @@ func NewMessageView(parent *RoomView) *MessageView {
 		messages:   make([]messages.UIMessage, 0),
 		messageIDs: make(map[string]messages.UIMessage),
 		msgBuffer:  make([]messages.UIMessage, 0),
+		threads:    make(map[string][]messages.UIMessage),
+
+		pollUpdates:  make(map[string][]*messages.PollUpdateMessage),
+		pendingEdits: make(map[string]messages.UIMessage),
 
 		width:        80,
 		widestSender: 5,
@@ -108,6 +136,41 @@ func (view *MessageView) AddMessage(ifcMessage ifc.Message, direction MessageDir
 		return
 	}
 
+	if update, ok := message.(*messages.PollUpdateMessage); ok {
+		view.addPollUpdate(update)
+		return
+	} else if poll, ok := message.(*messages.PollMessage); ok {
+		for _, update := range view.pollUpdates[poll.ID()] {
+			poll.ApplyUpdate(update)
+		}
+	}
+
+	rootID := message.ThreadRoot()
+	if target := message.EditTarget(); len(target) > 0 {
+		// Edits have the ID of the edited message, so they replace it below.
+		original, originalRootID := view.findMessage(target)
+		if original == nil {
+			view.addPendingEdit(target, message)
+			return
+		} else if original.SenderID() != message.SenderID() {
+			debug.Print("Ignoring edit of", target, "by", message.SenderID(), "who didn't send the message")
+			return
+		}
+		rootID = originalRootID
+	} else if edit, ok := view.pendingEdits[message.ID()]; ok {
+		delete(view.pendingEdits, message.ID())
+		if edit.SenderID() == message.SenderID() {
+			message = edit
+		}
+	}
+
+	if len(rootID) > 0 && !view.threadMode {
+		view.addThreadReply(rootID, message, direction)
+		if _, rootLoaded := view.messageIDs[rootID]; rootLoaded {
+			return
+		}
+	}
+
 	var oldMsg messages.UIMessage
 	var messageExists bool
 	if oldMsg, messageExists = view.messageIDs[message.ID()]; messageExists {
@@ -121,11 +184,12 @@ func (view *MessageView) AddMessage(ifcMessage ifc.Message, direction MessageDir
 
 	view.updateWidestSender(message.Sender())
 
-	width := view.width
-	bare := view.config.Preferences.BareMessageView
-	if !bare {
-		width -= view.TimestampWidth + TimestampSenderGap + view.widestSender + SenderMessageGap
+	if replies := view.threads[message.ID()]; len(replies) > 0 && !view.threadMode {
+		message.SetThreadSummary(len(replies), replies[len(replies)-1])
+		view.removeFromTimeline(replies)
 	}
+
+	width := view.messageWidth(view.config.Preferences)
 	message.CalculateBuffer(view.config.Preferences, width)
 
 	makeDateChange := func() messages.UIMessage {
@@ -137,7 +201,7 @@ func (view *MessageView) AddMessage(ifcMessage ifc.Message, direction MessageDir
 	}
 
 	if direction == AppendMessage {
-		if view.ScrollOffset > 0 {
+		if view.ScrollOffset > 0 && !view.isIgnored(message) {
 			view.ScrollOffset += message.Height()
 		}
 		if len(view.messages) > 0 && !view.messages[len(view.messages)-1].SameDate(message) {
@@ -164,11 +228,170 @@ func (view *MessageView) AddMessage(ifcMessage ifc.Message, direction MessageDir
 	}
 }
 
-func (view *MessageView) appendBuffer(message messages.UIMessage) {
-	for i := 0; i < message.Height(); i++ {
-		view.msgBuffer = append(view.msgBuffer, message)
+// addThreadReply stores a thread reply and updates the reply summary of the thread root.
+func (view *MessageView) addThreadReply(rootID string, message messages.UIMessage, direction MessageDirection) {
+	replies := view.threads[rootID]
+	replaced := false
+	for index, reply := range replies {
+		if (len(message.ID()) > 0 && reply.ID() == message.ID()) ||
+			(len(message.TxnID()) > 0 && reply.TxnID() == message.TxnID()) {
+			replies[index] = message
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		if direction == PrependMessage {
+			replies = append([]messages.UIMessage{message}, replies...)
+		} else {
+			replies = append(replies, message)
+		}
+	}
+	view.threads[rootID] = replies
+
+	if root, ok := view.messageIDs[rootID]; ok {
+		root.SetThreadSummary(len(replies), replies[len(replies)-1])
+		// Force recalculateBuffers() to rebuild the buffer, as the height of the root changed.
+		view.prevMsgCount = -1
+	}
+}
+
+// removeFromTimeline removes thread replies that were shown in the main timeline while their root wasn't loaded.
+func (view *MessageView) removeFromTimeline(replies []messages.UIMessage) {
+	removed := make(map[messages.UIMessage]bool)
+	for _, reply := range replies {
+		if shown, ok := view.messageIDs[reply.ID()]; ok && shown == reply {
+			delete(view.messageIDs, reply.ID())
+			removed[reply] = true
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	kept := view.messages[:0]
+	for _, msg := range view.messages {
+		if !removed[msg] {
+			kept = append(kept, msg)
+		}
+	}
+	view.messages = kept
+	if removed[view.selected] {
+		view.selected = nil
+	}
+	// Force recalculateBuffers() to rebuild the buffer without the removed replies.
+	view.prevMsgCount = -1
+}
+
+// findMessage returns the message with the given event ID. If the message is a thread reply,
+// the ID of the thread root is also returned.
+func (view *MessageView) findMessage(eventID string) (messages.UIMessage, string) {
+	for rootID, replies := range view.threads {
+		for _, reply := range replies {
+			if reply.ID() == eventID {
+				return reply, rootID
+			}
+		}
+	}
+	if message, ok := view.messageIDs[eventID]; ok {
+		return message, ""
+	}
+	return nil, ""
+}
+
+// addPendingEdit stores an edit of a message that isn't loaded yet. Only the latest edit is kept.
+func (view *MessageView) addPendingEdit(target string, edit messages.UIMessage) {
+	if existing, ok := view.pendingEdits[target]; ok && existing.Timestamp().After(edit.Timestamp()) {
+		return
+	}
+	view.pendingEdits[target] = edit
+}
+
+// addPollUpdate stores a poll response or end and applies it to the poll if the poll is loaded.
+func (view *MessageView) addPollUpdate(update *messages.PollUpdateMessage) {
+	view.pollUpdates[update.PollID] = append(view.pollUpdates[update.PollID], update)
+	if poll, ok := view.messageIDs[update.PollID].(*messages.PollMessage); ok {
+		poll.ApplyUpdate(update)
+		poll.CalculateBuffer(view.config.Preferences, view.messageWidth(view.config.Preferences))
+		// Force recalculateBuffers() to rebuild the buffer, as the height of the poll may have changed.
+		view.prevMsgCount = -1
+	}
+}
+
+// latestPoll returns the most recent poll in the view, or nil if there are no polls.
+func (view *MessageView) latestPoll() *messages.PollMessage {
+	for i := len(view.messages) - 1; i >= 0; i-- {
+		if poll, ok := view.messages[i].(*messages.PollMessage); ok {
+			return poll
+		}
+	}
+	return nil
+}
+
+// ThreadReplies returns the loaded replies in the thread of the given root event.
+func (view *MessageView) ThreadReplies(rootID string) []messages.UIMessage {
+	return view.threads[rootID]
+}
+
+// SetFullyReadMarker moves the "new messages" divider after the message with the given event ID.
+// If the message is the last message in the view, the divider is removed.
+func (view *MessageView) SetFullyReadMarker(eventID string) {
+	view.fullyReadEventID = eventID
+	if view.fullyReadDivider != nil {
+		view.fullyReadDivider = nil
+		// Force recalculateBuffers() to rebuild the buffer.
+		view.prevMsgCount = -1
+	}
+	view.placeFullyReadDivider()
+}
+
+// placeFullyReadDivider shows the "new messages" divider if the fully read message is loaded and isn't the
+// last message. It's called again after loading history in case the message wasn't loaded before.
+func (view *MessageView) placeFullyReadDivider() {
+	if view.fullyReadDivider != nil || len(view.fullyReadEventID) == 0 {
+		return
+	}
+	for index, msg := range view.messages {
+		if msg.ID() != view.fullyReadEventID {
+			continue
+		} else if index == len(view.messages)-1 {
+			break
+		}
+		divider := messages.NewFullyReadDividerMessage(msg.Timestamp())
+		divider.CalculateBuffer(view.config.Preferences, view.messageWidth(view.config.Preferences))
+		view.fullyReadDivider = divider
+		view.prevMsgCount = -1
+		break
 	}
+}
+
+// lastEventID returns the ID of the latest message sent by a user, or an empty string if there are no messages.
+func (view *MessageView) lastEventID() string {
+	for i := len(view.messages) - 1; i >= 0; i-- {
+		message := view.messages[i]
+		if strings.HasPrefix(message.ID(), "$") && strings.HasPrefix(message.SenderID(), "@") {
+			return message.ID()
+		}
+	}
+	return ""
+}
+
+// isIgnored returns whether or not the message should be hidden because the sender is ignored.
+func (view *MessageView) isIgnored(message messages.UIMessage) bool {
+	return view.parent.matrix.IsIgnored(message.SenderID())
+}
+
+func (view *MessageView) appendBuffer(message messages.UIMessage) {
 	view.prevMsgCount++
+	if !view.isIgnored(message) {
+		for i := 0; i < message.Height(); i++ {
+			view.msgBuffer = append(view.msgBuffer, message)
+		}
+	}
+	if view.fullyReadDivider != nil && message.ID() == view.fullyReadEventID {
+		for i := 0; i < view.fullyReadDivider.Height(); i++ {
+			view.msgBuffer = append(view.msgBuffer, view.fullyReadDivider)
+		}
+	}
 }
 
 func (view *MessageView) replaceMessage(original messages.UIMessage, new messages.UIMessage) {
@@ -180,9 +403,15 @@ func (view *MessageView) replaceMessage(original messages.UIMessage, new message
 			view.messages[index] = new
 		}
 	}
+	if view.selected == original {
+		view.selected = new
+	}
 }
 
 func (view *MessageView) replaceBuffer(original messages.UIMessage, new messages.UIMessage) {
+	if view.isIgnored(new) {
+		return
+	}
 	start := -1
 	end := -1
 	for index, meta := range view.msgBuffer {
@@ -224,18 +453,64 @@ func (view *MessageView) replaceBuffer(original messages.UIMessage, new messages
 	}
 }
 
+// showReadReceipts returns whether or not read receipts are drawn next to messages with the given preferences.
+func showReadReceipts(prefs config.UserPreferences) bool {
+	return !prefs.BareMessageView && !prefs.HideReadReceipts
+}
+
+// messageWidth returns the width available for message content with the given preferences.
+func (view *MessageView) messageWidth(prefs config.UserPreferences) int {
+	width := view.width
+	if !prefs.BareMessageView {
+		width -= view.TimestampWidth + TimestampSenderGap + view.widestSender + SenderMessageGap
+	}
+	if showReadReceipts(prefs) {
+		width -= ReadReceiptWidth
+	}
+	return width
+}
+
+// drawReadReceipts draws the initials of the members whose latest read receipt is the given message
+// at the right edge of the given line.
+func (view *MessageView) drawReadReceipts(screen mauview.Screen, msg messages.UIMessage, line int) {
+	room := view.parent.Room
+	var readers []string
+	for _, userID := range room.GetReadReceipts(msg.ID()) {
+		if userID != room.SessionUserID {
+			readers = append(readers, userID)
+		}
+	}
+	x := view.width - ReadReceiptWidth + 1
+	for i, userID := range readers {
+		if i == ReadReceiptWidth-2 && len(readers) > ReadReceiptWidth-1 {
+			screen.SetContent(x+i, line, '+', nil, tcell.StyleDefault.Foreground(tcell.ColorGray))
+			break
+		}
+		name := userID
+		if member := room.GetMember(userID); member != nil {
+			name = member.Displayname
+		}
+		initial := []rune(strings.TrimPrefix(name, "@"))
+		if len(initial) == 0 {
+			continue
+		}
+		screen.SetContent(x+i, line, initial[0], nil, tcell.StyleDefault.Foreground(widget.GetHashColor(userID)))
+	}
+}
+
 func (view *MessageView) recalculateBuffers() {
 	prefs := view.config.Preferences
 	recalculateMessageBuffers := view.width != view.prevWidth ||
 		view.prevPrefs.BareMessageView != prefs.BareMessageView ||
-		view.prevPrefs.DisableImages != prefs.DisableImages
+		view.prevPrefs.DisableImages != prefs.DisableImages ||
+		view.prevPrefs.HideReadReceipts != prefs.HideReadReceipts
 	if recalculateMessageBuffers || len(view.messages) != view.prevMsgCount {
-		width := view.width
-		if !prefs.BareMessageView {
-			width -= view.TimestampWidth + TimestampSenderGap + view.widestSender + SenderMessageGap
-		}
+		width := view.messageWidth(prefs)
 		view.msgBuffer = []messages.UIMessage{}
 		view.prevMsgCount = 0
+		if recalculateMessageBuffers && view.fullyReadDivider != nil {
+			view.fullyReadDivider.CalculateBuffer(prefs, width)
+		}
 		for i, message := range view.messages {
 			if message == nil {
 				debug.Print("O.o found nil message at", i)
@@ -252,17 +527,33 @@ func (view *MessageView) recalculateBuffers() {
 	view.prevPrefs = prefs
 }
 
-func (view *MessageView) handleMessageClick(message messages.UIMessage) bool {
+// handleMessageClick handles a click on the given row of the given message.
+func (view *MessageView) handleMessageClick(message messages.UIMessage, row int) bool {
+	if message.ThreadReplyCount() > 0 && !view.threadMode {
+		view.parent.OpenThread(message.ID())
+		return true
+	}
 	switch message := message.(type) {
 	case *messages.ImageMessage:
 		open.Open(message.Path())
+	case *messages.PollMessage:
+		row -= message.ReplyHeight()
+		if answerID, ok := message.AnswerAt(row); ok && !message.IsEnded() {
+			go view.parent.VotePoll(message, message.ToggleVote(answerID))
+			return true
+		} else if message.IsEndButtonAt(row) {
+			go view.parent.EndPoll(message)
+			return true
+		}
 	case messages.UIMessage:
 		debug.Print("Message clicked:", message)
 	}
 	return false
 }
 
-func (view *MessageView) handleUsernameClick(message messages.UIMessage, prevMessage messages.UIMessage) bool {
+// handleUsernameClick inserts a mention of the sender of the clicked message into the input field,
+// or opens the user info modal of the sender if a modifier key was held.
+func (view *MessageView) handleUsernameClick(message messages.UIMessage, prevMessage messages.UIMessage, modifiers tcell.ModMask) bool {
 	if prevMessage != nil && prevMessage.Sender() == message.Sender() {
 		return false
 	}
@@ -270,6 +561,14 @@ func (view *MessageView) handleUsernameClick(message messages.UIMessage, prevMes
 	if len(message.Sender()) == 0 {
 		return false
 	}
+
+	if modifiers != tcell.ModNone {
+		if !strings.HasPrefix(message.SenderID(), "@") {
+			return false
+		}
+		view.parent.parent.ShowModal(NewUserInfoModal(view.parent.parent, view.parent, message.SenderID()))
+		return true
+	}
 	sender := fmt.Sprintf("[%s](https://matrix.to/#/%s)", message.Sender(), message.SenderID())
 
 	cursorPos := view.parent.input.GetCursorOffset()
@@ -297,7 +596,7 @@ func (view *MessageView) handleUsernameClick(message messages.UIMessage, prevMes
 func (view *MessageView) OnMouseEvent(event mauview.MouseEvent) bool {
 	switch event.Buttons() {
 	case tcell.WheelUp:
-		if view.IsAtTop() {
+		if view.IsAtTop() && !view.threadMode {
 			go view.parent.parent.LoadHistory(view.parent.Room.ID)
 		} else {
 			view.AddScrollOffset(WheelScrollOffsetDiff)
@@ -324,9 +623,13 @@ func (view *MessageView) OnMouseEvent(event mauview.MouseEvent) bool {
 		messageX := usernameX + view.widestSender + SenderMessageGap
 
 		if x >= messageX {
-			return view.handleMessageClick(message)
+			row := 0
+			for i := line - 1; i >= 0 && view.msgBuffer[i] == message; i-- {
+				row++
+			}
+			return view.handleMessageClick(message, row)
 		} else if x >= usernameX {
-			return view.handleUsernameClick(message, prevMessage)
+			return view.handleUsernameClick(message, prevMessage, event.Modifiers())
 		}
 	}
 	return false
@@ -362,6 +665,126 @@ func (view *MessageView) IsAtTop() bool {
 	return view.ScrollOffset >= len(view.msgBuffer)-view.height+PaddingAtTop
 }
 
+// isSelectable returns whether or not the given message can be selected in normal mode.
+// Service messages like date changes don't have IDs and are skipped.
+func (view *MessageView) isSelectable(message messages.UIMessage) bool {
+	return len(message.ID()) > 0 && !view.isIgnored(message)
+}
+
+// Selected returns the message selected in normal mode, or nil if nothing is selected.
+func (view *MessageView) Selected() messages.UIMessage {
+	return view.selected
+}
+
+// Select selects the given message and scrolls to it on the next draw.
+func (view *MessageView) Select(message messages.UIMessage) {
+	view.selected = message
+	view.scrollToSelected = message != nil
+}
+
+func (view *MessageView) selectedIndex() int {
+	for index, message := range view.messages {
+		if message == view.selected {
+			return index
+		}
+	}
+	return -1
+}
+
+// MoveSelection selects the diff'th selectable message after the selected message, or before it
+// if diff is negative. If nothing is selected, the search starts after the newest message.
+// It returns false if there are no more messages in that direction.
+func (view *MessageView) MoveSelection(diff int) bool {
+	index := view.selectedIndex()
+	if index == -1 {
+		index = len(view.messages)
+	}
+	step := 1
+	if diff < 0 {
+		step = -1
+		diff = -diff
+	}
+	found := false
+	for i := index + step; i >= 0 && i < len(view.messages) && diff > 0; i += step {
+		if view.isSelectable(view.messages[i]) {
+			view.Select(view.messages[i])
+			found = true
+			diff--
+		}
+	}
+	return found
+}
+
+// SelectOldest selects the oldest loaded message.
+func (view *MessageView) SelectOldest() {
+	for _, message := range view.messages {
+		if view.isSelectable(message) {
+			view.Select(message)
+			return
+		}
+	}
+}
+
+// SelectNewest selects the newest message.
+func (view *MessageView) SelectNewest() {
+	view.selected = nil
+	view.MoveSelection(-1)
+}
+
+// SearchSelect selects the next loaded message containing the given text, case-insensitively.
+// The search goes towards older messages and wraps around, or towards newer messages if backwards is true.
+func (view *MessageView) SearchSelect(query string, backwards bool) bool {
+	query = strings.ToLower(query)
+	count := len(view.messages)
+	if len(query) == 0 || count == 0 {
+		return false
+	}
+	start := view.selectedIndex()
+	if start == -1 {
+		start = count
+	}
+	step := -1
+	if backwards {
+		step = 1
+	}
+	for n := 1; n <= count; n++ {
+		message := view.messages[((start+step*n)%count+count)%count]
+		if view.isSelectable(message) && strings.Contains(strings.ToLower(message.PlainText()), query) {
+			view.Select(message)
+			return true
+		}
+	}
+	return false
+}
+
+// scrollToSelection changes the scroll offset so that the whole selected message is visible, if it fits.
+func (view *MessageView) scrollToSelection() {
+	view.scrollToSelected = false
+	start := -1
+	end := -1
+	for index, message := range view.msgBuffer {
+		if message == view.selected {
+			if start == -1 {
+				start = index
+			}
+			end = index
+		}
+	}
+	if start == -1 {
+		return
+	}
+	top := view.TotalHeight() - view.ScrollOffset - view.height
+	if end >= top+view.height {
+		view.ScrollOffset = view.TotalHeight() - end - 1
+	}
+	if start < view.TotalHeight()-view.ScrollOffset-view.height {
+		view.ScrollOffset = view.TotalHeight() - start - view.height
+	}
+	if view.ScrollOffset < 0 {
+		view.ScrollOffset = 0
+	}
+}
+
 const (
 	TimestampSenderGap = 1
 	SenderSeparatorGap = 1
@@ -444,6 +867,9 @@ func (view *MessageView) CapturePlaintext(height int) string {
 func (view *MessageView) Draw(screen mauview.Screen) {
 	view.width, view.height = screen.Size()
 	view.recalculateBuffers()
+	if view.scrollToSelected {
+		view.scrollToSelection()
+	}
 
 	if view.TotalHeight() == 0 {
 		widget.WriteLineSimple(screen, "It's quite empty in here.", 0, view.height)
@@ -485,16 +911,22 @@ func (view *MessageView) Draw(screen mauview.Screen) {
 		index := indexOffset + line
 
 		msg := view.msgBuffer[index]
+		selected := msg == view.selected && !bareMode
+		if selected {
+			// The timestamp and sender columns of the selected message are drawn in reverse.
+			widget.WriteLinePadded(screen, mauview.AlignLeft, "", 0, line, usernameX+view.widestSender, tcell.StyleDefault.Reverse(true))
+		}
 		if msg != prevMsg {
 			if len(msg.FormatTime()) > 0 {
-				widget.WriteLineSimpleColor(screen, msg.FormatTime(), 0, line, msg.TimestampColor())
+				widget.WriteLine(screen, mauview.AlignLeft, msg.FormatTime(), 0, line, view.TimestampWidth,
+					tcell.StyleDefault.Foreground(msg.TimestampColor()).Reverse(selected))
 			}
 			// TODO hiding senders might not be that nice after all, maybe an option? (disabled for now)
 			//if !bareMode && (prevMsg == nil || meta.Sender() != prevMsg.Sender()) {
-			widget.WriteLineColor(
+			widget.WriteLine(
 				screen, mauview.AlignRight, msg.Sender(),
 				usernameX, line, view.widestSender,
-				msg.SenderColor())
+				tcell.StyleDefault.Foreground(msg.SenderColor()).Reverse(selected))
 			//}
 			prevMsg = msg
 		}
@@ -502,7 +934,28 @@ func (view *MessageView) Draw(screen mauview.Screen) {
 		for i := index - 1; i >= 0 && view.msgBuffer[i] == msg; i-- {
 			line--
 		}
-		msg.Draw(mauview.NewProxyScreen(screen, messageX, line, view.width-messageX, msg.Height()))
+		var msgScreen mauview.Screen = mauview.NewProxyScreen(screen, messageX, line, view.messageWidth(view.config.Preferences), msg.Height())
+		if msg == view.selected && bareMode {
+			// There are no timestamp and sender columns in bare mode, so the message text itself is highlighted.
+			msgScreen = reversedScreen{msgScreen}
+		}
+		msg.Draw(msgScreen)
 		line += msg.Height() - 1
+		if showReadReceipts(view.config.Preferences) {
+			view.drawReadReceipts(screen, msg, line)
+		}
 	}
 }
+
+// reversedScreen draws everything in reverse video.
+type reversedScreen struct {
+	mauview.Screen
+}
+
+func (screen reversedScreen) SetContent(x int, y int, mainc rune, combc []rune, style tcell.Style) {
+	screen.Screen.SetContent(x, y, mainc, combc, style.Reverse(true))
+}
+
+func (screen reversedScreen) SetCell(x, y int, style tcell.Style, ch ...rune) {
+	screen.Screen.SetCell(x, y, style.Reverse(true), ch...)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/tulir/tcell"
 
 	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/matrix/relations"
 	"github.com/kennetanti/gomuks/ui/messages/tstring"
 	"github.com/kennetanti/gomuks/ui/widget"
 )
@@ -44,7 +45,15 @@ type BaseMessage struct {
 	MsgIsService   bool
 	MsgSource      json.RawMessage
 	ReplyTo        UIMessage
-	buffer         []tstring.TString
+	// The ID of the thread root event if this message is a thread reply.
+	MsgThreadRoot string
+	// The ID of the edited event if this message is an edit. See ParseEvent.
+	MsgEditTarget string
+	buffer        []tstring.TString
+
+	threadReplyCount  int
+	threadLastSender  string
+	threadSenderColor tcell.Color
 }
 
 func newBaseMessage(event *mautrix.Event, displayname string) BaseMessage {
@@ -65,9 +74,17 @@ func newBaseMessage(event *mautrix.Event, displayname string) BaseMessage {
 		MsgIsHighlight: false,
 		MsgIsService:   false,
 		MsgSource:      event.Content.VeryRaw,
+		MsgThreadRoot:  relations.GetThreadRoot(&event.Content),
+		MsgEditTarget:  relations.GetEditTarget(&event.Content),
 	}
 }
 
+// isThreadFallback returns whether or not the reply relation of the event should be ignored because it only
+// exists as a fallback for clients that don't support threads.
+func isThreadFallback(event *mautrix.Event) bool {
+	return relations.IsThreadFallback(&event.Content)
+}
+
 func unixToTime(unix int64) time.Time {
 	timestamp := time.Now()
 	if unix != 0 {
@@ -185,7 +202,58 @@ func (msg *BaseMessage) ReplyHeight() int {
 
 // Height returns the number of rows in the computed buffer (see Buffer()).
 func (msg *BaseMessage) Height() int {
-	return msg.ReplyHeight() + len(msg.buffer)
+	return msg.ReplyHeight() + len(msg.buffer) + msg.ThreadSummaryHeight()
+}
+
+// ThreadRoot returns the ID of the thread root event if this message is a thread reply.
+func (msg *BaseMessage) ThreadRoot() string {
+	return msg.MsgThreadRoot
+}
+
+// EditTarget returns the ID of the edited event if this message is an edit, or an empty string otherwise.
+func (msg *BaseMessage) EditTarget() string {
+	return msg.MsgEditTarget
+}
+
+// SetThreadSummary sets the number of thread replies to this message and the sender of the latest reply.
+// If there are replies, a summary line is drawn under the message.
+func (msg *BaseMessage) SetThreadSummary(replyCount int, lastSender UIMessage) {
+	msg.threadReplyCount = replyCount
+	if lastSender != nil {
+		msg.threadLastSender = lastSender.RealSender()
+		msg.threadSenderColor = lastSender.SenderColor()
+	}
+}
+
+// ThreadReplyCount returns the number of thread replies to this message.
+func (msg *BaseMessage) ThreadReplyCount() int {
+	return msg.threadReplyCount
+}
+
+// ThreadSummaryHeight returns the number of rows the thread summary takes.
+func (msg *BaseMessage) ThreadSummaryHeight() int {
+	if msg.threadReplyCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// DrawThreadSummary draws the thread summary line on the given row.
+func (msg *BaseMessage) DrawThreadSummary(screen mauview.Screen, y int) {
+	if msg.threadReplyCount == 0 {
+		return
+	}
+	summary := "1 reply"
+	if msg.threadReplyCount > 1 {
+		summary = fmt.Sprintf("%d replies", msg.threadReplyCount)
+	}
+	summary = "└ " + summary
+	widget.WriteLineSimpleColor(screen, summary, 0, y, tcell.ColorBlue)
+	if len(msg.threadLastSender) > 0 {
+		x := mauview.StringWidth(summary)
+		widget.WriteLineSimpleColor(screen, ", latest from ", x, y, tcell.ColorBlue)
+		widget.WriteLineSimpleColor(screen, msg.threadLastSender, x+len(", latest from "), y, msg.threadSenderColor)
+	}
 }
 
 // Timestamp returns the full timestamp when the message was sent.
@@ -257,6 +325,7 @@ func (msg *BaseMessage) Draw(screen mauview.Screen) {
 	for y, line := range msg.buffer {
 		line.Draw(screen, 0, y)
 	}
+	msg.DrawThreadSummary(screen, len(msg.buffer))
 }
 
 func (msg *BaseMessage) clone() BaseMessage {
@@ -39,6 +39,20 @@ func NewExpandedTextMessage(event *mautrix.Event, displayname string, text tstri
 	}
 }
 
+// NewFullyReadDividerMessage creates a divider that marks where new (unread) messages start.
+// The timestamp should be the timestamp of the last read message.
+func NewFullyReadDividerMessage(timestamp time.Time) UIMessage {
+	return &ExpandedTextMessage{
+		BaseMessage: BaseMessage{
+			MsgSenderID:  "*",
+			MsgSender:    "*",
+			MsgTimestamp: timestamp,
+			MsgIsService: true,
+		},
+		MsgText: tstring.NewColorTString("——— New messages ———", tcell.ColorRed),
+	}
+}
+
 func NewDateChangeMessage(text string) UIMessage {
 	midnight := time.Now()
 	midnight = time.Date(midnight.Year(), midnight.Month(), midnight.Day(),
@@ -17,6 +17,7 @@
 package html
 
 import (
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -34,7 +35,7 @@ import (
 	"github.com/kennetanti/gomuks/ui/widget"
 )
 
-var matrixToURL = regexp.MustCompile("^(?:https?://)?(?:www\\.)?matrix\\.to/#/([#@!].*)")
+var matrixToURL = regexp.MustCompile("^(?:https?://)?(?:www\\.)?matrix\\.to/#/(.+)")
 
 type htmlParser struct {
 	room *rooms.Room
@@ -168,6 +169,26 @@ func (parser *htmlParser) blockquoteToEntity(node *html.Node) Entity {
 	return NewBlockquoteEntity(parser.nodeToEntities(node.FirstChild))
 }
 
+// parsePillTarget returns the user ID, room alias or room ID that the given matrix.to link points to.
+// Query parameters like ?via= are ignored. Event permalinks are not pills, so an empty string is returned for them.
+func parsePillTarget(href string) string {
+	match := matrixToURL.FindStringSubmatch(href)
+	if len(match) != 2 {
+		return ""
+	}
+	target := match[1]
+	if index := strings.IndexRune(target, '?'); index >= 0 {
+		target = target[:index]
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if len(target) == 0 || strings.ContainsRune(target, '/') || !strings.ContainsRune("#@!", rune(target[0])) {
+		return ""
+	}
+	return target
+}
+
 func (parser *htmlParser) linkToEntity(node *html.Node) Entity {
 	entity := &ContainerEntity{
 		BaseEntity: &BaseEntity{
@@ -179,22 +200,23 @@ func (parser *htmlParser) linkToEntity(node *html.Node) Entity {
 	if len(href) == 0 {
 		return entity
 	}
-	match := matrixToURL.FindStringSubmatch(href)
-	if len(match) == 2 {
-		pillTarget := match[1]
+	pillTarget := parsePillTarget(href)
+	switch {
+	case len(pillTarget) == 0:
+	case pillTarget[0] == '@':
+		// User pills show the current displayname of the user, or the link text if the user isn't in the room.
 		text := NewTextEntity(pillTarget)
-		if pillTarget[0] == '@' {
-			if member := parser.room.GetMember(pillTarget); member != nil {
-				text.Text = member.Displayname
-				text.Style = text.Style.Foreground(widget.GetHashColor(pillTarget))
-			}
-			entity.Children = []Entity{text}
-			/*} else if slash := strings.IndexRune(pillTarget, '/'); slash != -1 {
-			room := pillTarget[:slash]
-			event := pillTarget[slash+1:]*/
-		} else if pillTarget[0] == '#' {
-			entity.Children = []Entity{text}
+		if member := parser.room.GetMember(pillTarget); member != nil && len(member.Displayname) > 0 {
+			text.Text = member.Displayname
+		} else if linkText := strings.TrimSpace(entity.PlainText()); len(linkText) > 0 {
+			text.Text = linkText
 		}
+		text.Style = text.Style.Foreground(widget.GetHashColor(pillTarget))
+		entity.Children = []Entity{text}
+	case pillTarget[0] == '#':
+		text := NewTextEntity(pillTarget)
+		text.Style = text.Style.Foreground(widget.GetHashColor(pillTarget))
+		entity.Children = []Entity{text}
 	}
 	// TODO add click action and underline on hover for links
 	return entity
@@ -58,6 +58,7 @@ func (hw *HTMLMessage) Draw(screen mauview.Screen) {
 	}
 	screen.Clear()
 	hw.Root.Draw(screen)
+	hw.DrawThreadSummary(screen, hw.Root.Height())
 }
 
 func (hw *HTMLMessage) Focus() {
@@ -91,7 +92,7 @@ func (hw *HTMLMessage) CalculateBuffer(preferences config.UserPreferences, width
 }
 
 func (hw *HTMLMessage) Height() int {
-	return hw.ReplyHeight() + hw.Root.Height()
+	return hw.ReplyHeight() + hw.Root.Height() + hw.ThreadSummaryHeight()
 }
 
 func (hw *HTMLMessage) PlainText() string {
@@ -38,6 +38,10 @@ type UIMessage interface {
 	SameDate(message UIMessage) bool
 
 	SetReplyTo(message UIMessage)
+	ThreadRoot() string
+	EditTarget() string
+	ThreadReplyCount() int
+	SetThreadSummary(replyCount int, lastSender UIMessage)
 	CalculateBuffer(preferences config.UserPreferences, width int)
 	Draw(screen mauview.Screen)
 	Height() int
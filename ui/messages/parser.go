@@ -17,6 +17,7 @@
 package messages
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -25,6 +26,7 @@ import (
 
 	"github.com/kennetanti/gomuks/debug"
 	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/matrix/relations"
 	"github.com/kennetanti/gomuks/matrix/rooms"
 	"github.com/kennetanti/gomuks/ui/messages/html"
 	"github.com/kennetanti/gomuks/ui/messages/tstring"
@@ -42,12 +44,19 @@ func getCachedEvent(mainView ifc.MainView, roomID, eventID string) UIMessage {
 	return nil
 }
 
+// ParseEvent parses the given event into a UIMessage.
+//
+// Edits are parsed using their new content and get the ID of the edited event, so that MessageView replaces
+// the edited message instead of showing the fallback body. The EditTarget of the message is also set.
 func ParseEvent(matrix ifc.MatrixContainer, mainView ifc.MainView, room *rooms.Room, evt *mautrix.Event) UIMessage {
+	if edited := getEditedEvent(evt); edited != nil {
+		evt = edited
+	}
 	msg := directParseEvent(matrix, room, evt)
 	if msg == nil {
 		return nil
 	}
-	if len(evt.Content.GetReplyTo()) > 0 {
+	if len(evt.Content.GetReplyTo()) > 0 && !isThreadFallback(evt) {
 		replyToRoom := room
 		if len(evt.Content.RelatesTo.InReplyTo.RoomID) > 0 {
 			replyToRoom = matrix.GetRoom(evt.Content.RelatesTo.InReplyTo.RoomID)
@@ -70,6 +79,30 @@ func ParseEvent(matrix ifc.MatrixContainer, mainView ifc.MainView, room *rooms.R
 	return msg
 }
 
+// getEditedEvent returns a copy of the given edit event with the new content and the ID of the edited event,
+// or nil if the event isn't an edit.
+func getEditedEvent(evt *mautrix.Event) *mautrix.Event {
+	target := relations.GetEditTarget(&evt.Content)
+	newContent, ok := evt.Content.Raw["m.new_content"].(map[string]interface{})
+	if len(target) == 0 || !ok {
+		return nil
+	}
+	data, err := json.Marshal(newContent)
+	if err != nil {
+		return nil
+	}
+	edited := *evt
+	edited.ID = target
+	edited.Content = mautrix.Content{}
+	if err = json.Unmarshal(data, &edited.Content); err != nil {
+		debug.Print("Failed to parse new content of edit", evt.ID, err)
+		return nil
+	}
+	// Keep the edit relation so that the message knows what it replaces.
+	edited.Content.Raw["m.relates_to"] = evt.Content.Raw["m.relates_to"]
+	return &edited
+}
+
 func directParseEvent(matrix ifc.MatrixContainer, room *rooms.Room, evt *mautrix.Event) UIMessage {
 	switch evt.Type {
 	case mautrix.EventSticker:
@@ -77,13 +110,13 @@ func directParseEvent(matrix ifc.MatrixContainer, room *rooms.Room, evt *mautrix
 		fallthrough
 	case mautrix.EventMessage:
 		return ParseMessage(matrix, room, evt)
-	case mautrix.StateTopic, mautrix.StateRoomName, mautrix.StateAliases, mautrix.StateCanonicalAlias:
+	case mautrix.StateTopic, mautrix.StateRoomName, mautrix.StateAliases, mautrix.StateCanonicalAlias, mautrix.StatePinnedEvents:
 		return ParseStateEvent(matrix, room, evt)
 	case mautrix.StateMember:
 		return ParseMembershipEvent(room, evt)
 	}
 
-	return nil
+	return parsePollEvent(room, evt)
 }
 
 func ParseStateEvent(matrix ifc.MatrixContainer, room *rooms.Room, evt *mautrix.Event) UIMessage {
@@ -120,6 +153,8 @@ func ParseStateEvent(matrix ifc.MatrixContainer, room *rooms.Room, evt *mautrix.
 		}
 	case mautrix.StateAliases:
 		text = ParseAliasEvent(evt, displayname)
+	case mautrix.StatePinnedEvents:
+		text = text.AppendColor(" changed the pinned messages of the room.", tcell.ColorGreen)
 	}
 	return NewExpandedTextMessage(evt, displayname, text)
 }
@@ -0,0 +1,348 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tulir/mautrix-go"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/matrix/polls"
+	"github.com/kennetanti/gomuks/matrix/rooms"
+	"github.com/kennetanti/gomuks/ui/messages/tstring"
+)
+
+// PollBarWidth is the width of the result bar of an answer that got all the votes.
+const PollBarWidth = 10
+
+type pollVote struct {
+	answers   []string
+	timestamp time.Time
+}
+
+// PollMessage is a poll start event that shows the question, the answers and the tallies of the votes.
+type PollMessage struct {
+	BaseMessage
+	Poll *polls.Poll
+
+	// The ID of the current user, used to mark the chosen answers and to check if the poll can be ended.
+	ownUserID string
+	// The latest vote of each user.
+	votes   map[string]pollVote
+	ended   bool
+	endTime time.Time
+
+	// The buffer row where each answer starts, followed by the row after the last answer.
+	answerRows []int
+	// The buffer row of the end poll button, or -1 if the button isn't shown.
+	endRow int
+}
+
+// PollUpdateMessage is a poll response or end event. It isn't shown in the timeline,
+// but applied to the poll it refers to.
+type PollUpdateMessage struct {
+	ExpandedTextMessage
+	PollID     string
+	Selections []string
+	IsEnd      bool
+}
+
+// NewPollMessage creates a new PollMessage object with the provided values and no votes.
+func NewPollMessage(event *mautrix.Event, displayname string, poll *polls.Poll, ownUserID string) UIMessage {
+	return &PollMessage{
+		BaseMessage: newBaseMessage(event, displayname),
+		Poll:        poll,
+		ownUserID:   ownUserID,
+		votes:       make(map[string]pollVote),
+		endRow:      -1,
+	}
+}
+
+func parsePollEvent(room *rooms.Room, evt *mautrix.Event) UIMessage {
+	isStart := polls.IsPollStart(evt.Type)
+	if !isStart && !polls.IsPollUpdate(evt.Type) {
+		return nil
+	}
+	displayname := evt.Sender
+	if member := room.GetMember(evt.Sender); member != nil {
+		displayname = member.Displayname
+	}
+	if isStart {
+		poll := polls.ParsePoll(&evt.Content)
+		if poll == nil {
+			return nil
+		}
+		return NewPollMessage(evt, displayname, poll, room.SessionUserID)
+	}
+	pollID := polls.GetPollReference(&evt.Content)
+	if len(pollID) == 0 {
+		return nil
+	}
+	update := &PollUpdateMessage{
+		ExpandedTextMessage: ExpandedTextMessage{BaseMessage: newBaseMessage(evt, displayname)},
+		PollID:              pollID,
+		IsEnd:               polls.IsPollEnd(evt.Type),
+	}
+	if update.IsEnd {
+		update.MsgText = tstring.NewColorTString("ended a poll.", tcell.ColorGreen)
+	} else {
+		update.Selections = polls.GetPollSelections(&evt.Content)
+		update.MsgText = tstring.NewColorTString("voted in a poll.", tcell.ColorGreen)
+	}
+	return update
+}
+
+func (msg *PollUpdateMessage) Clone() UIMessage {
+	return &PollUpdateMessage{
+		ExpandedTextMessage: ExpandedTextMessage{
+			BaseMessage: msg.BaseMessage.clone(),
+			MsgText:     msg.MsgText.Clone(),
+		},
+		PollID:     msg.PollID,
+		Selections: msg.Selections,
+		IsEnd:      msg.IsEnd,
+	}
+}
+
+func (msg *PollMessage) Clone() UIMessage {
+	votes := make(map[string]pollVote, len(msg.votes))
+	for userID, vote := range msg.votes {
+		votes[userID] = vote
+	}
+	return &PollMessage{
+		BaseMessage: msg.BaseMessage.clone(),
+		Poll:        msg.Poll,
+		ownUserID:   msg.ownUserID,
+		votes:       votes,
+		ended:       msg.ended,
+		endTime:     msg.endTime,
+		endRow:      -1,
+	}
+}
+
+// ApplyUpdate counts the vote in the given poll response, or ends the poll if the update is an end event.
+// Only the latest response of each user is counted, and only the creator of the poll can end it.
+func (msg *PollMessage) ApplyUpdate(update *PollUpdateMessage) {
+	if update.IsEnd {
+		if update.SenderID() != msg.SenderID() {
+			return
+		} else if !msg.ended || update.Timestamp().Before(msg.endTime) {
+			msg.ended = true
+			msg.endTime = update.Timestamp()
+		}
+		return
+	}
+	if prev, ok := msg.votes[update.SenderID()]; ok && prev.timestamp.After(update.Timestamp()) {
+		return
+	}
+	answers := make([]string, 0, len(update.Selections))
+	for _, answerID := range update.Selections {
+		if len(answers) >= msg.Poll.MaxSelections {
+			break
+		} else if msg.Poll.HasAnswer(answerID) && !containsString(answers, answerID) {
+			answers = append(answers, answerID)
+		}
+	}
+	msg.votes[update.SenderID()] = pollVote{answers: answers, timestamp: update.Timestamp()}
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+// Tally returns the number of votes for each answer and the number of users who voted.
+// Votes sent after the poll ended aren't counted.
+func (msg *PollMessage) Tally() (counts map[string]int, total int) {
+	counts = make(map[string]int)
+	for _, vote := range msg.votes {
+		if len(vote.answers) == 0 || (msg.ended && vote.timestamp.After(msg.endTime)) {
+			continue
+		}
+		total++
+		for _, answerID := range vote.answers {
+			counts[answerID]++
+		}
+	}
+	return
+}
+
+// OwnVote returns the IDs of the answers the current user has chosen.
+func (msg *PollMessage) OwnVote() []string {
+	return msg.votes[msg.ownUserID].answers
+}
+
+// IsEnded returns whether or not the poll has been ended by its creator.
+func (msg *PollMessage) IsEnded() bool {
+	return msg.ended
+}
+
+// CanEnd returns whether or not the current user can end the poll.
+func (msg *PollMessage) CanEnd() bool {
+	return !msg.ended && msg.ownUserID == msg.SenderID()
+}
+
+// ToggleVote returns the answers the current user would have chosen after clicking the given answer.
+// In single choice polls the answer replaces the previous vote. In multiple choice polls the answer
+// is added or removed, and the oldest choice is dropped if the maximum number of choices is exceeded.
+func (msg *PollMessage) ToggleVote(answerID string) []string {
+	if msg.Poll.MaxSelections <= 1 {
+		return []string{answerID}
+	}
+	current := msg.OwnVote()
+	toggled := make([]string, 0, len(current)+1)
+	for _, chosen := range current {
+		if chosen != answerID {
+			toggled = append(toggled, chosen)
+		}
+	}
+	if len(toggled) == len(current) {
+		toggled = append(toggled, answerID)
+		if len(toggled) > msg.Poll.MaxSelections {
+			toggled = toggled[1:]
+		}
+	}
+	return toggled
+}
+
+// AnswerAt returns the ID of the answer drawn on the given row of the message, excluding the reply header.
+func (msg *PollMessage) AnswerAt(row int) (string, bool) {
+	for i := 0; i < len(msg.answerRows)-1; i++ {
+		if row >= msg.answerRows[i] && row < msg.answerRows[i+1] {
+			return msg.Poll.Answers[i].ID, true
+		}
+	}
+	return "", false
+}
+
+// IsEndButtonAt returns whether or not the end poll button is drawn on the given row of the message.
+func (msg *PollMessage) IsEndButtonAt(row int) bool {
+	return msg.endRow >= 0 && row == msg.endRow
+}
+
+func (msg *PollMessage) showResults() bool {
+	return msg.ended || msg.Poll.IsDisclosed()
+}
+
+func (msg *PollMessage) generateLines() (question tstring.TString, answers []tstring.TString, footer tstring.TString) {
+	prefix := "Poll: "
+	if msg.ended {
+		prefix = "Poll (ended): "
+	}
+	question = tstring.NewColorTString(prefix, msg.TextColor()).
+		AppendStyle(msg.Poll.Question, tcell.StyleDefault.Foreground(msg.TextColor()).Bold(true))
+
+	counts, total := msg.Tally()
+	winnerCount := 0
+	for _, count := range counts {
+		if count > winnerCount {
+			winnerCount = count
+		}
+	}
+	ownVote := msg.OwnVote()
+	for i, answer := range msg.Poll.Answers {
+		marker := "( ) "
+		if msg.Poll.MaxSelections > 1 {
+			marker = "[ ] "
+		}
+		if containsString(ownVote, answer.ID) {
+			marker = strings.Replace(marker, " ", "x", 1)
+		}
+		color := msg.TextColor()
+		if msg.ended && winnerCount > 0 && counts[answer.ID] == winnerCount {
+			color = tcell.ColorGreen
+		}
+		line := tstring.NewColorTString(fmt.Sprintf("%s%d. %s", marker, i+1, answer.Text), color)
+		if msg.showResults() {
+			count := counts[answer.ID]
+			percentage := 0
+			barWidth := 0
+			if total > 0 {
+				percentage = count * 100 / total
+				barWidth = count * PollBarWidth / total
+			}
+			line = line.AppendColor(" "+strings.Repeat("█", barWidth), tcell.ColorBlue).
+				AppendColor(fmt.Sprintf(" %d (%d%%)", count, percentage), color)
+		}
+		answers = append(answers, line)
+	}
+
+	summary := "1 vote"
+	if total != 1 {
+		summary = fmt.Sprintf("%d votes", total)
+	}
+	if !msg.showResults() {
+		summary += ", results are shown when the poll ends"
+	}
+	if !msg.ended {
+		summary += ". Click an answer or use /vote to vote."
+	}
+	footer = tstring.NewColorTString(summary, tcell.ColorGray)
+	return
+}
+
+func (msg *PollMessage) NotificationContent() string {
+	return "Poll: " + msg.Poll.Question
+}
+
+func (msg *PollMessage) PlainText() string {
+	question, answers, footer := msg.generateLines()
+	lines := []tstring.TString{question}
+	lines = append(lines, answers...)
+	lines = append(lines, footer)
+	return tstring.Join(lines, "\n").String()
+}
+
+// CalculateBuffer wraps each line of the poll separately to keep track of which rows each answer is drawn on.
+func (msg *PollMessage) CalculateBuffer(prefs config.UserPreferences, width int) {
+	if width < 2 {
+		return
+	}
+	msg.CalculateReplyBuffer(prefs, width)
+
+	question, answers, footer := msg.generateLines()
+	var buffer []tstring.TString
+	appendLine := func(line tstring.TString) {
+		msg.calculateBufferWithText(prefs, line, width)
+		buffer = append(buffer, msg.buffer...)
+		// Only the first line has the timestamp and sender in bare mode.
+		prefs.BareMessageView = false
+	}
+
+	appendLine(question)
+	msg.answerRows = make([]int, 0, len(answers)+1)
+	for _, answer := range answers {
+		msg.answerRows = append(msg.answerRows, len(buffer))
+		appendLine(answer)
+	}
+	msg.answerRows = append(msg.answerRows, len(buffer))
+	appendLine(footer)
+	msg.endRow = -1
+	if msg.CanEnd() {
+		msg.endRow = len(buffer)
+		appendLine(tstring.NewColorTString("[End poll]", tcell.ColorRed))
+	}
+	msg.buffer = buffer
+}
@@ -0,0 +1,308 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tulir/mautrix-go"
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/lib/clipboard"
+	"github.com/kennetanti/gomuks/lib/open"
+	"github.com/kennetanti/gomuks/ui/messages"
+)
+
+// normalMode is the state of the vim-style normal mode of a room view, in which keys navigate
+// the message view and act on the selected message instead of being typed into the input.
+type normalMode struct {
+	active bool
+	// The first key of a two-key command, i.e. 'g' of gg or 'y' of the copy commands.
+	pending rune
+	// Whether or not a search query is being typed after /.
+	searching bool
+	query     string
+	lastQuery string
+	// Whether or not the user is being asked to confirm redacting the selected message.
+	confirmRedact bool
+	// The result of the last command, shown in the status bar.
+	message string
+}
+
+var (
+	hrefRegex = regexp.MustCompile(`href="([^"]+)"`)
+	urlRegex  = regexp.MustCompile(`https?://[^\s<>"]+`)
+)
+
+// messageContent returns the body and formatted body of the given message from its source.
+func messageContent(message messages.UIMessage) (body, formattedBody string) {
+	var content struct {
+		Body          string `json:"body"`
+		FormattedBody string `json:"formatted_body"`
+	}
+	source, _ := message.(interface{ Source() json.RawMessage })
+	if source != nil && json.Unmarshal(source.Source(), &content) == nil && len(content.Body) > 0 {
+		return mautrix.TrimReplyFallbackText(content.Body), mautrix.TrimReplyFallbackHTML(content.FormattedBody)
+	}
+	return message.PlainText(), ""
+}
+
+// messageLink returns the first link in the given message, or the path of the image of an image message.
+func messageLink(message messages.UIMessage) string {
+	if image, ok := message.(*messages.ImageMessage); ok {
+		return image.Path()
+	}
+	body, formattedBody := messageContent(message)
+	if match := hrefRegex.FindStringSubmatch(formattedBody); match != nil {
+		return strings.Replace(match[1], "&amp;", "&", -1)
+	}
+	return strings.TrimRight(urlRegex.FindString(body), ".,;:!?)")
+}
+
+// EnterNormalMode switches the room view to normal mode, cancelling any reply or edit in progress.
+func (view *RoomView) EnterNormalMode() {
+	if view.editing != nil {
+		view.SetInputText("")
+	}
+	view.replyTo = nil
+	view.editing = nil
+	view.normal = normalMode{active: true, lastQuery: view.normal.lastQuery}
+	if view.content.Selected() == nil {
+		view.content.SelectNewest()
+	}
+	view.input.Blur()
+}
+
+// ExitNormalMode switches the room view back to typing into the input.
+func (view *RoomView) ExitNormalMode() {
+	view.normal = normalMode{lastQuery: view.normal.lastQuery}
+	view.content.Select(nil)
+	view.input.Focus()
+}
+
+// handleNormalModeKey handles a key event in normal mode. Keys that aren't normal mode commands
+// return false, so that key bindings like scrolling with PgUp and PgDn still work.
+func (view *RoomView) handleNormalModeKey(event mauview.KeyEvent) bool {
+	mode := &view.normal
+	if mode.searching {
+		view.handleSearchKey(event)
+		return true
+	} else if mode.confirmRedact {
+		mode.confirmRedact = false
+		if event.Key() == tcell.KeyRune && event.Rune() == 'y' {
+			go view.RedactMessage(view.content.Selected())
+		} else {
+			mode.message = "Redaction cancelled"
+		}
+		return true
+	}
+
+	msgView := view.MessageView()
+	pending := mode.pending
+	mode.pending = 0
+	mode.message = ""
+	if pending == 'y' {
+		view.copySelected(event)
+		return true
+	}
+	switch event.Key() {
+	case tcell.KeyEsc:
+		return true
+	case tcell.KeyEnter:
+		if selected := msgView.Selected(); selected != nil && selected.ThreadReplyCount() > 0 {
+			view.OpenThread(selected.ID())
+		}
+		return true
+	case tcell.KeyDown:
+		msgView.MoveSelection(1)
+		return true
+	case tcell.KeyUp:
+		view.selectOlder()
+		return true
+	case tcell.KeyRune:
+		if event.Modifiers()&(tcell.ModCtrl|tcell.ModAlt) != 0 {
+			return false
+		}
+	default:
+		return false
+	}
+
+	switch event.Rune() {
+	case 'i', 'a':
+		view.ExitNormalMode()
+	case 'j':
+		msgView.MoveSelection(1)
+	case 'k':
+		view.selectOlder()
+	case 'g':
+		if pending == 'g' {
+			msgView.SelectOldest()
+		} else {
+			mode.pending = 'g'
+		}
+	case 'G':
+		msgView.SelectNewest()
+	case '/':
+		mode.searching = true
+		mode.query = ""
+	case 'n', 'N':
+		if len(mode.lastQuery) == 0 {
+			mode.message = "No previous search"
+		} else if !msgView.SearchSelect(mode.lastQuery, event.Rune() == 'N') {
+			mode.message = fmt.Sprintf("Pattern not found: %s", mode.lastQuery)
+		}
+	default:
+		view.runMessageCommand(event.Rune())
+	}
+	return true
+}
+
+// selectOlder selects the previous message, loading more history if the oldest loaded message is selected.
+func (view *RoomView) selectOlder() {
+	if !view.MessageView().MoveSelection(-1) && !view.MessageView().LoadingMessages {
+		go view.parent.LoadHistory(view.Room.ID)
+	}
+}
+
+func (view *RoomView) handleSearchKey(event mauview.KeyEvent) {
+	mode := &view.normal
+	switch event.Key() {
+	case tcell.KeyEsc:
+		mode.searching = false
+	case tcell.KeyEnter:
+		mode.searching = false
+		if len(mode.query) == 0 {
+			return
+		}
+		mode.lastQuery = mode.query
+		if !view.MessageView().SearchSelect(mode.query, false) {
+			mode.message = fmt.Sprintf("Pattern not found: %s", mode.query)
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(mode.query) == 0 {
+			mode.searching = false
+		} else {
+			runes := []rune(mode.query)
+			mode.query = string(runes[:len(runes)-1])
+		}
+	case tcell.KeyRune:
+		mode.query += string(event.Rune())
+	}
+}
+
+// runMessageCommand runs the normal mode command of the given key on the selected message.
+func (view *RoomView) runMessageCommand(key rune) {
+	mode := &view.normal
+	selected := view.MessageView().Selected()
+	if strings.IndexRune("redyo", key) == -1 {
+		return
+	} else if selected == nil {
+		mode.message = "No message selected"
+		return
+	}
+	switch key {
+	case 'r':
+		view.ExitNormalMode()
+		view.replyTo = selected
+	case 'e':
+		if selected.SenderID() != view.matrix.Client().UserID {
+			mode.message = "You can only edit your own messages"
+			return
+		} else if msgtype := selected.Type(); msgtype != mautrix.MsgText && msgtype != mautrix.MsgEmote && msgtype != mautrix.MsgNotice {
+			mode.message = "Only text messages can be edited"
+			return
+		}
+		body, _ := messageContent(selected)
+		view.ExitNormalMode()
+		view.editing = selected
+		view.SetInputText(body)
+	case 'd':
+		mode.confirmRedact = true
+	case 'y':
+		mode.pending = 'y'
+		mode.message = "Copy: y text, h HTML, i event ID, s source"
+	case 'o':
+		if link := messageLink(selected); len(link) == 0 {
+			mode.message = "No link in message"
+		} else {
+			open.Open(link)
+		}
+	}
+}
+
+// copySelected copies the part of the selected message chosen by the key pressed after y.
+func (view *RoomView) copySelected(event mauview.KeyEvent) {
+	parts := map[rune]string{'y': clipboard.PartText, 't': clipboard.PartText, 'h': clipboard.PartHTML, 'i': clipboard.PartID, 's': clipboard.PartSource}
+	part, ok := parts[event.Rune()]
+	if event.Key() != tcell.KeyRune || !ok {
+		view.normal.message = "Copy cancelled"
+		return
+	}
+	view.normal.message = view.parent.CopyMessage(view.MessageView().Selected(), part)
+}
+
+// getNormalModeStatus returns the status bar text of normal mode, replies and edits.
+func (view *RoomView) getNormalModeStatus() string {
+	mode := &view.normal
+	switch {
+	case mode.searching:
+		return "/" + mode.query
+	case mode.confirmRedact:
+		return "Redact the selected message? (y/n)"
+	case mode.active && len(mode.message) > 0:
+		return "-- NORMAL -- " + mode.message
+	case mode.active:
+		return "-- NORMAL --"
+	case view.editing != nil:
+		return "Editing message (Esc to cancel)"
+	case view.replyTo != nil:
+		return fmt.Sprintf("Replying to %s (Esc to cancel)", view.replyTo.Sender())
+	}
+	return ""
+}
+
+// RedactMessage redacts the given message and replaces it with a placeholder.
+// It's called in a goroutine, so the view is updated on the UI goroutine.
+func (view *RoomView) RedactMessage(message messages.UIMessage) {
+	defer debug.Recover()
+	if message == nil {
+		return
+	}
+	err := view.matrix.Redact(view.Room.ID, message.ID(), "")
+	view.parent.parent.app.QueueUpdate(func() {
+		if err != nil {
+			view.normal.message = fmt.Sprintf("Failed to redact message: %v", err)
+		} else {
+			redacted := messages.NewTextMessage(&mautrix.Event{
+				ID:        message.ID(),
+				Sender:    message.SenderID(),
+				Type:      mautrix.EventMessage,
+				Timestamp: message.Timestamp().UnixNano() / int64(time.Millisecond),
+				Content:   mautrix.Content{MsgType: mautrix.MsgNotice},
+			}, message.Sender(), "Message deleted")
+			view.AddMessage(redacted)
+			view.normal.message = "Message redacted"
+		}
+		view.parent.parent.Render()
+	})
+}
@@ -0,0 +1,145 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"github.com/mattn/go-runewidth"
+
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/matrix/pills"
+	"github.com/kennetanti/gomuks/ui/widget"
+)
+
+type recordedCell struct {
+	x, y  int
+	mainc rune
+	combc []rune
+	style tcell.Style
+}
+
+// recordingScreen is a screen that records the cells set on it instead of drawing them immediately.
+// InputArea draws the runes of its text in order, so the recorded cells can be mapped back to the text.
+type recordingScreen struct {
+	mauview.Screen
+	cells []recordedCell
+
+	cursorShown      bool
+	cursorX, cursorY int
+}
+
+func (screen *recordingScreen) SetContent(x int, y int, mainc rune, combc []rune, style tcell.Style) {
+	screen.cells = append(screen.cells, recordedCell{x, y, mainc, combc, style})
+}
+
+func (screen *recordingScreen) ShowCursor(x int, y int) {
+	screen.cursorShown = true
+	screen.cursorX, screen.cursorY = x, y
+	screen.Screen.ShowCursor(x, y)
+}
+
+// inputRuneWidth returns the width of a rune the same way InputArea counts it.
+func inputRuneWidth(char rune) int {
+	if char == '\n' {
+		return 1
+	}
+	return runewidth.RuneWidth(char)
+}
+
+type pillSpan struct {
+	start, end int
+	color      tcell.Color
+}
+
+// pillSpans returns the width offsets of the tokens of the given pills in the text.
+func pillSpans(text string, mentions []pills.Pill) (spans []pillSpan) {
+	ranges := pills.Find(text, mentions)
+	if len(ranges) == 0 {
+		return
+	}
+	offset := 0
+	next := 0
+	for index, char := range text {
+		if next >= len(ranges) {
+			break
+		}
+		if index == ranges[next].Start {
+			spans = append(spans, pillSpan{start: offset, color: widget.GetHashColor(ranges[next].ID)})
+		}
+		offset += inputRuneWidth(char)
+		if index+len(string(char)) == ranges[next].End {
+			spans[len(spans)-1].end = offset
+			next++
+		}
+	}
+	return
+}
+
+// cellOffsets returns the width offset of the rune in each recorded cell relative to the first cell.
+// InputArea draws a wide rune into as many cells as it's wide, so the width of a rune is only added
+// once and the rest of its cells get the same offset as the first one.
+func cellOffsets(cells []recordedCell) []int {
+	offsets := make([]int, len(cells))
+	width := 0
+	for index := 0; index < len(cells); {
+		cell := cells[index]
+		runeWidth := inputRuneWidth(cell.mainc)
+		offsets[index] = width
+		index++
+		for i := 1; i < runeWidth && index < len(cells) && cells[index].mainc == cell.mainc &&
+			cells[index].y == cell.y && cells[index].x == cell.x+i; i++ {
+			offsets[index] = width
+			index++
+		}
+		width += runeWidth
+	}
+	return offsets
+}
+
+// drawInputWithPills draws the input area and shows the tokens of the given pills as coloured pills.
+func drawInputWithPills(input *mauview.InputArea, screen mauview.Screen, mentions []pills.Pill) {
+	spans := pillSpans(input.GetText(), mentions)
+	if len(spans) == 0 {
+		input.Draw(screen)
+		return
+	}
+	recorder := &recordingScreen{Screen: screen}
+	input.Draw(recorder)
+	offsets := cellOffsets(recorder.cells)
+	// The first drawn cell is at offset zero unless the input is scrolled. The cursor is always
+	// on a drawn cell, so its position tells the offset of the first cell when the input is focused.
+	startOffset := 0
+	if recorder.cursorShown {
+		for index, cell := range recorder.cells {
+			if cell.x == recorder.cursorX && cell.y == recorder.cursorY {
+				startOffset = input.GetCursorOffset() - offsets[index]
+				break
+			}
+		}
+	}
+	for index, cell := range recorder.cells {
+		offset := startOffset + offsets[index]
+		for _, span := range spans {
+			if offset >= span.start && offset < span.end {
+				cell.style = cell.style.Foreground(span.color).Reverse(true)
+				break
+			}
+		}
+		screen.SetContent(cell.x, cell.y, cell.mainc, cell.combc, cell.style)
+	}
+}
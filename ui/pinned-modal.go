@@ -0,0 +1,105 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/debug"
+)
+
+// PinnedMessagesModal lists the messages pinned in a room.
+type PinnedMessagesModal struct {
+	mauview.Component
+
+	container *mauview.Box
+	list      *mauview.TextView
+
+	room   *RoomView
+	parent *MainView
+}
+
+func NewPinnedMessagesModal(room *RoomView) *PinnedMessagesModal {
+	modal := &PinnedMessagesModal{
+		list:   mauview.NewTextView().SetWordWrap(true).SetText("Loading..."),
+		room:   room,
+		parent: room.parent,
+	}
+	modal.container = mauview.NewBox(modal.list).
+		SetBorder(true).
+		SetTitle("Pinned messages in " + room.Room.GetTitle()).
+		SetBlurCaptureFunc(func() bool {
+			modal.parent.HideModal()
+			return true
+		})
+	modal.Component = mauview.Center(modal.container, 70, 20).SetAlwaysFocusChild(true)
+
+	go modal.load()
+	return modal
+}
+
+// load fetches the pinned events and fills the list.
+func (modal *PinnedMessagesModal) load() {
+	defer debug.Recover()
+	pinned := modal.room.Room.GetPinnedEvents()
+	if len(pinned) == 0 {
+		modal.list.SetText("There are no pinned messages in this room.")
+		modal.parent.parent.Render()
+		return
+	}
+
+	var buf strings.Builder
+	for index, eventID := range pinned {
+		evt, err := modal.room.matrix.GetEvent(modal.room.Room, eventID)
+		if err != nil {
+			debug.Printf("Failed to get pinned event %s: %v", eventID, err)
+			fmt.Fprintf(&buf, "%d. Failed to load %s: %v\n\n", index+1, eventID, err)
+			continue
+		}
+		timestamp := time.Unix(evt.Timestamp/1000, evt.Timestamp%1000*int64(time.Millisecond))
+		body := evt.Content.Body
+		if len(body) == 0 {
+			body = fmt.Sprintf("<%s event>", evt.Type.Type)
+		}
+		fmt.Fprintf(&buf, "%d. %s, %s:\n%s\n\n", index+1, modal.room.getDisplayname(evt.Sender),
+			timestamp.Format("2006-01-02 15:04"), body)
+	}
+	buf.WriteString("Use /unpin <number> to unpin a message.")
+	modal.list.SetText(buf.String())
+	modal.parent.parent.Render()
+}
+
+func (modal *PinnedMessagesModal) OnKeyEvent(event mauview.KeyEvent) bool {
+	if event.Key() == tcell.KeyEsc {
+		modal.parent.HideModal()
+		return true
+	}
+	return modal.list.OnKeyEvent(event)
+}
+
+func (modal *PinnedMessagesModal) Focus() {
+	modal.container.Focus()
+}
+
+func (modal *PinnedMessagesModal) Blur() {
+	modal.container.Blur()
+}
@@ -0,0 +1,123 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+)
+
+// PresenceDot is the character used to show the presence of users in the member and room lists.
+const PresenceDot = '●'
+
+// IdleTimeout is how long the terminal must be unfocused before the own presence is set to unavailable.
+const IdleTimeout = 5 * time.Minute
+
+// PresenceColor returns the color of the presence dot for the given presence.
+func PresenceColor(presence *ifc.Presence) tcell.Color {
+	if presence == nil {
+		return tcell.ColorDefault
+	}
+	switch presence.Presence {
+	case ifc.PresenceOnline:
+		return tcell.ColorGreen
+	case ifc.PresenceUnavailable:
+		return tcell.ColorYellow
+	default:
+		return tcell.ColorGray
+	}
+}
+
+func formatDurationAgo(duration time.Duration) string {
+	switch {
+	case duration < time.Minute:
+		return "just now"
+	case duration < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(duration.Minutes()))
+	case duration < 24*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(duration.Hours()))
+	default:
+		return fmt.Sprintf("%d days ago", int(duration.Hours()/24))
+	}
+}
+
+// FormatPresence returns a human-readable description of the given presence, including when the user was last active.
+func FormatPresence(presence *ifc.Presence) string {
+	if presence == nil {
+		return "presence unknown"
+	}
+	var text string
+	switch presence.Presence {
+	case ifc.PresenceOnline:
+		text = "online"
+	case ifc.PresenceUnavailable:
+		text = "idle"
+	default:
+		text = "offline"
+	}
+	if presence.CurrentlyActive {
+		text += ", currently active"
+	} else if !presence.LastActive.IsZero() {
+		text += ", last active " + formatDurationAgo(time.Since(presence.LastActive))
+	}
+	if len(presence.StatusMsg) > 0 {
+		text += fmt.Sprintf(" (%s)", presence.StatusMsg)
+	}
+	return text
+}
+
+// updateOwnPresence sets the presence of all accounts to online or unavailable depending on
+// whether the terminal has been focused recently.
+func (view *MainView) updateOwnPresence() {
+	presence := ifc.PresenceOnline
+	if time.Since(view.getLastFocusTime()) > IdleTimeout {
+		presence = ifc.PresenceUnavailable
+	}
+	for _, account := range view.gmx.Accounts() {
+		go account.SetPresence(presence)
+	}
+}
+
+func (view *MainView) getLastFocusTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&view.lastFocusTime))
+}
+
+func (view *MainView) setLastFocusTime(t time.Time) {
+	atomic.StoreInt64(&view.lastFocusTime, t.UnixNano())
+}
+
+// autoIdleLoop periodically checks if the terminal has been unfocused long enough to be considered idle.
+// It returns when stopAutoIdle is closed.
+func (view *MainView) autoIdleLoop() {
+	defer debug.Recover()
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			view.updateOwnPresence()
+		case <-view.stopAutoIdle:
+			return
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+)
+
+// PromptTextWidth is the width of the text area in prompt modals.
+const PromptTextWidth = 41
+
+type promptResult struct {
+	text string
+	ok   bool
+}
+
+// PromptModal is a modal that asks the user for a line of text or a confirmation.
+// It's drawn on top of the current view, which makes it usable from both the login and main views.
+type PromptModal struct {
+	*mauview.Form
+
+	background mauview.Component
+	container  *mauview.Centerer
+
+	text     *mauview.TextView
+	input    *mauview.InputField
+	okButton *mauview.Button
+	cancel   *mauview.Button
+
+	result chan promptResult
+}
+
+func NewPromptModal(background mauview.Component, title, text string, withInput, mask bool) *PromptModal {
+	prompt := &PromptModal{
+		Form:       mauview.NewForm(),
+		background: background,
+
+		text:     mauview.NewTextView().SetWordWrap(true).SetText(text),
+		okButton: mauview.NewButton("OK"),
+		cancel:   mauview.NewButton("Cancel"),
+
+		result: make(chan promptResult, 1),
+	}
+	textHeight := 1
+	for _, line := range strings.Split(text, "\n") {
+		textHeight += runewidth.StringWidth(line)/PromptTextWidth + 1
+	}
+	rows := []int{textHeight, 1, 1, 1}
+	if withInput {
+		prompt.input = mauview.NewInputField()
+		if mask {
+			prompt.input.SetMaskCharacter('*')
+		}
+		rows = []int{textHeight, 1, 1, 1, 1, 1}
+	}
+	prompt.okButton.SetOnClick(prompt.Submit).SetBackgroundColor(tcell.ColorDarkCyan)
+	prompt.cancel.SetOnClick(prompt.Cancel).SetBackgroundColor(tcell.ColorDarkCyan)
+
+	prompt.SetColumns([]int{1, 20, 1, 20, 1})
+	prompt.SetRows(rows)
+	prompt.AddComponent(prompt.text, 1, 0, 3, 1)
+	buttonRow := 2
+	if withInput {
+		prompt.AddFormItem(prompt.input, 1, 2, 3, 1)
+		buttonRow = 4
+	}
+	prompt.AddFormItem(prompt.okButton, 3, buttonRow, 1, 1).
+		AddFormItem(prompt.cancel, 1, buttonRow, 1, 1)
+	prompt.FocusNextItem()
+
+	height := 2
+	for _, row := range rows {
+		height += row
+	}
+	prompt.container = mauview.Center(mauview.NewBox(prompt.Form).SetTitle(title), 45, height)
+	prompt.container.SetAlwaysFocusChild(true)
+	return prompt
+}
+
+// Submit closes the prompt and returns the entered text.
+func (prompt *PromptModal) Submit() {
+	var text string
+	if prompt.input != nil {
+		text = prompt.input.GetText()
+	}
+	prompt.sendResult(promptResult{text, true})
+}
+
+// Cancel closes the prompt without returning any text.
+func (prompt *PromptModal) Cancel() {
+	prompt.sendResult(promptResult{"", false})
+}
+
+func (prompt *PromptModal) sendResult(result promptResult) {
+	select {
+	case prompt.result <- result:
+	default:
+		// The prompt has already been submitted or cancelled.
+	}
+}
+
+func (prompt *PromptModal) Draw(screen mauview.Screen) {
+	if prompt.background != nil {
+		prompt.background.Draw(screen)
+	}
+	prompt.container.Draw(screen)
+}
+
+func (prompt *PromptModal) OnKeyEvent(event mauview.KeyEvent) bool {
+	if event.Key() == tcell.KeyEsc {
+		prompt.Cancel()
+		return true
+	}
+	return prompt.container.OnKeyEvent(event)
+}
+
+func (prompt *PromptModal) OnMouseEvent(event mauview.MouseEvent) bool {
+	return prompt.container.OnMouseEvent(event)
+}
+
+func (prompt *PromptModal) OnPasteEvent(event mauview.PasteEvent) bool {
+	return prompt.container.OnPasteEvent(event)
+}
+
+func (prompt *PromptModal) Focus() {
+	prompt.container.Focus()
+}
+
+func (prompt *PromptModal) Blur() {
+	prompt.container.Blur()
+}
+
+// showPrompt shows a prompt on top of the current view and blocks until the user submits or cancels it.
+// It must not be called from the UI event loop, the prompt is shown and hidden through QueueUpdate.
+func (ui *GomuksUI) showPrompt(title, text string, withInput, mask bool) promptResult {
+	prompt := NewPromptModal(nil, title, text, withInput, mask)
+	ui.app.QueueUpdate(func() {
+		prompt.background = ui.app.Root
+		ui.app.Root = prompt
+		prompt.Focus()
+		ui.Render()
+	})
+	result := <-prompt.result
+	ui.app.QueueUpdate(func() {
+		if ui.app.Root == prompt {
+			ui.app.Root = prompt.background
+			if focusable, ok := prompt.background.(mauview.Focusable); ok {
+				focusable.Focus()
+			}
+		}
+		ui.Render()
+	})
+	return result
+}
+
+// Prompt asks the user to enter a line of text. See ifc.UIAPrompter.
+func (ui *GomuksUI) Prompt(title, text string, mask bool) (string, bool) {
+	result := ui.showPrompt(title, text, true, mask)
+	return result.text, result.ok
+}
+
+// Confirm asks the user to confirm the given text. See ifc.UIAPrompter.
+func (ui *GomuksUI) Confirm(title, text string) bool {
+	return ui.showPrompt(title, text, false, false).ok
+}
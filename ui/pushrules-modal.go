@@ -0,0 +1,275 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/matrix/pushrules"
+	"github.com/kennetanti/gomuks/ui/widget"
+)
+
+// pushRuleEntry is a line in the push rule list, either a section header or a rule.
+type pushRuleEntry struct {
+	header string
+	rule   *pushrules.PushRule
+}
+
+// PushRulesModal lists all push rules of the account and allows enabling and disabling them.
+type PushRulesModal struct {
+	mauview.Component
+
+	container *mauview.Box
+
+	entries  []pushRuleEntry
+	selected int
+	offset   int
+	height   int
+	status   string
+
+	matrix ifc.MatrixContainer
+	parent *MainView
+}
+
+func NewPushRulesModal(parent *MainView, matrix ifc.MatrixContainer) *PushRulesModal {
+	modal := &PushRulesModal{
+		matrix: matrix,
+		parent: parent,
+		status: "Up/Down to move, Space to toggle, Esc to close",
+	}
+	modal.container = mauview.NewBox(&pushRuleList{modal}).
+		SetBorder(true).
+		SetTitle("Push rules").
+		SetBlurCaptureFunc(func() bool {
+			modal.parent.HideModal()
+			return true
+		})
+	modal.Component = mauview.Center(modal.container, 80, 24).SetAlwaysFocusChild(true)
+	modal.load()
+	return modal
+}
+
+// load fills the list from the cached push ruleset.
+func (modal *PushRulesModal) load() {
+	modal.entries = nil
+	ruleset := modal.matrix.PushRules()
+	if ruleset == nil {
+		modal.status = "Failed to load push rules"
+		return
+	}
+	sortedRules := func(ruleMap pushrules.PushRuleMap) pushrules.PushRuleArray {
+		rules := ruleMap.Unmap()
+		sort.Slice(rules, func(i, j int) bool {
+			return rules[i].RuleID < rules[j].RuleID
+		})
+		return rules
+	}
+	sections := []struct {
+		name  string
+		rules pushrules.PushRuleArray
+	}{
+		{"Override", ruleset.Override},
+		{"Content", ruleset.Content},
+		{"Room", sortedRules(ruleset.Room)},
+		{"Sender", sortedRules(ruleset.Sender)},
+		{"Underride", ruleset.Underride},
+	}
+	for _, section := range sections {
+		if len(section.rules) == 0 {
+			continue
+		}
+		modal.entries = append(modal.entries, pushRuleEntry{header: section.name})
+		for _, rule := range section.rules {
+			modal.entries = append(modal.entries, pushRuleEntry{rule: rule})
+		}
+	}
+	if modal.selected >= len(modal.entries) || modal.entries[modal.selected].rule == nil {
+		modal.selected = 0
+		modal.moveSelection(1)
+	}
+}
+
+// describeActions returns a short human-readable summary of the given push actions.
+func describeActions(actions pushrules.PushActionArray) string {
+	should := actions.Should()
+	if !should.Notify {
+		return "don't notify"
+	}
+	parts := []string{"notify"}
+	if should.Highlight {
+		parts = append(parts, "highlight")
+	}
+	if should.PlaySound {
+		parts = append(parts, "sound")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (modal *PushRulesModal) describeRule(rule *pushrules.PushRule) string {
+	name := rule.RuleID
+	switch rule.Type {
+	case pushrules.RoomRule, pushrules.OverrideRule:
+		if room := modal.matrix.GetRoom(rule.RuleID); room != nil && strings.HasPrefix(rule.RuleID, "!") {
+			name = room.GetTitle()
+		}
+	case pushrules.ContentRule:
+		if len(rule.Pattern) > 0 && rule.Pattern != rule.RuleID {
+			name = fmt.Sprintf("%s (%s)", rule.RuleID, rule.Pattern)
+		}
+	}
+	return fmt.Sprintf("%s: %s", name, describeActions(rule.Actions))
+}
+
+// moveSelection moves the selection by the given number of rules, skipping section headers.
+func (modal *PushRulesModal) moveSelection(diff int) {
+	for index := modal.selected + diff; index >= 0 && index < len(modal.entries); index += diff {
+		if modal.entries[index].rule != nil {
+			modal.selected = index
+			return
+		}
+	}
+}
+
+// toggleSelected enables or disables the selected rule.
+func (modal *PushRulesModal) toggleSelected() {
+	if modal.selected >= len(modal.entries) || modal.entries[modal.selected].rule == nil {
+		return
+	}
+	rule := modal.entries[modal.selected].rule
+	modal.status = "Saving..."
+	go func() {
+		defer debug.Recover()
+		err := modal.matrix.SetPushRuleEnabled(rule.Type, rule.RuleID, !rule.Enabled)
+		modal.parent.parent.app.QueueUpdate(func() {
+			if err != nil {
+				modal.status = fmt.Sprintf("Failed to toggle %s: %v", rule.RuleID, err)
+			} else {
+				modal.load()
+				modal.status = fmt.Sprintf("Toggled %s", rule.RuleID)
+			}
+			modal.parent.parent.Render()
+		})
+	}()
+}
+
+func (modal *PushRulesModal) OnKeyEvent(event mauview.KeyEvent) bool {
+	switch event.Key() {
+	case tcell.KeyEsc:
+		modal.parent.HideModal()
+	case tcell.KeyUp:
+		modal.moveSelection(-1)
+	case tcell.KeyDown:
+		modal.moveSelection(1)
+	case tcell.KeyPgUp:
+		for i := 0; i < modal.height/2; i++ {
+			modal.moveSelection(-1)
+		}
+	case tcell.KeyPgDn:
+		for i := 0; i < modal.height/2; i++ {
+			modal.moveSelection(1)
+		}
+	case tcell.KeyEnter:
+		modal.toggleSelected()
+	case tcell.KeyRune:
+		if event.Rune() != ' ' {
+			return false
+		}
+		modal.toggleSelected()
+	default:
+		return false
+	}
+	return true
+}
+
+func (modal *PushRulesModal) Focus() {
+	modal.container.Focus()
+}
+
+func (modal *PushRulesModal) Blur() {
+	modal.container.Blur()
+}
+
+// pushRuleList draws the entries of a PushRulesModal inside the modal box.
+type pushRuleList struct {
+	modal *PushRulesModal
+}
+
+func (list *pushRuleList) Draw(screen mauview.Screen) {
+	modal := list.modal
+	width, height := screen.Size()
+	screen.Clear()
+	// The last row is reserved for the status line.
+	modal.height = height - 1
+	if modal.selected < modal.offset {
+		modal.offset = modal.selected
+	} else if modal.selected >= modal.offset+modal.height {
+		modal.offset = modal.selected - modal.height + 1
+	}
+	for y := 0; y < modal.height && modal.offset+y < len(modal.entries); y++ {
+		entry := modal.entries[modal.offset+y]
+		if entry.rule == nil {
+			widget.WriteLine(screen, mauview.AlignLeft, entry.header, 0, y, width, TagDisplayNameStyle)
+			continue
+		}
+		style := tcell.StyleDefault
+		if modal.offset+y == modal.selected {
+			style = style.Background(tcell.ColorDarkGreen)
+		}
+		checkbox := "[ ] "
+		if entry.rule.Enabled {
+			checkbox = "[x] "
+		}
+		widget.WriteLinePadded(screen, mauview.AlignLeft, checkbox+modal.describeRule(entry.rule), 0, y, width, style)
+	}
+	widget.WriteLine(screen, mauview.AlignLeft, modal.status, 0, height-1, width, tcell.StyleDefault.Foreground(tcell.ColorGray))
+}
+
+func (list *pushRuleList) OnKeyEvent(event mauview.KeyEvent) bool {
+	return list.modal.OnKeyEvent(event)
+}
+
+func (list *pushRuleList) OnPasteEvent(event mauview.PasteEvent) bool {
+	return false
+}
+
+func (list *pushRuleList) OnMouseEvent(event mauview.MouseEvent) bool {
+	modal := list.modal
+	_, y := event.Position()
+	index := modal.offset + y
+	switch event.Buttons() {
+	case tcell.WheelUp:
+		modal.moveSelection(-1)
+	case tcell.WheelDown:
+		modal.moveSelection(1)
+	case tcell.Button1:
+		if y >= modal.height || index >= len(modal.entries) || modal.entries[index].rule == nil {
+			return false
+		}
+		modal.selected = index
+		modal.toggleSelected()
+	default:
+		return false
+	}
+	return true
+}
@@ -36,6 +36,8 @@ import (
 	"github.com/kennetanti/gomuks/config"
 	"github.com/kennetanti/gomuks/interface"
 	"github.com/kennetanti/gomuks/lib/util"
+	"github.com/kennetanti/gomuks/matrix/pills"
+	"github.com/kennetanti/gomuks/matrix/relations"
 	"github.com/kennetanti/gomuks/matrix/rooms"
 	"github.com/kennetanti/gomuks/ui/messages"
 	"github.com/kennetanti/gomuks/ui/widget"
@@ -43,6 +45,7 @@ import (
 
 type RoomView struct {
 	topic    *mauview.TextView
+	pinned   *mauview.TextField
 	content  *MessageView
 	status   *mauview.TextField
 	userList *MemberList
@@ -51,18 +54,34 @@ type RoomView struct {
 	Room     *rooms.Room
 
 	topicScreen    *mauview.ProxyScreen
+	pinnedScreen   *mauview.ProxyScreen
 	contentScreen  *mauview.ProxyScreen
 	statusScreen   *mauview.ProxyScreen
 	inputScreen    *mauview.ProxyScreen
 	ulBorderScreen *mauview.ProxyScreen
 	ulScreen       *mauview.ProxyScreen
+	threadScreen   *mauview.ProxyScreen
+
+	// The thread side pane, or nil if no thread is open.
+	thread *ThreadView
 
 	prevScreen mauview.Screen
 
 	parent *MainView
 	config *config.Config
+	// The account this room view sends events and fetches history through.
+	matrix ifc.MatrixContainer
 
+	// The user IDs of the members who are currently typing, excluding the current user.
 	typing []string
+	// The pills inserted into the input by tab-completion since the last message was sent.
+	mentions []pills.Pill
+
+	normal normalMode
+	// The message the next message is sent as a reply to, selected in normal mode.
+	replyTo messages.UIMessage
+	// The message the input replaces when sent, selected in normal mode.
+	editing messages.UIMessage
 
 	completions struct {
 		list      []string
@@ -74,23 +93,27 @@ type RoomView struct {
 func NewRoomView(parent *MainView, room *rooms.Room) *RoomView {
 	view := &RoomView{
 		topic:    mauview.NewTextView(),
+		pinned:   mauview.NewTextField(),
 		status:   mauview.NewTextField(),
-		userList: NewMemberList(),
 		ulBorder: widget.NewBorder(),
 		input:    mauview.NewInputArea(),
 		Room:     room,
 
 		topicScreen:    &mauview.ProxyScreen{OffsetX: 0, OffsetY: 0, Height: TopicBarHeight},
+		pinnedScreen:   &mauview.ProxyScreen{OffsetX: 0, OffsetY: TopicBarHeight, Height: PinnedBarHeight},
 		contentScreen:  &mauview.ProxyScreen{OffsetX: 0, OffsetY: StatusBarHeight},
 		statusScreen:   &mauview.ProxyScreen{OffsetX: 0, Height: StatusBarHeight},
 		inputScreen:    &mauview.ProxyScreen{OffsetX: 0},
 		ulBorderScreen: &mauview.ProxyScreen{OffsetY: StatusBarHeight, Width: UserListBorderWidth},
 		ulScreen:       &mauview.ProxyScreen{OffsetY: StatusBarHeight, Width: UserListWidth},
+		threadScreen:   &mauview.ProxyScreen{OffsetY: StatusBarHeight},
 
 		parent: parent,
 		config: parent.config,
+		matrix: parent.matrixFor(room),
 	}
 	view.content = NewMessageView(view)
+	view.userList = NewMemberList(view)
 
 	view.input.
 		SetBackgroundColor(tcell.ColorDefault).
@@ -104,6 +127,9 @@ func NewRoomView(parent *MainView, room *rooms.Room) *RoomView {
 		SetBackgroundColor(tcell.ColorDarkGreen)
 
 	view.status.SetBackgroundColor(tcell.ColorDimGray)
+	view.pinned.
+		SetTextColor(tcell.ColorWhite).
+		SetBackgroundColor(tcell.ColorDarkBlue)
 
 	return view
 }
@@ -129,35 +155,93 @@ func (view *RoomView) GetInputText() string {
 }
 
 func (view *RoomView) Focus() {
-	view.input.Focus()
+	if view.thread != nil && view.thread.focused {
+		view.thread.Focus()
+	} else if !view.normal.active {
+		view.input.Focus()
+	}
 }
 
 func (view *RoomView) Blur() {
 	view.input.Blur()
+	if view.thread != nil {
+		view.thread.input.Blur()
+	}
 }
 
-func (view *RoomView) GetStatus() string {
-	var buf strings.Builder
+// statusSegment is a part of the status bar text drawn in a single color.
+type statusSegment struct {
+	text  string
+	color tcell.Color
+}
+
+// getStatusSegments returns the parts of the status bar text. Typing users are colored with their hash colors.
+func (view *RoomView) getStatusSegments() []statusSegment {
+	var segments []statusSegment
+	separator := statusSegment{" - ", tcell.ColorDefault}
+
+	if status := view.getNormalModeStatus(); len(status) > 0 {
+		segments = append(segments, statusSegment{status, tcell.ColorYellow}, separator)
+	}
 
 	if len(view.completions.list) > 0 {
 		if view.completions.textCache != view.input.GetText() || view.completions.time.Add(10 * time.Second).Before(time.Now()) {
 			view.completions.list = []string{}
 		} else {
-			buf.WriteString(strings.Join(view.completions.list, ", "))
-			buf.WriteString(" - ")
+			segments = append(segments, statusSegment{strings.Join(view.completions.list, ", "), tcell.ColorDefault}, separator)
 		}
 	}
 
-	if len(view.typing) == 1 {
-		buf.WriteString("Typing: " + view.typing[0])
-		buf.WriteString(" - ")
-	} else if len(view.typing) > 1 {
-		_, _ = fmt.Fprintf(&buf,
-			"Typing: %s and %s - ",
-			strings.Join(view.typing[:len(view.typing)-1], ", "), view.typing[len(view.typing)-1])
+	typing := view.typing
+	if len(typing) > MaxTypingNamesShown {
+		segments = append(segments, statusSegment{"Several people are typing...", tcell.ColorDefault}, separator)
+	} else if len(typing) > 0 {
+		segments = append(segments, statusSegment{"Typing: ", tcell.ColorDefault})
+		for index, userID := range typing {
+			if index == len(typing)-1 && index > 0 {
+				segments = append(segments, statusSegment{" and ", tcell.ColorDefault})
+			} else if index > 0 {
+				segments = append(segments, statusSegment{", ", tcell.ColorDefault})
+			}
+			segments = append(segments, statusSegment{view.getDisplayname(userID), widget.GetHashColor(userID)})
+		}
+		segments = append(segments, separator)
+	}
+
+	if partner := view.Room.GetDirectChatPartner(); len(partner) > 0 && len(typing) == 0 {
+		if presence := view.matrix.GetPresence(partner); presence != nil {
+			segments = append(segments, statusSegment{FormatPresence(presence), tcell.ColorDefault})
+		}
+	}
+
+	if len(segments) > 0 && segments[len(segments)-1] == separator {
+		segments = segments[:len(segments)-1]
 	}
+	return segments
+}
+
+// GetStatus returns the text of the status bar.
+func (view *RoomView) GetStatus() string {
+	var buf strings.Builder
+	for _, segment := range view.getStatusSegments() {
+		buf.WriteString(segment.text)
+	}
+	return buf.String()
+}
 
-	return strings.TrimSuffix(buf.String(), " - ")
+func (view *RoomView) drawStatus(screen mauview.Screen) {
+	view.status.SetText("")
+	view.status.Draw(screen)
+	width, _ := screen.Size()
+	style := tcell.StyleDefault.Background(tcell.ColorDimGray)
+	x := 0
+	for _, segment := range view.getStatusSegments() {
+		if x >= width {
+			break
+		}
+		widget.WriteLine(screen, mauview.AlignLeft, segment.text, x, 0, width-x, style.Foreground(segment.color))
+		x += runewidth.StringWidth(segment.text)
+	}
 }
 
 // Constants defining the size of the room view grid.
@@ -167,6 +251,7 @@ const (
 	StaticHorizontalSpace = UserListBorderWidth + UserListWidth
 
 	TopicBarHeight  = 1
+	PinnedBarHeight = 1
 	StatusBarHeight = 1
 
 	MaxInputHeight = 5
@@ -180,11 +265,13 @@ func (view *RoomView) Draw(screen mauview.Screen) {
 
 	if view.prevScreen != screen {
 		view.topicScreen.Parent = screen
+		view.pinnedScreen.Parent = screen
 		view.contentScreen.Parent = screen
 		view.statusScreen.Parent = screen
 		view.inputScreen.Parent = screen
 		view.ulBorderScreen.Parent = screen
 		view.ulScreen.Parent = screen
+		view.threadScreen.Parent = screen
 		view.prevScreen = screen
 	}
 
@@ -195,13 +282,25 @@ func (view *RoomView) Draw(screen mauview.Screen) {
 	} else if inputHeight < 1 {
 		inputHeight = 1
 	}
-	contentHeight := height - inputHeight - TopicBarHeight - StatusBarHeight
+	pinnedCount := len(view.Room.GetPinnedEvents())
+	pinnedHeight := 0
+	if pinnedCount > 0 {
+		pinnedHeight = PinnedBarHeight
+	}
+	contentHeight := height - inputHeight - TopicBarHeight - pinnedHeight - StatusBarHeight
 	contentWidth := width - StaticHorizontalSpace
 	if view.config.Preferences.HideUserList {
 		contentWidth = width
 	}
+	threadWidth := 0
+	if view.thread != nil {
+		threadWidth = contentWidth * 2 / 5
+		contentWidth -= threadWidth
+	}
 
 	view.topicScreen.Width = width
+	view.pinnedScreen.Width = width
+	view.contentScreen.OffsetY = TopicBarHeight + pinnedHeight
 	view.contentScreen.Width = contentWidth
 	view.contentScreen.Height = contentHeight
 	view.statusScreen.OffsetY = view.contentScreen.YEnd()
@@ -209,17 +308,33 @@ func (view *RoomView) Draw(screen mauview.Screen) {
 	view.inputScreen.Width = width
 	view.inputScreen.OffsetY = view.statusScreen.YEnd()
 	view.inputScreen.Height = inputHeight
-	view.ulBorderScreen.OffsetX = view.contentScreen.XEnd()
+	view.threadScreen.OffsetX = view.contentScreen.XEnd()
+	view.threadScreen.OffsetY = view.contentScreen.OffsetY
+	view.threadScreen.Width = threadWidth
+	view.threadScreen.Height = contentHeight
+	view.ulBorderScreen.OffsetX = view.threadScreen.XEnd()
+	view.ulBorderScreen.OffsetY = view.contentScreen.OffsetY
 	view.ulBorderScreen.Height = contentHeight
 	view.ulScreen.OffsetX = view.ulBorderScreen.XEnd()
+	view.ulScreen.OffsetY = view.contentScreen.OffsetY
 	view.ulScreen.Height = contentHeight
 
 	// Draw everything
 	view.topic.Draw(view.topicScreen)
+	if pinnedCount > 0 {
+		if pinnedCount == 1 {
+			view.pinned.SetText("1 pinned message (click or /pins to show)")
+		} else {
+			view.pinned.SetText(fmt.Sprintf("%d pinned messages (click or /pins to show)", pinnedCount))
+		}
+		view.pinned.Draw(view.pinnedScreen)
+	}
 	view.content.Draw(view.contentScreen)
-	view.status.SetText(view.GetStatus())
-	view.status.Draw(view.statusScreen)
-	view.input.Draw(view.inputScreen)
+	if view.thread != nil {
+		view.thread.Draw(view.threadScreen)
+	}
+	view.drawStatus(view.statusScreen)
+	drawInputWithPills(view.input, view.inputScreen, view.mentions)
 	if !view.config.Preferences.HideUserList {
 		view.ulBorder.Draw(view.ulBorderScreen)
 		view.userList.Draw(view.ulScreen)
@@ -227,56 +342,121 @@ func (view *RoomView) Draw(screen mauview.Screen) {
 }
 
 func (view *RoomView) OnKeyEvent(event mauview.KeyEvent) bool {
-	msgView := view.MessageView()
-	switch event.Key() {
-	case tcell.KeyPgUp:
-		if msgView.IsAtTop() {
-			go view.parent.LoadHistory(view.Room.ID)
-		}
-		msgView.AddScrollOffset(+msgView.Height() / 2)
+	if view.thread != nil && view.thread.focused {
+		return view.thread.OnKeyEvent(event)
+	}
+	if view.normal.active && view.handleNormalModeKey(event) {
 		return true
-	case tcell.KeyPgDn:
-		msgView.AddScrollOffset(-msgView.Height() / 2)
+	}
+	action := view.config.Keybindings.Action(config.KeyContextRoom, eventChord(event))
+	if len(action) > 0 && view.runKeyAction(action) {
+		return true
+	} else if view.normal.active {
+		// Keys that aren't bound to anything aren't typed into the input in normal mode.
 		return true
-	case tcell.KeyEnter:
-		if event.Modifiers()&tcell.ModShift == 0 && event.Modifiers()&tcell.ModCtrl == 0 {
-			view.InputSubmit(view.input.GetText())
-			return true
-		}
 	}
 	return view.input.OnKeyEvent(event)
 }
 
 func (view *RoomView) OnPasteEvent(event mauview.PasteEvent) bool {
+	if view.thread != nil && view.thread.focused {
+		return view.thread.OnPasteEvent(event)
+	}
 	return view.input.OnPasteEvent(event)
 }
 
 func (view *RoomView) OnMouseEvent(event mauview.MouseEvent) bool {
 	switch {
+	case view.thread != nil && view.threadScreen.IsInArea(event.Position()):
+		if event.Buttons() == tcell.Button1 && !view.thread.focused {
+			view.input.Blur()
+			view.thread.Focus()
+		}
+		return view.thread.OnMouseEvent(view.threadScreen.OffsetMouseEvent(event))
 	case view.contentScreen.IsInArea(event.Position()):
 		return view.content.OnMouseEvent(view.contentScreen.OffsetMouseEvent(event))
 	case view.topicScreen.IsInArea(event.Position()):
 		return view.topic.OnMouseEvent(view.topicScreen.OffsetMouseEvent(event))
+	case view.pinnedScreen.IsInArea(event.Position()) && view.contentScreen.OffsetY > view.pinnedScreen.OffsetY:
+		if event.Buttons() == tcell.Button1 {
+			view.ShowPinnedMessages()
+			return true
+		}
 	case view.inputScreen.IsInArea(event.Position()):
+		if event.Buttons() == tcell.Button1 && view.thread != nil && view.thread.focused {
+			view.thread.Blur()
+			view.input.Focus()
+		}
 		return view.input.OnMouseEvent(view.inputScreen.OffsetMouseEvent(event))
 	}
 	return false
 }
 
+// OpenThread opens the side pane that shows the thread of the given root event and focuses its input.
+func (view *RoomView) OpenThread(rootID string) {
+	if view.thread != nil {
+		view.thread.Blur()
+	}
+	view.input.Blur()
+	view.thread = NewThreadView(view, rootID)
+	view.thread.Focus()
+}
+
+// CloseThread closes the thread side pane and moves focus back to the main input.
+func (view *RoomView) CloseThread() {
+	if view.thread == nil {
+		return
+	}
+	view.thread.Blur()
+	view.thread = nil
+	view.input.Focus()
+}
+
+// ShowPinnedMessages opens a modal that lists the messages pinned in the room.
+func (view *RoomView) ShowPinnedMessages() {
+	view.parent.ShowModal(NewPinnedMessagesModal(view))
+}
+
 func (view *RoomView) SetCompletions(completions []string) {
 	view.completions.list = completions
 	view.completions.textCache = view.input.GetText()
 	view.completions.time = time.Now()
 }
 
+// MaxTypingNamesShown is the maximum number of typing users whose names are shown in the status bar.
+// If more users are typing, the names are collapsed into "Several people are typing".
+const MaxTypingNamesShown = 3
+
+// TypingMarker is the character shown next to typing users in the member list and rooms with typing users in the room list.
+const TypingMarker = '✎'
+
+// SetTyping sets the list of user IDs that are currently typing in the room.
 func (view *RoomView) SetTyping(users []string) {
-	for index, user := range users {
-		member := view.Room.GetMember(user)
-		if member != nil {
-			users[index] = member.Displayname
+	typing := make([]string, 0, len(users))
+	for _, userID := range users {
+		if userID != view.Room.SessionUserID {
+			typing = append(typing, userID)
 		}
 	}
-	view.typing = users
+	view.typing = typing
+}
+
+// IsTyping returns whether or not the given user is currently typing in the room.
+func (view *RoomView) IsTyping(userID string) bool {
+	for _, typingUserID := range view.typing {
+		if typingUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// getDisplayname returns the displayname of the given user in the room, or the user ID if the member is unknown.
+func (view *RoomView) getDisplayname(userID string) string {
+	if member := view.Room.GetMember(userID); member != nil && len(member.Displayname) > 0 {
+		return member.Displayname
+	}
+	return userID
 }
 
 type completion struct {
@@ -304,10 +484,10 @@ func (view *RoomView) autocompleteRoom(existingText string) (completions []compl
 		alias := room.Room.GetCanonicalAlias()
 		if alias == existingText {
 			// Exact match, return that.
-			return []completion{{alias, room.Room.ID}}
+			return []completion{{alias, alias}}
 		}
 		if strings.HasPrefix(alias, existingText) {
-			completions = append(completions, completion{alias, room.Room.ID})
+			completions = append(completions, completion{alias, alias})
 			continue
 		}
 	}
@@ -342,7 +522,9 @@ func (view *RoomView) InputTabComplete(text string, cursorOffset int) {
 
 	if len(completions) == 1 {
 		completion := completions[0]
-		strCompletion = fmt.Sprintf("[%s](https://matrix.to/#/%s)", completion.displayName, completion.id)
+		pill := pills.Pill{Text: completion.displayName, ID: completion.id}
+		view.mentions = append(view.mentions, pill)
+		strCompletion = pill.Token()
 		if startIndex == 0 {
 			strCompletion = strCompletion + ": "
 		}
@@ -368,28 +550,82 @@ func (view *RoomView) InputTabComplete(text string, cursorOffset int) {
 }
 
 func (view *RoomView) InputSubmit(text string) {
+	mentions := view.mentions
+	view.mentions = nil
 	if len(text) == 0 {
 		return
-	} else if cmd := view.parent.cmdProcessor.ParseCommand(view, text); cmd != nil {
+	} else if cmd := view.parent.cmdProcessor.ParseCommand(view, pills.ToIDs(text, mentions)); cmd != nil {
+		// Pills in commands are replaced with the IDs they refer to, e.g. for /invite and /whois.
 		go view.parent.cmdProcessor.HandleCommand(cmd)
+	} else if view.editing != nil {
+		go view.SendEdit(view.editing, text, mentions...)
+		view.editing = nil
+	} else if view.replyTo != nil {
+		go view.SendReply(view.replyTo, mautrix.MsgText, text, mentions...)
+		view.replyTo = nil
 	} else {
-		go view.SendMessage(mautrix.MsgText, text)
+		go view.SendThreadMessage("", mautrix.MsgText, text, mentions...)
 	}
 	view.SetInputText("")
 }
 
 func (view *RoomView) SendMessage(msgtype mautrix.MessageType, text string) {
+	view.SendThreadMessage("", msgtype, text)
+}
+
+// SendThreadMessage sends a message as a reply in the thread of the given root event.
+// If the root ID is empty, the message is sent to the main timeline.
+// The tokens of the given pills in the text are sent as mentions.
+func (view *RoomView) SendThreadMessage(rootID string, msgtype mautrix.MessageType, text string, mentions ...pills.Pill) {
+	defer debug.Recover()
+	evt := view.prepareMessage(msgtype, text, mentions)
+	if len(rootID) > 0 {
+		relations.SetThreadRoot(&evt.Content, rootID)
+	}
+	view.sendPreparedMessage(evt)
+}
+
+// SendReply sends a message as a reply to the given message.
+func (view *RoomView) SendReply(replyTo messages.UIMessage, msgtype mautrix.MessageType, text string, mentions ...pills.Pill) {
+	defer debug.Recover()
+	evt := view.prepareMessage(msgtype, text, mentions)
+	replyToEvt, err := view.matrix.GetEvent(view.Room, replyTo.ID())
+	if err != nil {
+		view.AddServiceMessage(fmt.Sprintf("Failed to get the message to reply to: %v", err))
+		view.parent.parent.Render()
+		return
+	}
+	evt.Content.SetReply(replyToEvt)
+	view.sendPreparedMessage(evt)
+}
+
+// SendEdit sends an edit that replaces the content of the given message with the given text.
+func (view *RoomView) SendEdit(original messages.UIMessage, text string, mentions ...pills.Pill) {
 	defer debug.Recover()
+	evt := view.prepareMessage(original.Type(), text, mentions)
+	relations.SetEditTarget(&evt.Content, original.ID())
+	view.sendPreparedMessage(evt)
+}
+
+// prepareMessage creates the local echo of a message with the given markdown text.
+func (view *RoomView) prepareMessage(msgtype mautrix.MessageType, text string, mentions []pills.Pill) *mautrix.Event {
 	debug.Print("Sending message", msgtype, text, "to", view.Room.ID)
 	if !view.config.Preferences.DisableEmojis {
 		text = emoji.Sprint(text)
 	}
-	evt := view.parent.matrix.PrepareMarkdownMessage(view.Room.ID, msgtype, text)
+	return view.matrix.PrepareMarkdownMessage(view.Room.ID, msgtype, text, mentions...)
+}
+
+// sendPreparedMessage shows the local echo of the given event and sends it.
+func (view *RoomView) sendPreparedMessage(evt *mautrix.Event) {
 	msg := view.ParseEvent(evt)
 	view.AddMessage(msg)
-	eventID, err := view.parent.matrix.SendEvent(evt)
+	eventID, err := view.matrix.SendEvent(evt)
 	if err != nil {
 		msg.SetState(mautrix.EventStateSendFail)
+		if view.thread != nil {
+			view.thread.SetState(msg, mautrix.EventStateSendFail)
+		}
 		// Show shorter version if available
 		if httpErr, ok := err.(mautrix.HTTPError); ok {
 			err = httpErr
@@ -405,6 +641,35 @@ func (view *RoomView) SendMessage(msgtype mautrix.MessageType, text string) {
 	}
 }
 
+// VotePoll sends a vote for the given answers in the given poll.
+func (view *RoomView) VotePoll(poll *messages.PollMessage, answerIDs []string) {
+	defer debug.Recover()
+	err := view.matrix.SendPollResponse(view.Room.ID, poll.ID(), poll.Poll.Unstable, answerIDs)
+	if err != nil {
+		view.AddServiceMessage(fmt.Sprintf("Failed to vote: %v", err))
+		view.parent.parent.Render()
+	}
+}
+
+// EndPoll ends the given poll, which must have been started by the current user.
+func (view *RoomView) EndPoll(poll *messages.PollMessage) {
+	defer debug.Recover()
+	err := view.matrix.EndPoll(view.Room.ID, poll.ID(), poll.Poll.Unstable)
+	if err != nil {
+		view.AddServiceMessage(fmt.Sprintf("Failed to end poll: %v", err))
+		view.parent.parent.Render()
+	}
+}
+
+// GetPoll returns the poll with the given event ID, or the latest poll in the room if the ID is empty.
+func (view *RoomView) GetPoll(eventID string) *messages.PollMessage {
+	if len(eventID) == 0 {
+		return view.content.latestPoll()
+	}
+	poll, _ := view.content.messageIDs[eventID].(*messages.PollMessage)
+	return poll
+}
+
 func (view *RoomView) MessageView() *MessageView {
 	return view.content
 }
@@ -427,10 +692,13 @@ func (view *RoomView) AddServiceMessage(text string) {
 
 func (view *RoomView) AddMessage(message ifc.Message) {
 	view.content.AddMessage(message, AppendMessage)
+	if uiMessage, ok := message.(messages.UIMessage); ok && view.thread != nil {
+		view.thread.AddMessage(uiMessage)
+	}
 }
 
 func (view *RoomView) ParseEvent(evt *mautrix.Event) ifc.Message {
-	return messages.ParseEvent(view.parent.matrix, view.parent, view.Room, evt)
+	return messages.ParseEvent(view.matrix, view.parent, view.Room, evt)
 }
 
 func (view *RoomView) GetEvent(eventID string) ifc.Message {
@@ -56,10 +56,38 @@ func (or *OrderedRoom) Draw(roomList *RoomList, screen mauview.Screen, x, y, lin
 
 	unreadCount := or.UnreadCount()
 
-	widget.WriteLinePadded(screen, mauview.AlignLeft, or.GetTitle(), x, y, lineWidth, style)
+	if roomList.parent != nil && len(roomList.parent.gmx.Accounts()) > 1 {
+		// Show which account the room belongs to when there are multiple accounts.
+		badge := strings.TrimPrefix(or.SessionUserID, "@")
+		if len(badge) > 0 {
+			badge = string([]rune(badge)[0])
+		}
+		widget.WriteLinePadded(screen, mauview.AlignLeft, badge, x, y, 2, style.Foreground(widget.GetHashColor(or.SessionUserID)))
+		x += 2
+		lineWidth -= 2
+	}
+
+	// The presence column is reserved for every room so that the titles stay aligned.
+	widget.WriteLinePadded(screen, mauview.AlignLeft, "", x, y, 2, style)
+	if partner := or.GetDirectChatPartner(); len(partner) > 0 && roomList.parent != nil {
+		if presence := roomList.parent.matrixFor(or.Room).GetPresence(partner); presence != nil {
+			screen.SetCell(x, y, style.Foreground(PresenceColor(presence)), PresenceDot)
+		}
+	}
+	x += 2
+	lineWidth -= 2
+
+	typing := false
+	if roomList.parent != nil {
+		if roomView, ok := roomList.parent.rooms[or.ID]; ok && roomView != roomList.parent.currentRoom && len(roomView.typing) > 0 {
+			// Show that someone is typing in rooms other than the current one.
+			typing = true
+		}
+	}
 
+	unreadMessageCount := ""
 	if unreadCount > 0 {
-		unreadMessageCount := "99+"
+		unreadMessageCount = "99+"
 		if unreadCount < 100 {
 			unreadMessageCount = strconv.Itoa(unreadCount)
 		}
@@ -67,8 +95,26 @@ func (or *OrderedRoom) Draw(roomList *RoomList, screen mauview.Screen, x, y, lin
 			unreadMessageCount += "!"
 		}
 		unreadMessageCount = fmt.Sprintf("(%s)", unreadMessageCount)
+	}
+
+	titleWidth := lineWidth
+	if typing {
+		// Reserve a column (and a space) for the typing marker so that it doesn't overwrite the title.
+		titleWidth -= 2
+		if len(unreadMessageCount) > 0 {
+			titleWidth -= len(unreadMessageCount) + 1
+		}
+		widget.WriteLinePadded(screen, mauview.AlignLeft, "", x+titleWidth, y, lineWidth-titleWidth, style)
+	}
+	widget.WriteLinePadded(screen, mauview.AlignLeft, or.GetTitle(), x, y, titleWidth, style)
+
+	if len(unreadMessageCount) > 0 {
 		widget.WriteLine(screen, mauview.AlignRight, unreadMessageCount, x+lineWidth-7, y, 7, style)
-		lineWidth -= len(unreadMessageCount)
+		lineWidth -= len(unreadMessageCount) + 1
+	}
+
+	if typing {
+		screen.SetCell(x+lineWidth-1, y, style, TypingMarker)
 	}
 }
 
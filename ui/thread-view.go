@@ -0,0 +1,194 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"github.com/tulir/mautrix-go"
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/config"
+	"github.com/kennetanti/gomuks/interface"
+	"github.com/kennetanti/gomuks/ui/messages"
+	"github.com/kennetanti/gomuks/ui/widget"
+)
+
+const (
+	ThreadBorderWidth  = 1
+	ThreadHeaderHeight = 1
+)
+
+// ThreadView is a side pane next to the room timeline that shows the replies in a single thread.
+type ThreadView struct {
+	parent *RoomView
+	rootID string
+
+	header  *mauview.TextField
+	content *MessageView
+	input   *mauview.InputArea
+	border  *widget.Border
+
+	borderScreen  *mauview.ProxyScreen
+	headerScreen  *mauview.ProxyScreen
+	contentScreen *mauview.ProxyScreen
+	inputScreen   *mauview.ProxyScreen
+
+	prevScreen mauview.Screen
+
+	focused bool
+}
+
+func NewThreadView(parent *RoomView, rootID string) *ThreadView {
+	view := &ThreadView{
+		parent: parent,
+		rootID: rootID,
+
+		header: mauview.NewTextField(),
+		input:  mauview.NewInputArea(),
+		border: widget.NewBorder(),
+
+		borderScreen:  &mauview.ProxyScreen{OffsetX: 0, OffsetY: 0, Width: ThreadBorderWidth},
+		headerScreen:  &mauview.ProxyScreen{OffsetX: ThreadBorderWidth, OffsetY: 0, Height: ThreadHeaderHeight},
+		contentScreen: &mauview.ProxyScreen{OffsetX: ThreadBorderWidth, OffsetY: ThreadHeaderHeight},
+		inputScreen:   &mauview.ProxyScreen{OffsetX: ThreadBorderWidth},
+	}
+	view.content = NewMessageView(parent)
+	view.content.threadMode = true
+
+	view.header.
+		SetText("Thread (Esc to close)").
+		SetTextColor(tcell.ColorWhite).
+		SetBackgroundColor(tcell.ColorDarkBlue)
+
+	view.input.
+		SetBackgroundColor(tcell.ColorDefault).
+		SetPlaceholder("Reply in thread...").
+		SetPlaceholderTextColor(tcell.ColorGray)
+
+	// The messages are cloned, as the pane is narrower than the main timeline and needs its own buffers.
+	// Changes to the originals are forwarded to the clones by RoomView using AddMessage and SetState.
+	if root, ok := parent.content.messageIDs[rootID]; ok {
+		view.addClone(root)
+	}
+	for _, reply := range parent.content.ThreadReplies(rootID) {
+		view.addClone(reply)
+	}
+	return view
+}
+
+func (view *ThreadView) addClone(message messages.UIMessage) {
+	clone := message.Clone()
+	if clone.ID() == view.rootID {
+		clone.SetThreadSummary(0, nil)
+	}
+	view.content.AddMessage(clone, AppendMessage)
+}
+
+// findClone returns the clone of the given message in the thread pane, or nil if the message isn't shown.
+func (view *ThreadView) findClone(message ifc.Message) messages.UIMessage {
+	if clone, ok := view.content.messageIDs[message.ID()]; ok && len(message.ID()) > 0 {
+		return clone
+	} else if clone, ok = view.content.messageIDs[message.TxnID()]; ok && len(message.TxnID()) > 0 {
+		return clone
+	}
+	return nil
+}
+
+// AddMessage adds the given message to the thread if it's a reply in the thread,
+// or replaces the clone of the message if it's already shown in the thread.
+func (view *ThreadView) AddMessage(message messages.UIMessage) {
+	if message.ThreadRoot() != view.rootID && view.findClone(message) == nil {
+		return
+	}
+	view.addClone(message)
+}
+
+// SetState changes the state of the clone of the given message if it's shown in the thread.
+func (view *ThreadView) SetState(message ifc.Message, state mautrix.OutgoingEventState) {
+	if clone := view.findClone(message); clone != nil {
+		clone.SetState(state)
+	}
+}
+
+func (view *ThreadView) Focus() {
+	view.focused = true
+	view.input.Focus()
+}
+
+func (view *ThreadView) Blur() {
+	view.focused = false
+	view.input.Blur()
+}
+
+func (view *ThreadView) Draw(screen mauview.Screen) {
+	width, height := screen.Size()
+	if width <= ThreadBorderWidth || height <= ThreadHeaderHeight {
+		return
+	}
+
+	if view.prevScreen != screen {
+		view.borderScreen.Parent = screen
+		view.headerScreen.Parent = screen
+		view.contentScreen.Parent = screen
+		view.inputScreen.Parent = screen
+		view.prevScreen = screen
+	}
+
+	paneWidth := width - ThreadBorderWidth
+	view.input.PrepareDraw(paneWidth)
+	inputHeight := view.input.GetTextHeight()
+	if inputHeight > MaxInputHeight {
+		inputHeight = MaxInputHeight
+	} else if inputHeight < 1 {
+		inputHeight = 1
+	}
+
+	view.borderScreen.Height = height
+	view.headerScreen.Width = paneWidth
+	view.contentScreen.Width = paneWidth
+	view.contentScreen.Height = height - ThreadHeaderHeight - inputHeight
+	view.inputScreen.Width = paneWidth
+	view.inputScreen.OffsetY = view.contentScreen.YEnd()
+	view.inputScreen.Height = inputHeight
+
+	view.border.Draw(view.borderScreen)
+	view.header.Draw(view.headerScreen)
+	view.content.Draw(view.contentScreen)
+	view.input.Draw(view.inputScreen)
+}
+
+func (view *ThreadView) OnKeyEvent(event mauview.KeyEvent) bool {
+	action := view.parent.config.Keybindings.Action(config.KeyContextRoom, eventChord(event))
+	if len(action) > 0 && view.runKeyAction(action) {
+		return true
+	}
+	return view.input.OnKeyEvent(event)
+}
+
+func (view *ThreadView) OnPasteEvent(event mauview.PasteEvent) bool {
+	return view.input.OnPasteEvent(event)
+}
+
+func (view *ThreadView) OnMouseEvent(event mauview.MouseEvent) bool {
+	switch {
+	case view.contentScreen.IsInArea(event.Position()):
+		return view.content.OnMouseEvent(view.contentScreen.OffsetMouseEvent(event))
+	case view.inputScreen.IsInArea(event.Position()):
+		return view.input.OnMouseEvent(view.inputScreen.OffsetMouseEvent(event))
+	}
+	return false
+}
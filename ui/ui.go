@@ -75,12 +75,21 @@ func (ui *GomuksUI) Start() error {
 
 func (ui *GomuksUI) Stop() {
 	ui.app.Stop()
+	if ui.mainView != nil {
+		close(ui.mainView.stopAutoIdle)
+		if ui.mainView.notifier != nil {
+			ui.mainView.notifier.Close()
+		}
+	}
 }
 
 func (ui *GomuksUI) Finish() {
 	if ui.app.Screen() != nil {
 		ui.app.Screen().Fini()
 	}
+	if ui.mainView != nil {
+		ui.mainView.restoreTerminalTitle()
+	}
 }
 
 func (ui *GomuksUI) Render() {
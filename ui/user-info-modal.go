@@ -0,0 +1,360 @@
+// gomuks - A terminal Matrix client written in Go.
+// Copyright (C) 2019 Tulir Asokan
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tulir/mautrix-go"
+	"github.com/tulir/mauview"
+	"github.com/tulir/tcell"
+
+	"github.com/kennetanti/gomuks/debug"
+	"github.com/kennetanti/gomuks/interface"
+)
+
+// OpPowerLevel is the power level given to users with the op action of the user info modal.
+const OpPowerLevel = 50
+
+// UserInfoModal shows the profile of a user, the rooms the current user shares with them and
+// buttons for common actions like starting a direct chat or kicking the user from the current room.
+type UserInfoModal struct {
+	*mauview.Form
+
+	container *mauview.Centerer
+
+	info   *mauview.TextView
+	status *mauview.TextField
+
+	dmButton     *mauview.Button
+	ignoreButton *mauview.Button
+	kickButton   *mauview.Button
+	banButton    *mauview.Button
+	opButton     *mauview.Button
+	closeButton  *mauview.Button
+
+	userID string
+	// The room the modal was opened from. Kick, ban and op are applied to this room.
+	room   *RoomView
+	matrix ifc.MatrixContainer
+	parent *MainView
+}
+
+func NewUserInfoModal(parent *MainView, room *RoomView, userID string) *UserInfoModal {
+	modal := &UserInfoModal{
+		Form: mauview.NewForm(),
+
+		info:   mauview.NewTextView().SetWordWrap(true).SetText("Loading..."),
+		status: mauview.NewTextField(),
+
+		dmButton:     mauview.NewButton("DM"),
+		ignoreButton: mauview.NewButton("Ignore"),
+		kickButton:   mauview.NewButton("Kick"),
+		banButton:    mauview.NewButton("Ban"),
+		opButton:     mauview.NewButton("Op"),
+		closeButton:  mauview.NewButton("Close"),
+
+		userID: userID,
+		room:   room,
+		matrix: parent.matrixFor(nil),
+		parent: parent,
+	}
+	if room != nil {
+		modal.matrix = room.matrix
+	}
+	if modal.matrix.IsIgnored(userID) {
+		modal.ignoreButton.SetText("Unignore")
+	}
+	if room != nil {
+		if modal.powerLevels().GetUserLevel(userID) >= OpPowerLevel {
+			modal.opButton.SetText("Deop")
+		}
+	}
+
+	modal.closeButton.SetOnClick(parent.HideModal)
+	modal.dmButton.SetOnClick(modal.DirectChat)
+	modal.ignoreButton.SetOnClick(modal.Ignore)
+	modal.kickButton.SetOnClick(modal.Kick)
+	modal.banButton.SetOnClick(modal.Ban)
+	modal.opButton.SetOnClick(modal.Op)
+
+	modal.SetColumns([]int{1, 8, 1, 8, 1, 8, 1, 8, 1, 8, 1, 8, 1})
+	modal.SetRows([]int{-1, 1, 1, 1, 1})
+	modal.AddComponent(modal.info, 1, 0, 11, 1).
+		AddComponent(modal.status, 1, 2, 11, 1)
+	for i, button := range []*mauview.Button{
+		modal.dmButton, modal.ignoreButton, modal.kickButton, modal.banButton, modal.opButton, modal.closeButton,
+	} {
+		button.SetBackgroundColor(tcell.ColorDarkCyan)
+		modal.AddFormItem(button, 1+i*2, 3, 1, 1)
+	}
+	modal.FocusNextItem()
+
+	modal.container = mauview.Center(mauview.NewBox(modal.Form).SetTitle(userID), 57, 22)
+	modal.container.SetAlwaysFocusChild(true)
+
+	go modal.load()
+	return modal
+}
+
+func (modal *UserInfoModal) powerLevels() *mautrix.PowerLevels {
+	if plEvent := modal.room.Room.GetStateEvent(mautrix.StatePowerLevels, ""); plEvent != nil {
+		return plEvent.Content.GetPowerLevels()
+	}
+	return &mautrix.PowerLevels{}
+}
+
+// load fetches the profile and devices of the user and fills the info text.
+func (modal *UserInfoModal) load() {
+	defer debug.Recover()
+	var buf strings.Builder
+
+	profile, err := modal.matrix.GetProfile(modal.userID)
+	if err != nil {
+		debug.Print("Failed to get profile of", modal.userID, err)
+		fmt.Fprintf(&buf, "Failed to get profile: %v\n", err)
+	} else {
+		fmt.Fprintf(&buf, "Displayname: %s\n", profile.DisplayName)
+		if len(profile.AvatarURL) > 0 {
+			fmt.Fprintf(&buf, "Avatar:      %s\n", profile.AvatarURL)
+		}
+	}
+	fmt.Fprintf(&buf, "User ID:     %s\n", modal.userID)
+	if modal.room != nil {
+		if member := modal.room.Room.GetMember(modal.userID); member != nil && len(member.Displayname) > 0 &&
+			(profile == nil || member.Displayname != profile.DisplayName) {
+			fmt.Fprintf(&buf, "Room name:   %s\n", member.Displayname)
+		}
+		fmt.Fprintf(&buf, "Power level: %d\n", modal.powerLevels().GetUserLevel(modal.userID))
+	}
+	fmt.Fprintf(&buf, "Presence:    %s\n", FormatPresence(modal.matrix.GetPresence(modal.userID)))
+
+	devices, err := modal.matrix.GetUserDevices(modal.userID)
+	if err != nil {
+		debug.Print("Failed to get devices of", modal.userID, err)
+		fmt.Fprintf(&buf, "\nFailed to get devices: %v\n", err)
+	} else {
+		fmt.Fprintf(&buf, "\nDevices (%d):\n", len(devices))
+		for _, device := range devices {
+			if len(device.DisplayName) > 0 {
+				fmt.Fprintf(&buf, "* %s (%s)\n", device.DeviceID, device.DisplayName)
+			} else {
+				fmt.Fprintf(&buf, "* %s\n", device.DeviceID)
+			}
+		}
+	}
+
+	shared := modal.matrix.GetSharedRooms(modal.userID)
+	fmt.Fprintf(&buf, "\nRooms in common (%d):\n", len(shared))
+	for _, room := range shared {
+		fmt.Fprintf(&buf, "* %s\n", room.GetTitle())
+	}
+
+	modal.queueUpdate(func() {
+		modal.info.SetText(strings.TrimSuffix(buf.String(), "\n"))
+	})
+}
+
+// queueUpdate runs the given function on the UI goroutine and redraws the screen.
+// Actions that make network requests in the background must update the modal through it.
+func (modal *UserInfoModal) queueUpdate(fn func()) {
+	modal.parent.parent.app.QueueUpdate(func() {
+		fn()
+		modal.parent.parent.Render()
+	})
+}
+
+func (modal *UserInfoModal) setStatus(color tcell.Color, text string, args ...interface{}) {
+	modal.status.SetTextColor(color).SetText(fmt.Sprintf(text, args...))
+	modal.parent.parent.Render()
+}
+
+// queueStatus sets the status text on the UI goroutine.
+func (modal *UserInfoModal) queueStatus(color tcell.Color, text string, args ...interface{}) {
+	modal.queueUpdate(func() {
+		modal.setStatus(color, text, args...)
+	})
+}
+
+// requireRoom shows an error and returns false if the modal wasn't opened from a room.
+func (modal *UserInfoModal) requireRoom() bool {
+	if modal.room == nil {
+		modal.setStatus(tcell.ColorRed, "Open the user info from a room to do that.")
+		return false
+	}
+	return true
+}
+
+// DirectChat switches to an existing direct chat with the user, or creates a new one.
+func (modal *UserInfoModal) DirectChat() {
+	if room := modal.matrix.GetDirectChat(modal.userID); room != nil {
+		if roomView, ok := modal.parent.rooms[room.ID]; ok && roomView.matrix == modal.matrix {
+			modal.parent.HideModal()
+			modal.parent.SwitchRoom(roomView.Room.Tags()[0].Tag, roomView.Room)
+			return
+		}
+	}
+	modal.setStatus(tcell.ColorDefault, "Creating direct chat...")
+	go func() {
+		defer debug.Recover()
+		room, err := modal.matrix.CreateDirectChat(modal.userID)
+		if err != nil {
+			modal.queueStatus(tcell.ColorRed, "Failed to create direct chat: %v", err)
+			return
+		}
+		modal.queueUpdate(func() {
+			modal.parent.HideModal()
+			modal.parent.AddRoom(room)
+			modal.parent.SwitchRoom(room.Tags()[0].Tag, room)
+		})
+	}()
+}
+
+// Ignore adds the user to the ignored user list after asking for confirmation,
+// or removes them from the list if they're already ignored.
+func (modal *UserInfoModal) Ignore() {
+	if modal.matrix.IsIgnored(modal.userID) {
+		go func() {
+			defer debug.Recover()
+			if err := modal.matrix.SetIgnored(modal.userID, false); err != nil {
+				modal.queueStatus(tcell.ColorRed, "Failed to unignore user: %v", err)
+			} else {
+				modal.queueUpdate(func() {
+					modal.ignoreButton.SetText("Ignore")
+					modal.setStatus(tcell.ColorGreen, "Unignored %s", modal.userID)
+				})
+			}
+		}()
+		return
+	}
+	go func() {
+		defer debug.Recover()
+		if !modal.parent.parent.Confirm("Ignore user", fmt.Sprintf("Ignore all messages from %s?", modal.userID)) {
+			return
+		}
+		if err := modal.matrix.SetIgnored(modal.userID, true); err != nil {
+			modal.queueStatus(tcell.ColorRed, "Failed to ignore user: %v", err)
+		} else {
+			modal.queueUpdate(func() {
+				modal.ignoreButton.SetText("Unignore")
+				modal.setStatus(tcell.ColorGreen, "Ignored %s", modal.userID)
+			})
+		}
+	}()
+}
+
+func (modal *UserInfoModal) askReason(action string) (string, bool) {
+	return modal.parent.parent.Prompt(strings.Title(action)+" user",
+		fmt.Sprintf("Enter a reason to %s %s from %s (optional)", action, modal.userID, modal.room.Room.GetTitle()), false)
+}
+
+// Kick kicks the user from the room the modal was opened from.
+func (modal *UserInfoModal) Kick() {
+	if !modal.requireRoom() {
+		return
+	}
+	go func() {
+		defer debug.Recover()
+		reason, ok := modal.askReason("kick")
+		if !ok {
+			return
+		}
+		_, err := modal.matrix.Client().KickUser(modal.room.Room.ID, &mautrix.ReqKickUser{Reason: reason, UserID: modal.userID})
+		if err != nil {
+			modal.queueStatus(tcell.ColorRed, "Failed to kick user: %v", err)
+		} else {
+			modal.queueStatus(tcell.ColorGreen, "Kicked %s", modal.userID)
+		}
+	}()
+}
+
+// Ban bans the user from the room the modal was opened from.
+func (modal *UserInfoModal) Ban() {
+	if !modal.requireRoom() {
+		return
+	}
+	go func() {
+		defer debug.Recover()
+		reason, ok := modal.askReason("ban")
+		if !ok {
+			return
+		}
+		_, err := modal.matrix.Client().BanUser(modal.room.Room.ID, &mautrix.ReqBanUser{Reason: reason, UserID: modal.userID})
+		if err != nil {
+			modal.queueStatus(tcell.ColorRed, "Failed to ban user: %v", err)
+		} else {
+			modal.queueStatus(tcell.ColorGreen, "Banned %s", modal.userID)
+		}
+	}()
+}
+
+// Op gives the user moderator power in the room the modal was opened from,
+// or resets their power level to the default if they already have it.
+func (modal *UserInfoModal) Op() {
+	if !modal.requireRoom() {
+		return
+	}
+	levels := modal.powerLevels()
+	level := OpPowerLevel
+	if levels.GetUserLevel(modal.userID) >= OpPowerLevel {
+		level = levels.UsersDefault
+	}
+	go func() {
+		defer debug.Recover()
+		if err := modal.matrix.SetPowerLevel(modal.room.Room.ID, modal.userID, level); err != nil {
+			modal.queueStatus(tcell.ColorRed, "Failed to change power level: %v", err)
+			return
+		}
+		modal.queueUpdate(func() {
+			if level >= OpPowerLevel {
+				modal.opButton.SetText("Deop")
+			} else {
+				modal.opButton.SetText("Op")
+			}
+			modal.setStatus(tcell.ColorGreen, "Changed power level of %s to %d", modal.userID, level)
+		})
+	}()
+}
+
+func (modal *UserInfoModal) Draw(screen mauview.Screen) {
+	modal.container.Draw(screen)
+}
+
+func (modal *UserInfoModal) OnKeyEvent(event mauview.KeyEvent) bool {
+	if event.Key() == tcell.KeyEsc {
+		modal.parent.HideModal()
+		return true
+	}
+	return modal.container.OnKeyEvent(event)
+}
+
+func (modal *UserInfoModal) OnMouseEvent(event mauview.MouseEvent) bool {
+	return modal.container.OnMouseEvent(event)
+}
+
+func (modal *UserInfoModal) OnPasteEvent(event mauview.PasteEvent) bool {
+	return modal.container.OnPasteEvent(event)
+}
+
+func (modal *UserInfoModal) Focus() {
+	modal.container.Focus()
+}
+
+func (modal *UserInfoModal) Blur() {
+	modal.container.Blur()
+}
@@ -17,6 +17,8 @@
 package ui
 
 import (
+	"strings"
+
 	"github.com/tulir/tcell"
 
 	"github.com/tulir/mautrix-go"
@@ -40,13 +42,22 @@ type LoginView struct {
 	username   *mauview.InputField
 	password   *mauview.InputField
 	error      *mauview.TextField
+	flows      *mauview.TextView
 
 	loginButton *mauview.Button
+	ssoButton   *mauview.Button
+	regButton   *mauview.Button
 	quitButton  *mauview.Button
 
 	matrix ifc.MatrixContainer
 	config *config.Config
 	parent *GomuksUI
+
+	// Whether or not the view is used to log in to an additional account.
+	addingAccount bool
+	// The homeserver URL that was filled in automatically. If the homeserver field still contains it,
+	// the homeserver is discovered again from the user ID, which may be on a different server.
+	filledHS string
 }
 
 func (ui *GomuksUI) NewLoginView() mauview.Component {
@@ -60,8 +71,11 @@ func (ui *GomuksUI) NewLoginView() mauview.Component {
 		username:   mauview.NewInputField(),
 		password:   mauview.NewInputField(),
 		homeserver: mauview.NewInputField(),
+		flows:      mauview.NewTextView().SetWordWrap(true).SetTextColor(tcell.ColorGray),
 
 		loginButton: mauview.NewButton("Login"),
+		ssoButton:   mauview.NewButton("SSO"),
+		regButton:   mauview.NewButton("Register"),
 		quitButton:  mauview.NewButton("Quit"),
 
 		matrix: ui.gmx.Matrix(),
@@ -71,32 +85,77 @@ func (ui *GomuksUI) NewLoginView() mauview.Component {
 
 	hs := ui.gmx.Config().HS
 	view.homeserver.SetText(hs)
+	view.filledHS = hs
 	view.username.SetText(ui.gmx.Config().UserID)
 	view.password.SetMaskCharacter('*')
 
-	view.quitButton.SetOnClick(ui.gmx.Stop).SetBackgroundColor(tcell.ColorDarkCyan)
+	view.quitButton.SetOnClick(view.Quit).SetBackgroundColor(tcell.ColorDarkCyan)
 	view.loginButton.SetOnClick(view.Login).SetBackgroundColor(tcell.ColorDarkCyan)
+	view.ssoButton.SetOnClick(view.LoginSSO).SetBackgroundColor(tcell.ColorDarkCyan)
+	view.regButton.SetOnClick(view.Register).SetBackgroundColor(tcell.ColorDarkCyan)
 
 	view.SetColumns([]int{1, 10, 1, 9, 1, 9, 1, 10, 1})
-	view.SetRows([]int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+	view.SetRows([]int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
 	view.AddFormItem(view.username, 3, 1, 5, 1).
 		AddFormItem(view.password, 3, 3, 5, 1).
 		AddFormItem(view.homeserver, 3, 5, 5, 1).
 		AddFormItem(view.loginButton, 5, 7, 3, 1).
 		AddFormItem(view.quitButton, 1, 7, 3, 1).
+		AddFormItem(view.regButton, 1, 9, 3, 1).
+		AddFormItem(view.ssoButton, 5, 9, 3, 1).
 		AddComponent(view.usernameLabel, 1, 1, 1, 1).
 		AddComponent(view.passwordLabel, 1, 3, 1, 1).
-		AddComponent(view.homeserverLabel, 1, 5, 1, 1)
+		AddComponent(view.homeserverLabel, 1, 5, 1, 1).
+		AddComponent(view.flows, 1, 11, 7, 4)
 	view.FocusNextItem()
 	ui.loginView = view
 
-	view.container = mauview.Center(mauview.NewBox(view).SetTitle("Log in to Matrix"), 45, 13)
+	if len(hs) > 0 && len(ui.gmx.Config().AccessToken) == 0 {
+		go view.UpdateFlows()
+	}
+
+	view.container = mauview.Center(mauview.NewBox(view).SetTitle("Log in to Matrix"), 45, 20)
 	view.container.SetAlwaysFocusChild(true)
 	return view.container
 }
 
+// SetAccount changes the account that the login view logs in to.
+//
+// If addingAccount is true, the quit button cancels adding the account instead of quitting gomuks.
+func (view *LoginView) SetAccount(matrix ifc.MatrixContainer, addingAccount bool) {
+	view.matrix = matrix
+	view.config = matrix.Config()
+	view.addingAccount = addingAccount
+	view.homeserver.SetText(view.config.HS)
+	view.filledHS = view.config.HS
+	view.username.SetText(view.config.UserID)
+	view.password.SetText("")
+	view.flows.SetText("")
+	view.Error("")
+	if addingAccount {
+		view.quitButton.SetText("Cancel")
+	} else {
+		view.quitButton.SetText("Quit")
+	}
+}
+
+// Quit quits gomuks, or cancels adding an additional account.
+func (view *LoginView) Quit() {
+	if !view.addingAccount {
+		view.parent.gmx.Stop()
+		return
+	}
+	view.matrix.Stop()
+	view.parent.gmx.RemoveAccount(view.matrix)
+	view.SetAccount(view.parent.gmx.Matrix(), false)
+	view.parent.SetView(ViewMain)
+}
+
 func (view *LoginView) Error(err string) {
 	if len(err) == 0 {
+		if view.error == nil {
+			return
+		}
 		debug.Print("Hiding error")
 		view.RemoveComponent(view.error)
 		view.error = nil
@@ -105,23 +164,148 @@ func (view *LoginView) Error(err string) {
 	debug.Print("Showing error", err)
 	if view.error == nil {
 		view.error = mauview.NewTextField().SetTextColor(tcell.ColorRed)
-		view.AddComponent(view.error, 1, 9, 7, 1)
+		view.AddComponent(view.error, 1, 16, 7, 1)
 	}
 	view.error.SetText(err)
 
 	view.parent.Render()
 }
 
+// resolveHomeserver stores the homeserver in the homeserver field in the config.
+//
+// If the homeserver field is empty or contains a plain server name, the homeserver URL is
+// discovered using .well-known/matrix/client of the server name (or the server of the user ID).
+// If the field contains a URL that was filled in automatically and the username is a full user ID,
+// the URL is discovered again from the user ID, but kept if discovery fails.
+func (view *LoginView) resolveHomeserver() error {
+	hs := strings.TrimSpace(view.homeserver.GetText())
+	username := strings.TrimSpace(view.username.GetText())
+	if !strings.Contains(hs, "://") {
+		discoverFrom := hs
+		if len(discoverFrom) == 0 {
+			discoverFrom = username
+		}
+		if err := view.matrix.DiscoverHomeserver(discoverFrom); err != nil {
+			debug.Print("Homeserver discovery error:", err)
+			view.Error("Failed to find homeserver URL.")
+			return err
+		}
+	} else if hs == view.filledHS && strings.HasPrefix(username, "@") {
+		view.config.HS = hs
+		if err := view.matrix.DiscoverHomeserver(username); err != nil {
+			debug.Print("Homeserver rediscovery error, using", hs, "as homeserver:", err)
+		}
+	} else {
+		view.config.HS = hs
+		return nil
+	}
+	view.homeserver.SetText(view.config.HS)
+	view.filledHS = view.config.HS
+	return nil
+}
+
+// initClient resolves the homeserver, initializes the Matrix client and fetches the login flows
+// that the homeserver supports.
+func (view *LoginView) initClient() (flows []string, err error) {
+	if err = view.resolveHomeserver(); err != nil {
+		return
+	}
+	if err = view.matrix.InitClient(); err != nil {
+		debug.Print("Init error:", err)
+		view.Error("Failed to initialize client.")
+		return
+	}
+	flows = view.UpdateFlows()
+	return
+}
+
+// UpdateFlows fetches and shows the login flows that the homeserver in the config supports.
+//
+// The flows are fetched without initializing the Matrix client.
+func (view *LoginView) UpdateFlows() []string {
+	defer debug.Recover()
+	flows, err := view.matrix.GetLoginFlows()
+	if err != nil {
+		debug.Print("Failed to get login flows:", err)
+		view.flows.SetText("")
+		return nil
+	}
+	view.flows.SetText("Supported: " + strings.Join(flows, ", "))
+	view.parent.Render()
+	return flows
+}
+
+func hasFlow(flows []string, flow string) bool {
+	for _, item := range flows {
+		if item == flow {
+			return true
+		}
+	}
+	return false
+}
+
 func (view *LoginView) Login() {
-	hs := view.homeserver.GetText()
 	mxid := view.username.GetText()
 	password := view.password.GetText()
 
-	debug.Printf("Logging into %s as %s...", hs, mxid)
-	view.config.HS = hs
-	err := view.matrix.InitClient()
-	debug.Print("Init error:", err)
-	err = view.matrix.Login(mxid, password)
+	debug.Printf("Logging into %s as %s...", view.homeserver.GetText(), mxid)
+	flows, err := view.initClient()
+	if err != nil {
+		return
+	}
+	if flows != nil && !hasFlow(flows, ifc.LoginFlowPassword) && hasFlow(flows, ifc.LoginFlowSSO) {
+		debug.Print("Homeserver doesn't support password login, using single sign-on")
+		go view.loginSSO()
+		return
+	}
+	view.handleLoginResult("Login", view.matrix.Login(mxid, password))
+}
+
+// LoginSSO logs in using single sign-on in a browser.
+func (view *LoginView) LoginSSO() {
+	debug.Printf("Logging into %s using single sign-on...", view.homeserver.GetText())
+	go func() {
+		defer debug.Recover()
+		if _, err := view.initClient(); err != nil {
+			return
+		}
+		view.loginSSO()
+	}()
+}
+
+func (view *LoginView) loginSSO() {
+	defer debug.Recover()
+	err := view.matrix.LoginSSO(func(url string) {
+		view.flows.SetText("Log in via the browser: " + url)
+		view.parent.Render()
+	})
+	view.handleLoginResult("Login", err)
+}
+
+// Register registers a new account with the username and password in the form.
+// Additional registration steps like accepting terms or validating an email address are prompted separately.
+func (view *LoginView) Register() {
+	username := view.username.GetText()
+	password := view.password.GetText()
+	debug.Printf("Registering %s on %s...", username, view.homeserver.GetText())
+	if strings.HasPrefix(username, "@") {
+		username = username[1:]
+		if colon := strings.IndexRune(username, ':'); colon != -1 {
+			username = username[:colon]
+		}
+	}
+	go func() {
+		defer debug.Recover()
+		if _, err := view.initClient(); err != nil {
+			return
+		}
+		view.handleLoginResult("Registration", view.matrix.Register(username, password, view.parent))
+	}()
+}
+
+// handleLoginResult shows the error of a login or registration, or leaves the login view if it succeeded.
+// The action is shown if the user cancelled the operation.
+func (view *LoginView) handleLoginResult(action string, err error) {
 	if err != nil {
 		if httpErr, ok := err.(mautrix.HTTPError); ok {
 			if httpErr.RespError != nil {
@@ -129,9 +313,13 @@ func (view *LoginView) Login() {
 			} else {
 				view.Error(httpErr.Message)
 			}
+		} else if err == ifc.ErrUIACancelled {
+			view.Error(action + " cancelled.")
 		} else {
 			view.Error("Failed to connect to server.")
 		}
 		debug.Print("Login error:", err)
+	} else if view.addingAccount {
+		view.SetAccount(view.parent.gmx.Matrix(), false)
 	}
 }
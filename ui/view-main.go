@@ -20,11 +20,12 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/tulir/mauview"
-	"github.com/tulir/tcell"
 
 	"github.com/kennetanti/gomuks/config"
 	"github.com/kennetanti/gomuks/debug"
@@ -47,7 +48,18 @@ type MainView struct {
 
 	modal mauview.Component
 
-	lastFocusTime time.Time
+	// The time when the terminal was last focused in Unix nanoseconds. Accessed atomically.
+	lastFocusTime int64
+	stopAutoIdle  chan struct{}
+	// The current terminal title, or an empty string if it hasn't been changed.
+	terminalTitle string
+
+	notifier notification.Notifier
+	// Whether or not do not disturb mode is on, and when it ends. A zero time means it lasts until turned off.
+	// Both are guarded by doNotDisturbLock, as notifications are sent from the sync goroutine.
+	doNotDisturb      bool
+	doNotDisturbUntil time.Time
+	doNotDisturbLock  sync.Mutex
 
 	matrix ifc.MatrixContainer
 	gmx    ifc.Gomuks
@@ -61,6 +73,8 @@ func (ui *GomuksUI) NewMainView() mauview.Component {
 		roomView: mauview.NewBox(nil).SetBorder(false),
 		rooms:    make(map[string]*RoomView),
 
+		stopAutoIdle: make(chan struct{}),
+
 		matrix: ui.gmx.Matrix(),
 		gmx:    ui.gmx,
 		config: ui.gmx.Config(),
@@ -74,12 +88,26 @@ func (ui *GomuksUI) NewMainView() mauview.Component {
 		AddFixedComponent(widget.NewBorder(), 1).
 		AddProportionalComponent(mainView.roomView, 1)
 	mainView.BumpFocus(nil)
+	mainView.setLastFocusTime(time.Now())
+	mainView.initNotifier()
+	go mainView.autoIdleLoop()
 
 	ui.mainView = mainView
 
 	return mainView
 }
 
+// matrixFor returns the MatrixContainer of the account the given room belongs to.
+// If the account is not found, the main account is returned.
+func (view *MainView) matrixFor(room *rooms.Room) ifc.MatrixContainer {
+	if room != nil && room.SessionUserID != view.config.UserID {
+		if account := view.gmx.Account(room.SessionUserID); account != nil {
+			return account
+		}
+	}
+	return view.matrix
+}
+
 func (view *MainView) ShowModal(modal mauview.Component) {
 	view.modal = modal
 	var ok bool
@@ -106,12 +134,56 @@ func (view *MainView) Draw(screen mauview.Screen) {
 	if view.modal != nil {
 		view.modal.Draw(screen)
 	}
+	view.updateTerminalTitle()
+}
+
+// updateTerminalTitle shows the total number of unread messages in the terminal title using an OSC escape sequence.
+// The title is only written when it changes. The previous title is saved to the terminal's title stack
+// the first time, so that it can be restored with restoreTerminalTitle when gomuks exits.
+func (view *MainView) updateTerminalTitle() {
+	unread := 0
+	highlighted := false
+	for _, roomView := range view.rooms {
+		if roomView.Room.HasLeft {
+			continue
+		}
+		unread += roomView.Room.UnreadCount()
+		highlighted = highlighted || roomView.Room.Highlighted()
+	}
+	title := "gomuks"
+	if unread > 0 {
+		count := strconv.Itoa(unread)
+		if highlighted {
+			count += "!"
+		}
+		title = fmt.Sprintf("(%s) gomuks", count)
+	}
+	if title == view.terminalTitle {
+		return
+	}
+	if len(view.terminalTitle) == 0 {
+		fmt.Fprint(os.Stdout, "\x1b[22;2t")
+	}
+	view.terminalTitle = title
+	fmt.Fprintf(os.Stdout, "\x1b]2;%s\x07", title)
+}
+
+// restoreTerminalTitle restores the terminal title that was saved by updateTerminalTitle.
+func (view *MainView) restoreTerminalTitle() {
+	if len(view.terminalTitle) > 0 {
+		fmt.Fprint(os.Stdout, "\x1b[23;2t")
+		view.terminalTitle = ""
+	}
 }
 
 func (view *MainView) BumpFocus(roomView *RoomView) {
 	if roomView != nil {
-		view.lastFocusTime = time.Now()
+		wasIdle := time.Since(view.getLastFocusTime()) > IdleTimeout
+		view.setLastFocusTime(time.Now())
 		view.MarkRead(roomView)
+		if wasIdle {
+			view.updateOwnPresence()
+		}
 	}
 }
 
@@ -121,8 +193,9 @@ func (view *MainView) MarkRead(roomView *RoomView) {
 		if len(msgList) > 0 {
 			msg := msgList[len(msgList)-1]
 			if roomView.Room.MarkRead(msg.ID()) {
-				view.matrix.MarkRead(roomView.Room.ID, msg.ID())
+				roomView.matrix.MarkRead(roomView.Room.ID, msg.ID(), true)
 			}
+			roomView.Room.ClearUnreadCounts()
 		}
 	}
 }
@@ -130,9 +203,9 @@ func (view *MainView) MarkRead(roomView *RoomView) {
 func (view *MainView) InputChanged(roomView *RoomView, text string) {
 	if !roomView.config.Preferences.DisableTypingNotifs {
 		if len(text) == 0 {
-			go view.matrix.SendTyping(roomView.Room.ID, false)
+			go roomView.matrix.SendTyping(roomView.Room.ID, false)
 		} else if text[0] != '/' {
-			go view.matrix.SendTyping(roomView.Room.ID, true)
+			go roomView.matrix.SendTyping(roomView.Room.ID, true)
 		}
 	}
 }
@@ -174,34 +247,10 @@ func (view *MainView) OnKeyEvent(event mauview.KeyEvent) bool {
 		return view.modal.OnKeyEvent(event)
 	}
 
-	k := event.Key()
-	c := event.Rune()
-	if event.Modifiers() == tcell.ModCtrl || event.Modifiers() == tcell.ModAlt {
-		switch {
-		case k == tcell.KeyDown:
-			view.SwitchRoom(view.roomList.Next())
-		case k == tcell.KeyUp:
-			view.SwitchRoom(view.roomList.Previous())
-		case k == tcell.KeyEnter:
-			view.ShowModal(NewFuzzySearchModal(view, 42, 12))
-		case k == tcell.KeyHome:
-			msgView := view.currentRoom.MessageView()
-			msgView.AddScrollOffset(msgView.TotalHeight())
-		case k == tcell.KeyEnd:
-			msgView := view.currentRoom.MessageView()
-			msgView.AddScrollOffset(-msgView.TotalHeight())
-		case c == 'n' || k == tcell.KeyCtrlN:
-			return view.flex.OnKeyEvent(tcell.NewEventKey(tcell.KeyEnter, '\n', event.Modifiers()|tcell.ModShift))
-		case c == 'a':
-			view.SwitchRoom(view.roomList.NextWithActivity())
-		case c == 'l' || k == tcell.KeyCtrlL:
-			view.ShowBare(view.currentRoom)
-		default:
-			goto defaultHandler
-		}
+	action := view.config.Keybindings.Action(config.KeyContextMain, eventChord(event))
+	if len(action) > 0 && view.runKeyAction(action, event) {
 		return true
 	}
-defaultHandler:
 	if view.config.Preferences.HideRoomList {
 		return view.roomView.OnKeyEvent(event)
 	}
@@ -256,6 +305,10 @@ func (view *MainView) SwitchRoom(tag string, room *rooms.Room) {
 		return
 	}
 	view.roomView.SetInnerComponent(roomView)
+	if view.currentRoom != roomView {
+		// Show where the new messages start before they're marked as read.
+		roomView.MessageView().SetFullyReadMarker(room.FullyRead)
+	}
 	view.currentRoom = roomView
 	view.MarkRead(roomView)
 	view.roomList.SetSelected(tag, room)
@@ -282,7 +335,7 @@ func (view *MainView) addRoomPage(room *rooms.Room) {
 func (view *MainView) GetRoom(roomID string) ifc.RoomView {
 	room, ok := view.rooms[roomID]
 	if !ok {
-		view.AddRoom(view.matrix.GetRoom(roomID))
+		view.AddRoom(view.findRoom(roomID))
 		room, ok := view.rooms[roomID]
 		if !ok {
 			return nil
@@ -292,6 +345,17 @@ func (view *MainView) GetRoom(roomID string) ifc.RoomView {
 	return room
 }
 
+// findRoom returns the room with the given ID from the first account that knows it,
+// falling back to the main account.
+func (view *MainView) findRoom(roomID string) *rooms.Room {
+	for _, account := range view.gmx.Accounts() {
+		if room, ok := account.Config().Rooms[roomID]; ok && room != nil {
+			return room
+		}
+	}
+	return view.matrix.GetRoom(roomID)
+}
+
 func (view *MainView) AddRoom(room *rooms.Room) {
 	if view.roomList.Contains(room.ID) {
 		debug.Print("Add aborted (room exists)", room.ID, room.GetTitle())
@@ -306,8 +370,8 @@ func (view *MainView) AddRoom(room *rooms.Room) {
 }
 
 func (view *MainView) RemoveRoom(room *rooms.Room) {
-	roomView := view.GetRoom(room.ID)
-	if roomView == nil {
+	roomView, ok := view.rooms[room.ID]
+	if !ok || roomView.Room != room {
 		debug.Print("Remove aborted (not found)", room.ID, room.GetTitle())
 		return
 	}
@@ -317,25 +381,98 @@ func (view *MainView) RemoveRoom(room *rooms.Room) {
 	view.SwitchRoom(view.roomList.Selected())
 
 	delete(view.rooms, room.ID)
+	if shared := view.findSharedRoom(room.ID, room.SessionUserID); shared != nil {
+		view.AddRoom(shared)
+	}
 
 	view.parent.Render()
 }
 
-func (view *MainView) SetRooms(rooms map[string]*rooms.Room) {
-	view.roomList.Clear()
-	view.rooms = make(map[string]*RoomView)
+// SetRooms replaces the rooms of the account with the given user ID with the given rooms.
+// Rooms that are already shown through another account are skipped.
+func (view *MainView) SetRooms(userID string, rooms map[string]*rooms.Room) {
+	view.removeAccountRooms(userID)
 	for _, room := range rooms {
 		if room.HasLeft {
 			continue
+		} else if _, ok := view.rooms[room.ID]; ok {
+			continue
 		}
 		view.roomList.Add(room)
 		view.addRoomPage(room)
 	}
-	view.SwitchRoom(view.roomList.First())
+	if view.currentRoom == nil {
+		view.SwitchRoom(view.roomList.First())
+	}
+}
+
+// removeAccountRooms removes all rooms of the account with the given user ID and returns their IDs.
+func (view *MainView) removeAccountRooms(userID string) (removed []string) {
+	for roomID, roomView := range view.rooms {
+		if roomView.Room.SessionUserID == userID {
+			view.roomList.Remove(roomView.Room)
+			delete(view.rooms, roomID)
+			removed = append(removed, roomID)
+		}
+	}
+	if view.currentRoom != nil && view.currentRoom.Room.SessionUserID == userID {
+		view.currentRoom = nil
+		view.roomView.SetInnerComponent(nil)
+	}
+	return
+}
+
+// findSharedRoom returns the room with the given ID from another logged-in account that is also in the room.
+func (view *MainView) findSharedRoom(roomID, excludeUserID string) *rooms.Room {
+	for _, account := range view.gmx.Accounts() {
+		cfg := account.Config()
+		if cfg.UserID == excludeUserID || len(cfg.AccessToken) == 0 {
+			continue
+		}
+		if room, ok := cfg.Rooms[roomID]; ok && room != nil && !room.HasLeft {
+			return room
+		}
+	}
+	return nil
+}
+
+// RemoveAccountRooms removes all rooms of the account with the given user ID from the UI.
+// Rooms that another logged-in account is also in are shown through that account instead.
+func (view *MainView) RemoveAccountRooms(userID string) {
+	var currentRoomID string
+	if view.currentRoom != nil {
+		currentRoomID = view.currentRoom.Room.ID
+	}
+	for _, roomID := range view.removeAccountRooms(userID) {
+		if room := view.findSharedRoom(roomID, userID); room != nil {
+			view.roomList.Add(room)
+			view.addRoomPage(room)
+		}
+	}
+	if view.currentRoom == nil {
+		if roomView, ok := view.rooms[currentRoomID]; ok {
+			view.SwitchRoom(roomView.Room.Tags()[0].Tag, roomView.Room)
+		} else {
+			view.SwitchRoom(view.roomList.First())
+		}
+	}
+	view.parent.Render()
+}
+
+// IgnoredUsersChanged hides or shows messages in the rooms of the account with the given user ID
+// after its ignored user list has changed.
+func (view *MainView) IgnoredUsersChanged(userID string) {
+	for _, roomView := range view.rooms {
+		if roomView.Room.SessionUserID == userID {
+			// Force recalculateBuffers() to rebuild the buffer.
+			roomView.MessageView().prevMsgCount = -1
+		}
+	}
+	view.parent.Render()
 }
 
 func (view *MainView) UpdateTags(room *rooms.Room) {
-	if !view.roomList.Contains(room.ID) {
+	if roomView, ok := view.rooms[room.ID]; !ok || roomView.Room != room {
 		return
 	}
 	view.roomList.Remove(room)
@@ -350,24 +487,136 @@ func (view *MainView) SetTyping(room string, users []string) {
 	}
 }
 
-func sendNotification(room *rooms.Room, sender, text string, critical, sound bool) {
+// initNotifier creates the notification backend selected in the config.
+// If the backend can't be created, the native notifier of the platform is used instead.
+func (view *MainView) initNotifier() {
+	cfg := view.config.Notifications
+	var err error
+	view.notifier, err = notification.New(cfg.Backend, cfg.Command, view.openRoomFromNotification)
+	if err != nil {
+		debug.Printf("Failed to create %s notification backend: %v", cfg.Backend, err)
+		view.notifier = notification.NativeNotifier{}
+	}
+	onError := func(err error) {
+		debug.Printf("Failed to send notification: %v", err)
+	}
+	switch notifier := view.notifier.(type) {
+	case *notification.BellNotifier:
+		notifier.Beep = view.beep
+	case *notification.CommandNotifier:
+		notifier.OnError = onError
+	}
+	view.notifier = notification.NewBatcher(view.notifier, cfg.GetBatchWindow())
+	if batcher, ok := view.notifier.(*notification.Batcher); ok {
+		batcher.OnError = onError
+	}
+	for _, period := range cfg.QuietHours {
+		if err = period.Validate(); err != nil {
+			debug.Printf("Invalid quiet hours %s-%s: %v", period.Start, period.End, err)
+		}
+	}
+}
+
+// beep rings the terminal bell on the UI goroutine, so that it doesn't end up in the middle of a screen update.
+func (view *MainView) beep() error {
+	view.parent.app.QueueUpdate(func() {
+		if beeper, ok := view.parent.app.Screen().(interface{ Beep() error }); ok {
+			if err := beeper.Beep(); err != nil {
+				debug.Printf("Failed to ring the bell: %v", err)
+			}
+		} else if _, err := os.Stdout.Write([]byte{'\a'}); err != nil {
+			debug.Printf("Failed to ring the bell: %v", err)
+		}
+	})
+	return nil
+}
+
+// SetDoNotDisturb turns do not disturb mode on or off. If the duration is zero, the mode lasts until turned off.
+func (view *MainView) SetDoNotDisturb(enabled bool, duration time.Duration) {
+	view.doNotDisturbLock.Lock()
+	defer view.doNotDisturbLock.Unlock()
+	view.doNotDisturb = enabled
+	view.doNotDisturbUntil = time.Time{}
+	if enabled && duration > 0 {
+		view.doNotDisturbUntil = time.Now().Add(duration)
+	}
+}
+
+// DoNotDisturbStatus returns whether or not do not disturb mode is on, and when it ends.
+func (view *MainView) DoNotDisturbStatus() (bool, time.Time) {
+	view.doNotDisturbLock.Lock()
+	defer view.doNotDisturbLock.Unlock()
+	if view.doNotDisturb && !view.doNotDisturbUntil.IsZero() && time.Now().After(view.doNotDisturbUntil) {
+		view.doNotDisturb = false
+		view.doNotDisturbUntil = time.Time{}
+	}
+	return view.doNotDisturb, view.doNotDisturbUntil
+}
+
+// notificationsSuppressed returns whether or not the notification for the given message should be
+// dropped because of do not disturb mode or quiet hours.
+func (view *MainView) notificationsSuppressed(room *rooms.Room, message ifc.Message, highlight bool) bool {
+	cfg := &view.config.Notifications
+	dnd, _ := view.DoNotDisturbStatus()
+	if !dnd && !cfg.IsQuietTime(time.Now()) {
+		return false
+	}
+	return !cfg.Exceptions.Matches(room.ID, message.SenderID(), highlight)
+}
+
+// openRoomFromNotification switches to the given room when the "open room" action of a notification is clicked.
+//
+// It's called by the notification backend on its own goroutine, so the switch is queued to the UI goroutine.
+func (view *MainView) openRoomFromNotification(roomID string) {
+	view.parent.app.QueueUpdate(func() {
+		defer debug.Recover()
+		roomView, ok := view.rooms[roomID]
+		if !ok {
+			return
+		}
+		tag := ""
+		if tags := roomView.Room.Tags(); len(tags) > 0 {
+			tag = tags[0].Tag
+		}
+		view.SwitchRoom(tag, roomView.Room)
+		view.parent.Render()
+	})
+}
+
+func (view *MainView) sendNotification(room *rooms.Room, message ifc.Message, critical, sound bool) {
+	sender := message.NotificationSenderName()
+	title := sender
 	if room.GetTitle() != sender {
-		sender = fmt.Sprintf("%s (%s)", sender, room.GetTitle())
+		title = fmt.Sprintf("%s (%s)", sender, room.GetTitle())
+	}
+	text := message.NotificationContent()
+	debug.Printf("Sending notification with body \"%s\" from %s in room ID %s (critical=%v, sound=%v)", text, title, room.ID, critical, sound)
+	err := view.notifier.Send(notification.Notification{
+		Title:    title,
+		Body:     text,
+		Sender:   message.SenderID(),
+		RoomID:   room.ID,
+		RoomName: room.GetTitle(),
+		EventID:  message.ID(),
+		Critical: critical,
+		Sound:    sound,
+	})
+	if err != nil {
+		debug.Printf("Failed to send notification: %v", err)
 	}
-	debug.Printf("Sending notification with body \"%s\" from %s in room ID %s (critical=%v, sound=%v)", text, sender, room.ID, critical, sound)
-	notification.Send(sender, text, critical, sound)
 }
 
 func (view *MainView) NotifyMessage(room *rooms.Room, message ifc.Message, should pushrules.PushActionArrayShould) {
 	view.roomList.Bump(room)
-	if message.SenderID() == view.config.UserID {
+	if message.SenderID() == room.SessionUserID {
 		return
 	}
 	// Whether or not the room where the message came is the currently shown room.
 	isCurrent := room == view.roomList.SelectedRoom()
 	// Whether or not the terminal window is focused.
-	recentlyFocused := time.Now().Add(-30 * time.Second).Before(view.lastFocusTime)
-	isFocused := time.Now().Add(-5 * time.Second).Before(view.lastFocusTime)
+	lastFocusTime := view.getLastFocusTime()
+	recentlyFocused := time.Now().Add(-30 * time.Second).Before(lastFocusTime)
+	isFocused := time.Now().Add(-5 * time.Second).Before(lastFocusTime)
 
 	// Whether or not the push rules say this message should be notified about.
 	shouldNotify := should.Notify || !should.NotifySpecified
@@ -376,13 +625,17 @@ func (view *MainView) NotifyMessage(room *rooms.Room, message ifc.Message, shoul
 		// The message is not in the current room, show new message status in room list.
 		room.AddUnread(message.ID(), shouldNotify, should.Highlight)
 	} else {
-		view.matrix.MarkRead(room.ID, message.ID())
+		room.MarkRead(message.ID())
+		room.ClearUnreadCounts()
+		// The fully read marker is only moved if the new message is visible.
+		atBottom := view.currentRoom != nil && view.currentRoom.MessageView().ScrollOffset == 0
+		view.matrixFor(room).MarkRead(room.ID, message.ID(), atBottom)
 	}
 
-	if shouldNotify && !recentlyFocused {
+	if shouldNotify && !recentlyFocused && !view.notificationsSuppressed(room, message, should.Highlight) {
 		// Push rules say notify and the terminal is not focused, send desktop notification.
 		shouldPlaySound := should.PlaySound && should.SoundName == "default"
-		sendNotification(room, message.NotificationSenderName(), message.NotificationContent(), should.Highlight, shouldPlaySound)
+		view.sendNotification(room, message, should.Highlight, shouldPlaySound)
 	}
 
 	// TODO this should probably happen somewhere else
@@ -420,7 +673,7 @@ func (view *MainView) LoadHistory(room string) {
 		return
 	}
 
-	history, err := view.matrix.GetHistory(roomView.Room, 50)
+	history, err := roomView.matrix.GetHistory(roomView.Room, 50)
 	if err != nil {
 		roomView.AddServiceMessage("Failed to fetch history")
 		debug.Print("Failed to fetch history for", roomView.Room.ID, err)
@@ -431,5 +684,6 @@ func (view *MainView) LoadHistory(room string) {
 			msgView.AddMessage(message, PrependMessage)
 		}
 	}
+	msgView.placeFullyReadDivider()
 	view.parent.Render()
 }